@@ -1,10 +1,11 @@
-import { useMemo } from 'react';
+import { useMemo, useState } from 'react';
 import { useSortable, SortableContext, horizontalListSortingStrategy } from '@dnd-kit/sortable';
 import { CSS } from '@dnd-kit/utilities';
 import { FiFile, FiTrash } from 'react-icons/fi';
-import { UploadedFile } from '../services/api';
+import api, { UploadedFile } from '../services/api';
 
 interface PDFItemProps {
+    sessionId: string;
     file: UploadedFile;
     onRemove: (filename: string) => void;
     disabled?: boolean;
@@ -25,7 +26,8 @@ function truncateFilename(filename: string, maxLength = 20): { name: string; ext
     };
 }
 
-const PDFItem = ({ file, onRemove , disabled }: PDFItemProps) => {
+const PDFItem = ({ sessionId, file, onRemove , disabled }: PDFItemProps) => {
+    const [thumbnailFailed, setThumbnailFailed] = useState(false);
     const {
         attributes,
         listeners,
@@ -92,7 +94,17 @@ const PDFItem = ({ file, onRemove , disabled }: PDFItemProps) => {
                 <FiTrash size={16} className="remove-button" />
             </button>
             <div className="file-content">
-                <FiFile size={20} />
+                {thumbnailFailed ? (
+                    <FiFile size={20} />
+                ) : (
+                    <img
+                        className="file-thumbnail"
+                        src={api.thumbnailUrl(sessionId, file.filename)}
+                        alt={`First page of ${displayName}`}
+                        draggable={false}
+                        onError={() => setThumbnailFailed(true)}
+                    />
+                )}
                 <div className="file-name" title={displayName}>
                     <span>{name}</span>
                     <span className="file-ext">{ext}</span>
@@ -106,13 +118,14 @@ const PDFItem = ({ file, onRemove , disabled }: PDFItemProps) => {
 };
 
 interface PDFListProps {
+    sessionId: string;
     files: UploadedFile[];
     onReorder: (files: UploadedFile[]) => void;
     onRemove: (filename: string) => void;
     disabled?: boolean;
 }
 
-const PDFList = ({ files, onRemove , disabled}: PDFListProps) => {
+const PDFList = ({ sessionId, files, onRemove , disabled}: PDFListProps) => {
     const items = useMemo(() => files.map(file => file.filename), [files]);
 
     return (
@@ -120,6 +133,7 @@ const PDFList = ({ files, onRemove , disabled}: PDFListProps) => {
             {files.map((file) => (
                 <PDFItem
                     key={file.filename}
+                    sessionId={sessionId}
                     file={file}
                     onRemove={onRemove}
                     disabled={disabled}
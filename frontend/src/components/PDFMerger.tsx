@@ -160,6 +160,7 @@ const PDFMerger = () => {
                                     <div className="dropzone-content">
                                         <div className="file-list-container">
                                             <PDFList 
+                                                sessionId={sessionId}
                                                 files={files} 
                                                 onReorder={handleReorder}
                                                 onRemove={handleRemoveFile}
@@ -34,6 +34,9 @@ const api = {
         return response.data;
     },
 
+    thumbnailUrl: (sessionId: string, filename: string, page = 1, width = 160): string =>
+        `${API_BASE_URL}/api/sessions/${sessionId}/files/${encodeURIComponent(filename)}/thumbnail?page=${page}&width=${width}`,
+
     downloadFile: (url: string) => {
         window.location.href = `${API_BASE_URL}${url}`;
         setTimeout(() => {
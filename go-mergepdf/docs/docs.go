@@ -38,16 +38,1225 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/sessions/{sessionID}": {
+            "get": {
+                "description": "Returns the uploaded files, assets, merge status and output file of a session, including the output's document metadata",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Inspect a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ sessionId: string, files: [string], assets: [string], outputFile: string, mergeStatus: string, metadata: object }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/attach": {
+            "post": {
+                "description": "Embeds session assets or PDFs into an uploaded file or the current output, e.g. the spreadsheet a\nmerged report was made from. Files are stored under their original names unless name is given, and\nmay total at most 25 MB. portfolio makes viewers present the document as a PDF portfolio. Uploads are\nchanged in place; the output is written to a new download.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Attach files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, attachments: [{ file, name, description }], portfolio: bool }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ downloadUrl or filename: string, attached: [string] }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/bulkfill": {
+            "post": {
+                "description": "Fills the form of a session file or the current output once per CSV row and returns the results as a ZIP,\neither one PDF per row or a single combined PDF. The first CSV row holds the column headers. Rows that\nfail are reported individually. The ZIP is kept beside the session output, which stays as it was.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Fill a form from CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV file",
+                        "name": "csv",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Template filename, defaults to the current output",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object mapping CSV columns to field names or IDs",
+                        "name": "mapping",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filename template such as {lastName}-{firstName}, defaults to row-{row}",
+                        "name": "nameTemplate",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Flatten the filled fields",
+                        "name": "flatten",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Combine all rows into one PDF",
+                        "name": "combine",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ downloadUrl: string, report: { rows, filled, files, errors: [{ row, error }] } }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "{ error: string, report: object } when no row could be filled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/compare": {
+            "post": {
+                "description": "Compares a revised session file with the original, e.g. a signed copy with the draft that was sent out.\nReports the page counts, differing document information and every page that was added, removed or\nchanged, with the text lines and images added or removed on it. Pages are matched by their text, so an\ninserted page does not mark every later page as changed. With report set, the differences are also\nwritten as a PDF with added content highlighted in green and removed content in red. The report is kept\nbeside the session output, which stays as it was.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Compare two PDFs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ original: string, revised: string, report: bool }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ comparison: { sameContent, originalPageCount, revisedPageCount, metadata: [{ field, original, revised }], pages: [{ originalPage, revisedPage, status, addedText, removedText, addedImages, removedImages, otherContent }] }, downloadUrl: string with report }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/crop": {
+            "post": {
+                "description": "Sets the crop box, trim box or both of selected pages, cutting fixed margins in points or percent, or\ntrimming each page to its content with optional padding. Cropping an uploaded file replaces it in place,\nso the cropped pages are what gets merged; cropping the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pages"
+                ],
+                "summary": "Crop page margins",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, pages: string, box: crop|trim|both, unit: pt|percent, top, right, bottom, left: number, auto: bool, padding: number }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, cropped: int } or { downloadUrl: string, cropped: int }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/extract-images": {
+            "post": {
+                "description": "Collects the images used by selected pages of a session file or the current output into a ZIP archive\nwith entries named page-\u003cpage\u003e-obj-\u003cobject\u003e.\u003cext\u003e. JPEG and JPEG 2000 images keep their encoding, others\nbecome PNG or TIFF. The archive is kept beside the session output, which stays as it was.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "images"
+                ],
+                "summary": "Extract embedded images",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, pages: string }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ downloadUrl: string, report: { images: int, skipped: [{ page, object, error }] } }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "No images found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/fill": {
+            "post": {
+                "description": "Fills the AcroForm fields of a session file or the current output, matching fields by name or ID.\nCheck boxes take booleans, list boxes take an array of options. With flatten set the fields become\nstatic content. Filling an uploaded file replaces it in place; filling the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Fill form fields",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, values: { \u003cfield\u003e: value }, flatten: bool }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string } or { downloadUrl: string }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or unknown field",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/flatten": {
+            "post": {
+                "description": "Draws the form fields of a session file or the current output into the page content and removes the form.\nFlattening an uploaded file replaces it in place; flattening the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "Flatten form fields",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, flattened: int } or { downloadUrl: string, flattened: int }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/flatten-annotations": {
+            "post": {
+                "description": "Draws the annotations of a session file or the current output selected by type, author and pages into\nthe page content as viewers show them and removes them; without types all but links are selected.\nAnnotations without an appearance stay as they are. Uploads are changed in place; the current output\nis written to a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Flatten annotations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, types: [string], authors: [string], pages: string }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename or downloadUrl: string, flattened: int }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/impose": {
+            "post": {
+                "description": "Arranges the pages of a session file or the current output 2, 4, 6 or 9 per sheet, row or column first,\nor reorders them into a saddle-stitch booklet padded with blank pages to a multiple of four.\nThe result becomes the session output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "impose"
+                ],
+                "summary": "Impose pages n-up or as a booklet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, mode: nup|booklet, n: int, paperSize: string, border: bool, order: row|column }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ downloadUrl: string }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/insert": {
+            "post": {
+                "description": "Inserts blank pages, or pages of an asset or another session file such as a signature page, after a\ngiven page. Inserting into an uploaded file replaces it in place, so the file keeps its position in the\nmerge order; inserting into the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pages"
+                ],
+                "summary": "Insert pages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, after: int, source: string, pages: string, blank: int, size: string }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, inserted: int } or { downloadUrl: string, inserted: int }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/api/sessions/{sessionID}/actions/merge": {
             "post": {
-                "description": "Merges all uploaded files in the session and returns a download URL",
+                "description": "Merges all uploaded files in the session and returns a download URL.\nThe optional body can set or strip document metadata on the merged output and prefix the form\nfields of each file with doc\u003cn\u003e_ so identical forms keep separate values. pageSize scales every page\nto A4, Letter, Legal or a custom size in points, using fit, fill or center mode with optional margins.\nmode \"interleave\" alternates the pages of exactly two files, optionally reversing the second.\npdfa \"2b\" converts the output to PDF/A-2b; if that is not possible the merge fails with the list of issues.\nlinearize writes a linearized file that browser viewers can display before it has fully downloaded.\nPage labels of the source files are kept; pageLabels adds ranges from a 1-based page with a style\n(decimal, roman, lower-roman, letters, lower-letters or none), a prefix and a start number.\ntoc puts clickable contents pages in front listing each file and, with bookmarks, its top-level bookmarks.\nsplit delivers the output as a ZIP of parts named merged-part\u003ci\u003e-of-\u003cn\u003e.pdf, each within maxPages pages\nand maxBytes bytes; with keepFilesTogether parts only end between source files.\ndropDuplicatePages leaves out pages whose content repeats an earlier page and reports them as removedPages.\nremoveBlankPages leaves out pages with nothing drawn on them, or without text and inked below threshold\npercent (default 0.5), checking the pages selection of each file, and reports them as blankPages.\nThe uploads are left as they are, so merging again without the option brings the pages back.\nA session can be merged again at any time; the new output replaces the previous one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Merge uploaded files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ metadata: { title, author, subject, keywords, creator, producer, creationDate, modDate, strip }, renameFormFields: bool, pageSize: { size, width, height, mode, margin }, mode: sequential|interleave, reverseSecond: bool, pdfa: 2b, linearize: bool, pageLabels: [{ page, style, prefix, start }], toc: { title, bookmarks }, split: { maxPages, maxBytes, keepFilesTogether }, dropDuplicatePages: bool, removeBlankPages: { threshold, pages } }",
+                        "name": "options",
+                        "in": "body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ downloadUrl: string, parts: [{ name, firstPage, lastPage, size, oversized }] with split, removedPages: [{ file, page, duplicateOf: { file, page } }] with dropDuplicatePages, blankPages: [{ file, page, reason, coverage }] with removeBlankPages }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "No files to merge",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Merge already in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "{ error: string, issues: [{ code, message, fixable }] } when PDF/A conversion is not possible, or a message when all pages are blank",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/metadata": {
+            "post": {
+                "description": "Sets or strips the document information and XMP metadata of a session file or the current output.\nEditing an uploaded file replaces it in place; editing the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metadata"
+                ],
+                "summary": "Edit document metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, title, author, subject, keywords, creator, producer, creationDate, modDate, strip }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, metadata: object } or { downloadUrl: string, metadata: object }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/overlay": {
+            "post": {
+                "description": "Stamps pages of a template PDF over or under every page of a session file or the current output.\nThe template is an uploaded asset or file; separate template pages can be chosen for the first, other\nand last pages, with -1 leaving those pages blank. Stamping an uploaded file replaces it in place;\nstamping the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "overlay"
+                ],
+                "summary": "Overlay a letterhead or background template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, template: string, firstPage, otherPages, lastPage: int, underlay: bool, opacity: number }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string } or { downloadUrl: string }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, or a template that is not a PDF",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/redact": {
+            "post": {
+                "description": "Permanently removes the text, image pixels, graphics and annotations under rectangles given in points from\nthe top left of the displayed page, and under every match of a string or regular expression, then paints\nblack boxes over them. Redacting an uploaded file replaces it in place; redacting the current output\nmakes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pages"
+                ],
+                "summary": "Redact areas and search terms",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, areas: [{ page, x, y, width, height }], search: string, regex: bool, ignoreCase: bool, pages: string }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename or downloadUrl: string, report: { pages: [{ page, areas, matches, characters }], matches: int } }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/remove-annotations": {
+            "post": {
+                "description": "Deletes the annotations of a session file or the current output selected by type, author and pages;\nwithout types all but links are selected. Pop-up notes go with their annotation. Uploads are changed\nin place; the current output is written to a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Remove annotations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, types: [string], authors: [string], pages: string }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename or downloadUrl: string, removed: int }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/remove-blank-pages": {
+            "post": {
+                "description": "Finds pages with nothing drawn on them, or without text and inked below threshold percent (default 0.5),\nsuch as the blank backs of a duplex scan, among the selected pages and removes them. The removed pages\nare listed by their page number before removal. With dryRun the pages are only listed. Editing an\nuploaded file replaces it in place, so unlike the removeBlankPages merge option the pages are gone from\nthe upload and can only be put back from a fresh upload of the original with the insert action; editing\nthe current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pages"
+                ],
+                "summary": "Remove blank pages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, threshold: number, pages: string, dryRun: bool }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, blankPages: [{ file, page, reason, coverage }] } or { downloadUrl: string, blankPages: [...] }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "All pages are blank",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/actions/sanitize": {
+            "post": {
+                "description": "Removes JavaScript, embedded files, hidden layers, annotations, form fields, document actions and metadata\nfrom a session file or the current output. Revision history is always discarded. Sanitizing an uploaded file\nreplaces it in place; sanitizing the current output makes a new output.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sanitize"
+                ],
+                "summary": "Sanitize a PDF",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "{ file: string, javascript, embeddedFiles, hiddenLayers, annotations, formFields, documentActions, metadata: bool }",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, report: object } or { downloadUrl: string, report: object }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/assets": {
+            "post": {
+                "description": "Uploads a file used by other actions, such as a letterhead template, without adding it to the files\nthat are merged. Assets are kept when the file order changes and are removed with the session.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a supporting file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Asset file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, size: int }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files": {
+            "post": {
+                "description": "Uploads a PDF file to the session. The file is validated in relaxed and strict mode; files that fail\nrelaxed validation, e.g. because of a broken cross-reference table, are repaired and replaced by the\nrepaired copy unless repair is false. Files that cannot be repaired are rejected with the diagnostics.\nsha256 is the hash of the uploaded content; duplicateOf lists session files with the same content.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a PDF file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "PDF file",
+                        "name": "pdf",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Repair damaged files, true by default",
+                        "name": "repair",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ filename: string, size: int, sha256: string, duplicateOf: [string], validation: { valid, strict, repaired: bool, issues: [{ severity, message }] } }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "{ error: string, validation: object }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files/{filename}": {
+            "get": {
+                "description": "Downloads the merged PDF file for the session, or the ZIP archive produced by actions with several results.\nArchives and reports made besides the output, such as extracted images, are downloaded here as well\nunder their downloadUrl; they keep the session and its output.\nRange requests are supported so PDF viewers can load a linearized file page by page. With inline=true the\nfile is served for display in the browser; the session is then kept until it expires instead of being\ndeleted after the download, as are sessions whose file was fetched in ranges.",
+                "produces": [
+                    "application/pdf",
+                    "application/zip"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download merged PDF",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merged PDF filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Serve for display instead of as an attachment",
+                        "name": "inline",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Byte range, e.g. bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "PDF file download",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Requested byte range",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Unauthorized access to file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "416": {
+                        "description": "Range not satisfiable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files/{filename}/annotations": {
+            "get": {
+                "description": "Lists the comments, highlights and other annotations of a session file or the current output per page,\nwith their type, author and contents. Form fields and pop-up notes are left out.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "annotations"
                 ],
-                "summary": "Merge uploaded files",
+                "summary": "List annotations",
                 "parameters": [
                     {
                         "type": "string",
@@ -55,32 +1264,123 @@ const docTemplate = `{
                         "name": "sessionID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded or output filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{ downloadUrl: string }",
+                        "description": "{ annotations: [{ page, type, author, subject, contents, modified }] }",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "No files to merge",
+                    "404": {
+                        "description": "Session or file not found",
                         "schema": {
                             "type": "string"
                         }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files/{filename}/attachments": {
+            "get": {
+                "description": "Lists the files embedded in a session file or the current output, with their sizes as recorded in the PDF",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "List attachments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded or output filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ attachments: [{ name, description, size, modified }] }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     },
                     "404": {
-                        "description": "Session not found",
+                        "description": "Session or file not found",
                         "schema": {
                             "type": "string"
                         }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files/{filename}/attachments/{name}": {
+            "get": {
+                "description": "Returns a file embedded in a session file or the current output as an octet-stream download, whatever its\nname. Attachments over 25 MB are refused.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Download an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded or output filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment name as listed",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Attachment data",
+                        "schema": {
+                            "type": "file"
+                        }
                     },
-                    "409": {
-                        "description": "Merge already in progress or done",
+                    "404": {
+                        "description": "Session, file or attachment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Attachment too large",
                         "schema": {
                             "type": "string"
                         }
@@ -88,19 +1388,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/sessions/{sessionID}/files": {
-            "post": {
-                "description": "Uploads a PDF file to the session",
-                "consumes": [
-                    "multipart/form-data"
+        "/api/sessions/{sessionID}/files/{filename}/fields": {
+            "get": {
+                "description": "Lists the AcroForm fields of a session file or the current output with their type, value, options and pages.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forms"
+                ],
+                "summary": "List form fields",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded or output filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "{ fields: [{ name, id, type, value, default, options, pages, locked, multiple }] }",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files/{filename}/pdfa": {
+            "get": {
+                "description": "Reports why a session file or the current output does not conform to PDF/A-1b, 2b or 3b: fonts that\nare not embedded, transparency, a missing output intent or XMP identification, encryption, JavaScript\nand other forbidden content. Issues marked fixable are resolved by the pdfa merge option. The check\ncovers the common causes of failure and does not replace a full validator.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "files"
                 ],
-                "summary": "Upload a PDF file",
+                "summary": "Check PDF/A conformance",
                 "parameters": [
                     {
                         "type": "string",
@@ -110,29 +1450,90 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "PDF file",
-                        "name": "pdf",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "Uploaded or output filename",
+                        "name": "filename",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "1b, 2b or 3b (default: all three)",
+                        "name": "level",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{ filename: string, size: int }",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pdf.PDFAReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown PDF/A level",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Session or file not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{sessionID}/files/{filename}/text": {
+            "get": {
+                "description": "Returns the text of each page of a session file or the current output. Pages without extractable text,\ntypically scans that need OCR, are flagged with noText and listed in noTextPages. With format=text the\npages are returned as plain text separated by form feeds, and the X-No-Text-Pages header lists those pages.",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Extract text",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploaded or output filename",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or text",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{ pages: [{ page, text, noText }], noTextPages: [int] }",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Unknown format",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Session not found",
+                        "description": "Session or file not found",
                         "schema": {
                             "type": "string"
                         }
@@ -140,16 +1541,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/sessions/{sessionID}/files/{filename}": {
+        "/api/sessions/{sessionID}/files/{filename}/thumbnail": {
             "get": {
-                "description": "Downloads the merged PDF file for the session",
+                "description": "Renders a page of a session file or the current output as a PNG image, for previews in the reorder UI.\nThumbnails are cached by file content, page and width. Rendering runs on a limited number of workers\nand is abandoned after 10 seconds; the built-in renderer draws text as gray bars.",
                 "produces": [
-                    "application/pdf"
+                    "image/png"
                 ],
                 "tags": [
                     "files"
                 ],
-                "summary": "Download merged PDF",
+                "summary": "Page thumbnail",
                 "parameters": [
                     {
                         "type": "string",
@@ -160,21 +1561,33 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Merged PDF filename",
+                        "description": "Uploaded or output filename",
                         "name": "filename",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, 1 by default",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Width in pixels, 32 to 400, 160 by default",
+                        "name": "width",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "PDF file download",
+                        "description": "PNG image",
                         "schema": {
                             "type": "file"
                         }
                     },
-                    "403": {
-                        "description": "Unauthorized access to file",
+                    "400": {
+                        "description": "Invalid page or width",
                         "schema": {
                             "type": "string"
                         }
@@ -184,6 +1597,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Rendering timed out",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -352,6 +1771,53 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "definitions": {
+        "pdf.PDFAConformance": {
+            "type": "object",
+            "properties": {
+                "conformant": {
+                    "type": "boolean"
+                },
+                "issues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pdf.PDFAIssue"
+                    }
+                },
+                "level": {
+                    "type": "string"
+                }
+            }
+        },
+        "pdf.PDFAIssue": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "fixable": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "pdf.PDFAReport": {
+            "type": "object",
+            "properties": {
+                "claimed": {
+                    "type": "string"
+                },
+                "levels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pdf.PDFAConformance"
+                    }
+                }
+            }
+        }
     }
 }`
 
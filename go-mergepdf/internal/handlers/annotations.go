@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListAnnotations godoc
+// @Summary      List annotations
+// @Description  Lists the comments, highlights and other annotations of a session file or the current output per page,
+// @Description  with their type, author and contents. Form fields and pop-up notes are left out.
+// @Tags         annotations
+// @Produce      json
+// @Param        sessionID  path      string  true  "Session ID"
+// @Param        filename   path      string  true  "Uploaded or output filename"
+// @Success      200  {object}  map[string]interface{}  "{ annotations: [{ page, type, author, subject, contents, modified }] }"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/files/{filename}/annotations [get]
+func (h *APIHandler) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	annotations, err := pdf.ListAnnotations(sourcePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list annotations: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"annotations": annotations})
+}
+
+// RemoveAnnotations godoc
+// @Summary      Remove annotations
+// @Description  Deletes the annotations of a session file or the current output selected by type, author and pages;
+// @Description  without types all but links are selected. Pop-up notes go with their annotation. Uploads are changed
+// @Description  in place; the current output is written to a new output.
+// @Tags         annotations
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, types: [string], authors: [string], pages: string }"
+// @Success      200  {object}  map[string]interface{}  "{ filename or downloadUrl: string, removed: int }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/remove-annotations [post]
+func (h *APIHandler) RemoveAnnotations(w http.ResponseWriter, r *http.Request) {
+	h.editAnnotations(w, r, "unannotated", "removed", pdf.RemoveAnnotations)
+}
+
+// FlattenAnnotations godoc
+// @Summary      Flatten annotations
+// @Description  Draws the annotations of a session file or the current output selected by type, author and pages into
+// @Description  the page content as viewers show them and removes them; without types all but links are selected.
+// @Description  Annotations without an appearance stay as they are. Uploads are changed in place; the current output
+// @Description  is written to a new output.
+// @Tags         annotations
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, types: [string], authors: [string], pages: string }"
+// @Success      200  {object}  map[string]interface{}  "{ filename or downloadUrl: string, flattened: int }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/flatten-annotations [post]
+func (h *APIHandler) FlattenAnnotations(w http.ResponseWriter, r *http.Request) {
+	h.editAnnotations(w, r, "flattened", "flattened", pdf.FlattenAnnotations)
+}
+
+// editAnnotations runs an annotation action, reporting its count under key.
+func (h *APIHandler) editAnnotations(w http.ResponseWriter, r *http.Request, prefix, key string, edit func(pdfPath, outputPath string, filter pdf.AnnotationFilter) (int, error)) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+		pdf.AnnotationFilter
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := req.AnnotationFilter.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid annotation filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, prefix)
+	count, err := edit(sourcePath, outputPath, req.AnnotationFilter)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		if errors.Is(err, pdf.ErrInvalidAnnotationFilter) {
+			http.Error(w, fmt.Sprintf("Invalid annotation filter: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to edit annotations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{key: count})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"go-mergepdf/internal/session"
+	"go-mergepdf/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// assetPath resolves a filename sent by a client to a supporting asset of the session.
+func (h *APIHandler) assetPath(session *session.Session, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	path := filepath.Join(h.UploadDir, name)
+	return path, slices.Contains(session.GetAssets(), path)
+}
+
+// inputPath resolves a filename that may name either an asset or a PDF
+// accepted by sourcePath. Unlike sourcePath an empty name is not allowed.
+func (h *APIHandler) inputPath(session *session.Session, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	if path, ok := h.assetPath(session, name); ok {
+		return path, true
+	}
+	return h.sourcePath(session, name)
+}
+
+// UploadAsset godoc
+// @Summary      Upload a supporting file
+// @Description  Uploads a file used by other actions, such as a letterhead template, without adding it to the files
+// @Description  that are merged. Assets are kept when the file order changes and are removed with the session.
+// @Tags         files
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        sessionID  path      string  true  "Session ID"
+// @Param        file       formData  file    true  "Asset file"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, size: int }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session not found"
+// @Router       /api/sessions/{sessionID}/assets [post]
+func (h *APIHandler) UploadAsset(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		http.Error(w, "File too large", http.StatusBadRequest)
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	filename := fmt.Sprintf("asset-%s-%s", utils.GenerateUUID(), utils.SanitizeFilename(handler.Filename))
+	filepath := filepath.Join(h.UploadDir, filename)
+	dst, err := os.Create(filepath)
+	if err != nil {
+		http.Error(w, "Failed to create file", http.StatusInternalServerError)
+		return
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		os.Remove(filepath)
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+
+	session.AddAsset(filepath)
+	writeJSON(w, map[string]interface{}{"filename": filename, "size": handler.Size})
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// originalName returns the name a file was uploaded under, without the asset
+// and UUID prefixes added when it was stored.
+func originalName(path string) string {
+	name := strings.TrimPrefix(filepath.Base(path), "asset-")
+	if len(name) > 37 && name[36] == '-' {
+		if _, err := uuid.Parse(name[:36]); err == nil {
+			return name[37:]
+		}
+	}
+	return filepath.Base(path)
+}
+
+// AttachFiles godoc
+// @Summary      Attach files
+// @Description  Embeds session assets or PDFs into an uploaded file or the current output, e.g. the spreadsheet a
+// @Description  merged report was made from. Files are stored under their original names unless name is given, and
+// @Description  may total at most 25 MB. portfolio makes viewers present the document as a PDF portfolio. Uploads are
+// @Description  changed in place; the output is written to a new download.
+// @Tags         attachments
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, attachments: [{ file, name, description }], portfolio: bool }"
+// @Success      200  {object}  map[string]interface{}  "{ downloadUrl or filename: string, attached: [string] }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/attach [post]
+func (h *APIHandler) AttachFiles(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File        string `json:"file"` // Filename only, empty for the current output
+		Attachments []struct {
+			File        string `json:"file"`        // Asset or PDF of the session
+			Name        string `json:"name"`        // Name in the PDF, the original filename by default
+			Description string `json:"description"` // Shown by viewers next to the name
+		} `json:"attachments"`
+		Portfolio bool `json:"portfolio"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if len(req.Attachments) == 0 {
+		http.Error(w, "No files to attach", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	files := make([]pdf.AttachmentFile, 0, len(req.Attachments))
+	var total int64
+	for _, a := range req.Attachments {
+		path, ok := h.inputPath(session, a.File)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Attachment %q not found in session", a.File), http.StatusNotFound)
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			http.Error(w, "Failed to read attachment", http.StatusInternalServerError)
+			return
+		}
+		total += info.Size()
+
+		name := filepath.Base(strings.TrimSpace(a.Name))
+		if name == "." || name == string(filepath.Separator) {
+			name = originalName(path)
+		}
+		files = append(files, pdf.AttachmentFile{Path: path, Name: name, Description: a.Description})
+	}
+	if total > maxUploadSize {
+		http.Error(w, fmt.Sprintf("Attachments exceed the %d MB limit", maxUploadSize/(1024*1024)), http.StatusBadRequest)
+		return
+	}
+
+	outputPath, filename, inPlace := h.editPath(session, sourcePath, "attached")
+	names, err := pdf.AddAttachments(sourcePath, outputPath, files, req.Portfolio)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		http.Error(w, fmt.Sprintf("Failed to attach files: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, filename, inPlace, map[string]interface{}{"attached": names})
+}
+
+// ListAttachments godoc
+// @Summary      List attachments
+// @Description  Lists the files embedded in a session file or the current output, with their sizes as recorded in the PDF
+// @Tags         attachments
+// @Produce      json
+// @Param        sessionID  path      string  true  "Session ID"
+// @Param        filename   path      string  true  "Uploaded or output filename"
+// @Success      200  {object}  map[string]interface{}  "{ attachments: [{ name, description, size, modified }] }"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/files/{filename}/attachments [get]
+func (h *APIHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	attachments, err := pdf.ListAttachments(sourcePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list attachments: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"attachments": attachments})
+}
+
+// ExtractAttachment godoc
+// @Summary      Download an attachment
+// @Description  Returns a file embedded in a session file or the current output as an octet-stream download, whatever its
+// @Description  name. Attachments over 25 MB are refused.
+// @Tags         attachments
+// @Produce      octet-stream
+// @Param        sessionID  path      string  true  "Session ID"
+// @Param        filename   path      string  true  "Uploaded or output filename"
+// @Param        name       path      string  true  "Attachment name as listed"
+// @Success      200  {file}    binary  "Attachment data"
+// @Failure      404  {string}  string  "Session, file or attachment not found"
+// @Failure      422  {string}  string  "Attachment too large"
+// @Router       /api/sessions/{sessionID}/files/{filename}/attachments/{name} [get]
+func (h *APIHandler) ExtractAttachment(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+	name, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, "Invalid attachment name", http.StatusBadRequest)
+		return
+	}
+
+	data, err := pdf.ExtractAttachment(sourcePath, name, maxUploadSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, pdf.ErrAttachmentNotFound):
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+		case errors.Is(err, pdf.ErrAttachmentTooLarge):
+			http.Error(w, fmt.Sprintf("Attachment exceeds the %d MB limit", maxUploadSize/(1024*1024)), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to extract attachment: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The name comes from the uploaded PDF, so never let it pick a type the
+	// browser would render from this origin.
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(name)}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RemoveBlankPages godoc
+// @Summary      Remove blank pages
+// @Description  Finds pages with nothing drawn on them, or without text and inked below threshold percent (default 0.5),
+// @Description  such as the blank backs of a duplex scan, among the selected pages and removes them. The removed pages
+// @Description  are listed by their page number before removal. With dryRun the pages are only listed. Editing an
+// @Description  uploaded file replaces it in place, so unlike the removeBlankPages merge option the pages are gone from
+// @Description  the upload and can only be put back from a fresh upload of the original with the insert action; editing
+// @Description  the current output makes a new output.
+// @Tags         pages
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, threshold: number, pages: string, dryRun: bool }"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, blankPages: [{ file, page, reason, coverage }] } or { downloadUrl: string, blankPages: [...] }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Failure      422  {string}  string  "All pages are blank"
+// @Router       /api/sessions/{sessionID}/actions/remove-blank-pages [post]
+func (h *APIHandler) RemoveBlankPages(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File   string `json:"file"` // Filename only, empty for the current output
+		DryRun bool   `json:"dryRun"`
+		pdf.BlankPageOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := req.BlankPageOptions.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid blank page options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	if req.DryRun {
+		blank, err := pdf.FindBlankPages(sourcePath, req.BlankPageOptions)
+		if err != nil {
+			if errors.Is(err, pdf.ErrInvalidBlankOptions) {
+				http.Error(w, fmt.Sprintf("Invalid blank page options: %v", err), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to find blank pages: %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"blankPages": blank})
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "noblank")
+	blank, err := pdf.RemoveBlankPages(sourcePath, outputPath, req.BlankPageOptions)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		switch {
+		case errors.Is(err, pdf.ErrInvalidBlankOptions):
+			http.Error(w, fmt.Sprintf("Invalid blank page options: %v", err), http.StatusBadRequest)
+		case errors.Is(err, pdf.ErrAllPagesBlank):
+			http.Error(w, "All pages are blank", http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to remove blank pages: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"blankPages": blank})
+}
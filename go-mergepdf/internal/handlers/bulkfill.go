@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go-mergepdf/internal/pdf"
+	"go-mergepdf/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BulkFillForm godoc
+// @Summary      Fill a form from CSV
+// @Description  Fills the form of a session file or the current output once per CSV row and returns the results as a ZIP,
+// @Description  either one PDF per row or a single combined PDF. The first CSV row holds the column headers. Rows that
+// @Description  fail are reported individually. The ZIP is kept beside the session output, which stays as it was.
+// @Tags         forms
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        sessionID     path      string  true   "Session ID"
+// @Param        csv           formData  file    true   "CSV file"
+// @Param        file          formData  string  false  "Template filename, defaults to the current output"
+// @Param        mapping       formData  string  false  "JSON object mapping CSV columns to field names or IDs"
+// @Param        nameTemplate  formData  string  false  "Filename template such as {lastName}-{firstName}, defaults to row-{row}"
+// @Param        flatten       formData  bool    false  "Flatten the filled fields"
+// @Param        combine       formData  bool    false  "Combine all rows into one PDF"
+// @Success      200  {object}  map[string]interface{}  "{ downloadUrl: string, report: { rows, filled, files, errors: [{ row, error }] } }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Failure      422  {object}  map[string]interface{}  "{ error: string, report: object } when no row could be filled"
+// @Router       /api/sessions/{sessionID}/actions/bulkfill [post]
+func (h *APIHandler) BulkFillForm(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		http.Error(w, "File too large", http.StatusBadRequest)
+		return
+	}
+
+	csvFile, _, err := r.FormFile("csv")
+	if err != nil {
+		http.Error(w, "Error retrieving CSV file", http.StatusBadRequest)
+		return
+	}
+	defer csvFile.Close()
+
+	var opts pdf.BulkFillOptions
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			http.Error(w, "Invalid mapping", http.StatusBadRequest)
+			return
+		}
+	}
+	opts.NameTemplate = r.FormValue("nameTemplate")
+	for name, dst := range map[string]*bool{"flatten": &opts.Flatten, "combine": &opts.Combine} {
+		if v := r.FormValue(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s value", name), http.StatusBadRequest)
+				return
+			}
+			*dst = b
+		}
+	}
+
+	sourcePath, ok := h.sourcePath(session, r.FormValue("file"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputFilename := fmt.Sprintf("bulkfill-%s.zip", utils.GenerateUUID())
+	outputPath := filepath.Join(h.OutputDir, outputFilename)
+	report, err := pdf.BulkFillForms(sourcePath, csvFile, outputPath, opts)
+	if err != nil {
+		os.Remove(outputPath)
+		if errors.Is(err, pdf.ErrInvalidBulkFill) {
+			http.Error(w, fmt.Sprintf("Invalid bulk fill request: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to fill forms: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if report.Filled == 0 {
+		os.Remove(outputPath)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "No rows could be filled",
+			"report": report,
+		})
+		return
+	}
+
+	session.AddArtifact(outputPath)
+
+	writeJSON(w, map[string]interface{}{
+		"downloadUrl": fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, outputFilename),
+		"report":      report,
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go-mergepdf/internal/pdf"
+	"go-mergepdf/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ComparePDFs godoc
+// @Summary      Compare two PDFs
+// @Description  Compares a revised session file with the original, e.g. a signed copy with the draft that was sent out.
+// @Description  Reports the page counts, differing document information and every page that was added, removed or
+// @Description  changed, with the text lines and images added or removed on it. Pages are matched by their text, so an
+// @Description  inserted page does not mark every later page as changed. With report set, the differences are also
+// @Description  written as a PDF with added content highlighted in green and removed content in red. The report is kept
+// @Description  beside the session output, which stays as it was.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ original: string, revised: string, report: bool }"
+// @Success      200  {object}  map[string]interface{}  "{ comparison: { sameContent, originalPageCount, revisedPageCount, metadata: [{ field, original, revised }], pages: [{ originalPage, revisedPage, status, addedText, removedText, addedImages, removedImages, otherContent }] }, downloadUrl: string with report }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/compare [post]
+func (h *APIHandler) ComparePDFs(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Original string `json:"original"` // Filename only, empty for the current output
+		Revised  string `json:"revised"`  // Filename only, empty for the current output
+		Report   bool   `json:"report"`   // Also write the differences as a PDF
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	originalPath, ok := h.sourcePath(session, req.Original)
+	if !ok {
+		http.Error(w, "Original file not found in session", http.StatusNotFound)
+		return
+	}
+	revisedPath, ok := h.sourcePath(session, req.Revised)
+	if !ok {
+		http.Error(w, "Revised file not found in session", http.StatusNotFound)
+		return
+	}
+	// An empty name and the output filename select the same file.
+	if originalPath == revisedPath {
+		http.Error(w, "original and revised must be different files", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := pdf.ComparePDFs(originalPath, revisedPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compare PDFs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	result := map[string]interface{}{"comparison": comparison}
+
+	if req.Report {
+		outputFilename := fmt.Sprintf("compare-%s.pdf", utils.GenerateUUID())
+		outputPath := filepath.Join(h.OutputDir, outputFilename)
+		if err := pdf.WriteCompareReport(comparison, outputPath, displayName(originalPath), displayName(revisedPath)); err != nil {
+			os.Remove(outputPath)
+			http.Error(w, fmt.Sprintf("Failed to write comparison report: %v", err), http.StatusInternalServerError)
+			return
+		}
+		session.AddArtifact(outputPath)
+		result["downloadUrl"] = fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, outputFilename)
+	}
+
+	writeJSON(w, result)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CropPDF godoc
+// @Summary      Crop page margins
+// @Description  Sets the crop box, trim box or both of selected pages, cutting fixed margins in points or percent, or
+// @Description  trimming each page to its content with optional padding. Cropping an uploaded file replaces it in place,
+// @Description  so the cropped pages are what gets merged; cropping the current output makes a new output.
+// @Tags         pages
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, pages: string, box: crop|trim|both, unit: pt|percent, top, right, bottom, left: number, auto: bool, padding: number }"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, cropped: int } or { downloadUrl: string, cropped: int }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/crop [post]
+func (h *APIHandler) CropPDF(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+		pdf.CropOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := req.CropOptions.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid crop: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "cropped")
+	count, err := pdf.CropPDF(sourcePath, outputPath, req.CropOptions)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		if errors.Is(err, pdf.ErrInvalidCrop) {
+			http.Error(w, fmt.Sprintf("Invalid crop: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to crop PDF: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"cropped": count})
+}
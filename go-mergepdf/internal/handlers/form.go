@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// formValues converts the JSON values of a fill request to the string form
+// expected by pdf.FillFormFields. Arrays select several list box options.
+func formValues(raw map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case string:
+			values[k] = v
+		case bool:
+			values[k] = strconv.FormatBool(v)
+		case float64:
+			values[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+			values[k] = ""
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("field %s: list values must be strings", k)
+				}
+				parts = append(parts, s)
+			}
+			values[k] = strings.Join(parts, ",")
+		default:
+			return nil, fmt.Errorf("field %s: unsupported value", k)
+		}
+	}
+	return values, nil
+}
+
+// ListFormFields godoc
+// @Summary      List form fields
+// @Description  Lists the AcroForm fields of a session file or the current output with their type, value, options and pages.
+// @Tags         forms
+// @Produce      json
+// @Param        sessionID  path      string  true  "Session ID"
+// @Param        filename   path      string  true  "Uploaded or output filename"
+// @Success      200  {object}  map[string]interface{}  "{ fields: [{ name, id, type, value, default, options, pages, locked, multiple }] }"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/files/{filename}/fields [get]
+func (h *APIHandler) ListFormFields(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	fields, err := pdf.ListFormFields(sourcePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read form fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"fields": fields})
+}
+
+// FillForm godoc
+// @Summary      Fill form fields
+// @Description  Fills the AcroForm fields of a session file or the current output, matching fields by name or ID.
+// @Description  Check boxes take booleans, list boxes take an array of options. With flatten set the fields become
+// @Description  static content. Filling an uploaded file replaces it in place; filling the current output makes a new output.
+// @Tags         forms
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, values: { <field>: value }, flatten: bool }"
+// @Success      200  {object}  map[string]string  "{ filename: string } or { downloadUrl: string }"
+// @Failure      400  {string}  string  "Bad request or unknown field"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/fill [post]
+func (h *APIHandler) FillForm(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File    string                 `json:"file"` // Filename only, empty for the current output
+		Values  map[string]interface{} `json:"values"`
+		Flatten bool                   `json:"flatten"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if len(req.Values) == 0 {
+		http.Error(w, "No field values provided", http.StatusBadRequest)
+		return
+	}
+	values, err := formValues(req.Values)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid field values: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "filled")
+	if err := pdf.FillFormFields(sourcePath, outputPath, values, req.Flatten); err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		if errors.Is(err, pdf.ErrUnknownFormField) || errors.Is(err, pdf.ErrInvalidFormValue) {
+			http.Error(w, fmt.Sprintf("Invalid field values: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to fill form: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{})
+}
+
+// FlattenForm godoc
+// @Summary      Flatten form fields
+// @Description  Draws the form fields of a session file or the current output into the page content and removes the form.
+// @Description  Flattening an uploaded file replaces it in place; flattening the current output makes a new output.
+// @Tags         forms
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string }"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, flattened: int } or { downloadUrl: string, flattened: int }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/flatten [post]
+func (h *APIHandler) FlattenForm(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "flattened")
+	count, err := pdf.FlattenForm(sourcePath, outputPath)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		http.Error(w, fmt.Sprintf("Failed to flatten form: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"flattened": count})
+}
@@ -1,7 +1,8 @@
 // Package handlers provides HTTP handlers for the PDF merging API.
 //
 // This package contains the main HTTP endpoints for session management,
-// file upload, file ordering, PDF merging, and download.
+// file upload, file ordering, PDF merging, and download. Follow-up actions on
+// session files live in their own files next to this one.
 //
 // Example usage:
 //
@@ -14,13 +15,17 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
 	"time"
@@ -30,16 +35,88 @@ import (
 	"go-mergepdf/internal/utils"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
+// maxUploadSize limits uploaded PDFs and assets, and the files attached to or
+// extracted from a PDF.
+const maxUploadSize = 25 * 1024 * 1024
+
 type APIHandler struct {
-	SessionManager *session.SessionManager
-	UploadDir      string
-	OutputDir      string
+	SessionManager    *session.SessionManager
+	UploadDir         string
+	OutputDir         string
+	ThumbnailRenderer string        // Optional pdftoppm compatible binary for thumbnails
+	CleanupDelay      time.Duration // How long a session lingers after its output was downloaded
+
+	thumbnails  *thumbnailCache
+	renderSlots chan struct{} // Limits concurrent thumbnail rendering
 }
 
 func NewAPIHandler(sm *session.SessionManager, uploadDir, outputDir string) *APIHandler {
-	return &APIHandler{SessionManager: sm, UploadDir: uploadDir, OutputDir: outputDir}
+	return &APIHandler{
+		SessionManager: sm,
+		UploadDir:      uploadDir,
+		OutputDir:      outputDir,
+		CleanupDelay:   time.Second,
+		thumbnails:     newThumbnailCache(),
+		renderSlots:    make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+// sourcePath resolves a filename sent by a client to a PDF owned by the session.
+// An empty name or the output filename selects the current output file, as
+// long as it is a PDF rather than an archive; any other name must be one of
+// the session's uploads.
+func (h *APIHandler) sourcePath(session *session.Session, name string) (string, bool) {
+	outputFile := session.GetOutputFile()
+	if name == "" || filepath.Join(h.OutputDir, name) == outputFile {
+		return outputFile, filepath.Ext(outputFile) == ".pdf"
+	}
+	path := filepath.Join(h.UploadDir, name)
+	return path, slices.Contains(session.GetFiles(), path)
+}
+
+// editPath returns where an action that edits sourcePath writes its result.
+// Uploads are replaced in place so they keep their name and merge position;
+// the output is written to a new output file named prefix-<uuid>.pdf.
+func (h *APIHandler) editPath(session *session.Session, sourcePath, prefix string) (path, filename string, inPlace bool) {
+	if sourcePath != session.GetOutputFile() {
+		return sourcePath, filepath.Base(sourcePath), true
+	}
+	filename = fmt.Sprintf("%s-%s.pdf", prefix, utils.GenerateUUID())
+	return filepath.Join(h.OutputDir, filename), filename, false
+}
+
+// writeEditResult responds to an action that edited a file in place or made
+// a new output, adding the entries in extra.
+func (h *APIHandler) writeEditResult(w http.ResponseWriter, session *session.Session, outputPath, filename string, inPlace bool, extra map[string]interface{}) {
+	if inPlace {
+		extra["filename"] = filename
+	} else {
+		session.SetOutputFile(outputPath)
+		extra["downloadUrl"] = fmt.Sprintf("/api/sessions/%s/files/%s", session.ID, filename)
+	}
+	writeJSON(w, extra)
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writePDFAError responds that the merge cannot be made PDF/A-2b, listing
+// the issues in the way.
+func writePDFAError(w http.ResponseWriter, issues []pdf.PDFAIssue) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "The merged PDF cannot be converted to PDF/A-2b",
+		"issues": issues,
+	})
 }
 
 // CreateSession godoc
@@ -55,17 +132,74 @@ func (h *APIHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"sessionId": "%s"}`, session.ID)
 }
 
+// sessionInfo is the inspection view of a session returned by GetSession.
+type sessionInfo struct {
+	SessionID   string        `json:"sessionId"`
+	Files       []string      `json:"files"`
+	Assets      []string      `json:"assets"`
+	OutputFile  string        `json:"outputFile,omitempty"`
+	MergeStatus string        `json:"mergeStatus"`
+	Metadata    *pdf.Metadata `json:"metadata,omitempty"`
+}
+
+// GetSession godoc
+// @Summary      Inspect a session
+// @Description  Returns the uploaded files, assets, merge status and output file of a session, including the output's document metadata
+// @Tags         sessions
+// @Produce      json
+// @Param        sessionID  path      string  true  "Session ID"
+// @Success      200  {object}  map[string]interface{}  "{ sessionId: string, files: [string], assets: [string], outputFile: string, mergeStatus: string, metadata: object }"
+// @Failure      404  {string}  string  "Session not found"
+// @Router       /api/sessions/{sessionID} [get]
+func (h *APIHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	info := sessionInfo{SessionID: session.ID, Files: []string{}, Assets: []string{}}
+	for _, file := range session.GetFiles() {
+		info.Files = append(info.Files, filepath.Base(file))
+	}
+	for _, file := range session.GetAssets() {
+		info.Assets = append(info.Assets, filepath.Base(file))
+	}
+	session.Mutex.Lock()
+	info.MergeStatus = session.MergeStatus
+	outputFile := session.OutputFile
+	session.Mutex.Unlock()
+
+	if outputFile != "" {
+		info.OutputFile = filepath.Base(outputFile)
+	}
+	if filepath.Ext(outputFile) == ".pdf" {
+		md, err := pdf.ReadMetadata(outputFile)
+		if err != nil {
+			log.Printf("Error reading output metadata: %v", err)
+		}
+		info.Metadata = md
+	}
+	writeJSON(w, info)
+}
+
 // UploadFile godoc
 // @Summary      Upload a PDF file
-// @Description  Uploads a PDF file to the session
+// @Description  Uploads a PDF file to the session. The file is validated in relaxed and strict mode; files that fail
+// @Description  relaxed validation, e.g. because of a broken cross-reference table, are repaired and replaced by the
+// @Description  repaired copy unless repair is false. Files that cannot be repaired are rejected with the diagnostics.
+// @Description  sha256 is the hash of the uploaded content; duplicateOf lists session files with the same content.
 // @Tags         files
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        sessionID  path      string  true  "Session ID"
-// @Param        pdf        formData  file    true  "PDF file"
-// @Success      200  {object}  map[string]interface{}  "{ filename: string, size: int }"
+// @Param        sessionID  path      string  true   "Session ID"
+// @Param        pdf        formData  file    true   "PDF file"
+// @Param        repair     formData  bool    false  "Repair damaged files, true by default"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, size: int, sha256: string, duplicateOf: [string], validation: { valid, strict, repaired: bool, issues: [{ severity, message }] } }"
 // @Failure      400  {string}  string  "Bad request"
 // @Failure      404  {string}  string  "Session not found"
+// @Failure      422  {object}  map[string]interface{}  "{ error: string, validation: object }"
 // @Router       /api/sessions/{sessionID}/files [post]
 func (h *APIHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "sessionID")
@@ -75,7 +209,6 @@ func (h *APIHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	const maxUploadSize = 25 * 1024 * 1024
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
 		http.Error(w, "File too large", http.StatusBadRequest)
@@ -145,16 +278,76 @@ func (h *APIHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to create file", http.StatusInternalServerError)
 		return
 	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dst, hasher), file)
+	dst.Close()
+	if err != nil {
+		os.Remove(filepath)
 		http.Error(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	report, err := validateUpload(filepath, r.FormValue("repair") != "false")
+	if err != nil {
+		os.Remove(filepath)
+		http.Error(w, "Failed to validate file", http.StatusInternalServerError)
+		return
+	}
+	if !report.Valid {
+		os.Remove(filepath)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      "Uploaded PDF is damaged",
+			"validation": report,
+		})
+		return
+	}
 
+	// Uploading the same file twice is allowed but flagged.
+	duplicateOf := baseNames(session.FilesWithHash(hash))
 	session.AddFile(filepath)
-	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"filename": "%s", "size": %d}`, filename, handler.Size)
+	session.SetFileHash(filepath, hash)
+	writeJSON(w, map[string]interface{}{
+		"filename":    filename,
+		"size":        handler.Size,
+		"sha256":      hash,
+		"duplicateOf": duplicateOf,
+		"validation":  report,
+	})
+}
+
+// baseNames returns the file names of paths, never nil.
+func baseNames(paths []string) []string {
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
+
+// validateUpload validates an uploaded PDF and, if repair is set and it is
+// damaged, replaces it with a repaired copy. The issues found before the
+// repair stay in the report.
+func validateUpload(path string, repair bool) (*pdf.ValidationReport, error) {
+	report, err := pdf.ValidatePDF(path)
+	if err != nil || report.Valid || !repair {
+		return report, err
+	}
+	if err := pdf.RepairPDF(path, path); err != nil {
+		log.Printf("Failed to repair %s: %v", filepath.Base(path), err)
+		report.Issues = append(report.Issues, pdf.ValidationIssue{Severity: "error", Message: err.Error()})
+		return report, nil
+	}
+
+	repaired, err := pdf.ValidatePDF(path)
+	if err != nil {
+		return nil, err
+	}
+	repaired.Repaired = true
+	repaired.Issues = append(report.Issues, repaired.Issues...)
+	return repaired, nil
 }
 
 // UpdateOrder godoc
@@ -205,16 +398,102 @@ func (h *APIHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"success": true}`)
 }
 
+// mergeOptions are the optional settings accepted in the MergeFiles request body.
+type mergeOptions struct {
+	Metadata         pdf.MetadataOptions   `json:"metadata"`
+	RenameFormFields bool                  `json:"renameFormFields"` // Prefix form fields per file to avoid name collisions
+	PageSize         *pdf.PageSizeOptions  `json:"pageSize"`
+	Mode             string                `json:"mode"`               // "sequential" (default) or "interleave"
+	ReverseSecond    bool                  `json:"reverseSecond"`      // Interleave the second file back to front
+	PDFA             string                `json:"pdfa"`               // "2b" converts the output to PDF/A-2b
+	Linearize        bool                  `json:"linearize"`          // Write a linearized ("fast web view") file
+	PageLabels       pdf.PageLabels        `json:"pageLabels"`         // Label ranges on the output, over the source labels
+	TOC              *pdf.TOCOptions       `json:"toc"`                // Contents page in front of the output
+	Split            *pdf.SplitOptions     `json:"split"`              // Deliver the output as parts in a ZIP archive
+	DropDuplicates   bool                  `json:"dropDuplicatePages"` // Leave out pages that repeat an earlier page
+	RemoveBlank      *pdf.BlankPageOptions `json:"removeBlankPages"`   // Leave out blank pages, such as scanned backs
+}
+
+// applyMergeOptions post-processes the PDF merged from files at outputPath
+// according to opts.
+func applyMergeOptions(files []string, outputPath string, opts mergeOptions) error {
+	if opts.PageSize != nil {
+		if err := pdf.NormalizePageSizes(outputPath, outputPath, *opts.PageSize); err != nil {
+			return fmt.Errorf("failed to normalize page sizes: %w", err)
+		}
+	}
+	// Source labels only line up with the output when files are merged in
+	// sequence.
+	labels := pdf.PageLabels{}
+	if opts.Mode != "interleave" {
+		var err error
+		if labels, err = pdf.MergedPageLabels(files); err != nil {
+			return fmt.Errorf("failed to read page labels: %w", err)
+		}
+	}
+	if err := pdf.SetPageLabels(outputPath, outputPath, labels.With(opts.PageLabels)); err != nil {
+		return fmt.Errorf("failed to set page labels: %w", err)
+	}
+	if opts.TOC != nil {
+		titles := make([]string, len(files))
+		for i, file := range files {
+			titles[i] = displayName(file)
+		}
+		entries, err := pdf.TOCEntries(files, titles, opts.TOC.Bookmarks)
+		if err != nil {
+			return fmt.Errorf("failed to list contents: %w", err)
+		}
+		if _, err := pdf.AddTOC(outputPath, outputPath, entries, *opts.TOC); err != nil {
+			return fmt.Errorf("failed to add contents page: %w", err)
+		}
+	}
+	// Every step above rewrites the file, stamping pdfcpu's own Producer and
+	// dates, so metadata is set last. PDF/A conversion and linearization in
+	// MergeFiles come later but keep it: the first carries the Info entries
+	// over into the XMP, the second copies the objects as they are.
+	if err := pdf.SetMetadata(outputPath, outputPath, opts.Metadata); err != nil {
+		return fmt.Errorf("failed to set metadata: %w", err)
+	}
+	return nil
+}
+
+// displayName returns the name a file was uploaded under as a label, without
+// its extension and with underscores as spaces.
+func displayName(path string) string {
+	name := originalName(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.ReplaceAll(name, "_", " ")
+}
+
 // MergeFiles godoc
 // @Summary      Merge uploaded files
-// @Description  Merges all uploaded files in the session and returns a download URL
+// @Description  Merges all uploaded files in the session and returns a download URL.
+// @Description  The optional body can set or strip document metadata on the merged output and prefix the form
+// @Description  fields of each file with doc<n>_ so identical forms keep separate values. pageSize scales every page
+// @Description  to A4, Letter, Legal or a custom size in points, using fit, fill or center mode with optional margins.
+// @Description  mode "interleave" alternates the pages of exactly two files, optionally reversing the second.
+// @Description  pdfa "2b" converts the output to PDF/A-2b; if that is not possible the merge fails with the list of issues.
+// @Description  linearize writes a linearized file that browser viewers can display before it has fully downloaded.
+// @Description  Page labels of the source files are kept; pageLabels adds ranges from a 1-based page with a style
+// @Description  (decimal, roman, lower-roman, letters, lower-letters or none), a prefix and a start number.
+// @Description  toc puts clickable contents pages in front listing each file and, with bookmarks, its top-level bookmarks.
+// @Description  split delivers the output as a ZIP of parts named merged-part<i>-of-<n>.pdf, each within maxPages pages
+// @Description  and maxBytes bytes; with keepFilesTogether parts only end between source files.
+// @Description  dropDuplicatePages leaves out pages whose content repeats an earlier page and reports them as removedPages.
+// @Description  removeBlankPages leaves out pages with nothing drawn on them, or without text and inked below threshold
+// @Description  percent (default 0.5), checking the pages selection of each file, and reports them as blankPages.
+// @Description  The uploads are left as they are, so merging again without the option brings the pages back.
+// @Description  A session can be merged again at any time; the new output replaces the previous one.
 // @Tags         files
+// @Accept       json
 // @Produce      json
-// @Param        sessionID  path      string  true  "Session ID"
-// @Success      200  {object}  map[string]string  "{ downloadUrl: string }"
+// @Param        sessionID  path      string  true   "Session ID"
+// @Param        options    body      object  false  "{ metadata: { title, author, subject, keywords, creator, producer, creationDate, modDate, strip }, renameFormFields: bool, pageSize: { size, width, height, mode, margin }, mode: sequential|interleave, reverseSecond: bool, pdfa: 2b, linearize: bool, pageLabels: [{ page, style, prefix, start }], toc: { title, bookmarks }, split: { maxPages, maxBytes, keepFilesTogether }, dropDuplicatePages: bool, removeBlankPages: { threshold, pages } }"
+// @Success      200  {object}  map[string]interface{}  "{ downloadUrl: string, parts: [{ name, firstPage, lastPage, size, oversized }] with split, removedPages: [{ file, page, duplicateOf: { file, page } }] with dropDuplicatePages, blankPages: [{ file, page, reason, coverage }] with removeBlankPages }"
 // @Failure      400  {string}  string  "No files to merge"
 // @Failure      404  {string}  string  "Session not found"
-// @Failure      409  {string}  string  "Merge already in progress or done"
+// @Failure      409  {string}  string  "Merge already in progress"
+// @Failure      422  {object}  map[string]interface{}  "{ error: string, issues: [{ code, message, fixable }] } when PDF/A conversion is not possible, or a message when all pages are blank"
 // @Router       /api/sessions/{sessionID}/actions/merge [post]
 func (h *APIHandler) MergeFiles(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "sessionID")
@@ -224,17 +503,66 @@ func (h *APIHandler) MergeFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Options are optional; an empty body merges with defaults
+	var opts mergeOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+		http.Error(w, "Invalid merge options", http.StatusBadRequest)
+		return
+	}
+	if opts.PageSize != nil {
+		if err := opts.PageSize.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid merge options: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if opts.Mode != "" && opts.Mode != "sequential" && opts.Mode != "interleave" {
+		http.Error(w, "Invalid merge options: unknown mode", http.StatusBadRequest)
+		return
+	}
+	if err := opts.PageLabels.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid merge options: %v", err), http.StatusBadRequest)
+		return
+	}
+	if opts.TOC != nil && opts.Mode == "interleave" {
+		http.Error(w, "Invalid merge options: a contents page needs a sequential merge", http.StatusBadRequest)
+		return
+	}
+	if opts.PDFA != "" && opts.PDFA != "2b" {
+		http.Error(w, "Invalid merge options: only PDF/A-2b output is supported", http.StatusBadRequest)
+		return
+	}
+	if opts.Split != nil {
+		if err := opts.Split.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid merge options: %v", err), http.StatusBadRequest)
+			return
+		}
+		if opts.Split.KeepFilesTogether && opts.Mode == "interleave" {
+			http.Error(w, "Invalid merge options: interleaved files cannot be kept together", http.StatusBadRequest)
+			return
+		}
+	}
+	if opts.DropDuplicates && opts.Mode == "interleave" {
+		http.Error(w, "Invalid merge options: duplicate pages cannot be dropped when interleaving", http.StatusBadRequest)
+		return
+	}
+	if opts.RemoveBlank != nil {
+		if err := opts.RemoveBlank.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid merge options: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if opts.TOC != nil && opts.PDFA != "" {
+		// The contents pages use a standard font, which PDF/A requires to be embedded.
+		http.Error(w, "Invalid merge options: a contents page cannot be converted to PDF/A", http.StatusBadRequest)
+		return
+	}
+
 	session.Mutex.Lock()
 	if session.MergeStatus == "in_progress" {
 		session.Mutex.Unlock()
 		http.Error(w, "Merge already in progress", http.StatusConflict)
 		return
 	}
-	if session.MergeStatus == "done" {
-		session.Mutex.Unlock()
-		http.Error(w, "Files already merged", http.StatusConflict)
-		return
-	}
 	session.MergeStatus = "in_progress"
 	session.Mutex.Unlock()
 
@@ -246,10 +574,72 @@ func (h *APIHandler) MergeFiles(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "No files to merge", http.StatusBadRequest)
 		return
 	}
+	if opts.Mode == "interleave" && len(files) != 2 {
+		session.Mutex.Lock()
+		session.MergeStatus = "idle"
+		session.Mutex.Unlock()
+		http.Error(w, "Interleaving needs exactly two files", http.StatusBadRequest)
+		return
+	}
+
+	// Files that lose pages are merged from copies without them. Interleaved
+	// files keep their blank pages until they are paired up.
+	var blankPages []pdf.BlankPage
+	if opts.RemoveBlank != nil && opts.Mode != "interleave" {
+		blankDir, err := os.MkdirTemp(h.OutputDir, "blank-")
+		if err == nil {
+			defer os.RemoveAll(blankDir)
+			files, blankPages, err = pdf.DropBlankPages(files, blankDir, *opts.RemoveBlank)
+		}
+		if err != nil {
+			session.Mutex.Lock()
+			session.MergeStatus = "idle"
+			session.Mutex.Unlock()
+			if errors.Is(err, pdf.ErrAllPagesBlank) {
+				http.Error(w, "All pages are blank", http.StatusUnprocessableEntity)
+				return
+			}
+			log.Printf("Error dropping blank pages: %v", err)
+			http.Error(w, "Failed to merge PDFs", http.StatusInternalServerError)
+			return
+		}
+	}
+	var removedPages []pdf.RemovedPage
+	if opts.DropDuplicates {
+		dedupDir, err := os.MkdirTemp(h.OutputDir, "dedup-")
+		if err == nil {
+			defer os.RemoveAll(dedupDir)
+			files, removedPages, err = pdf.DropDuplicatePages(files, dedupDir)
+		}
+		if err != nil {
+			session.Mutex.Lock()
+			session.MergeStatus = "idle"
+			session.Mutex.Unlock()
+			log.Printf("Error dropping duplicate pages: %v", err)
+			http.Error(w, "Failed to merge PDFs", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	outputFilename := fmt.Sprintf("merged-%s.pdf", utils.GenerateUUID())
 	outputPath := filepath.Join(h.OutputDir, outputFilename)
-	if err := pdf.MergePDFs(files, outputPath); err != nil {
+	// A merge that fails at any step leaves no output behind.
+	merged := false
+	defer func() {
+		if !merged {
+			os.Remove(outputPath)
+		}
+	}()
+	merge := pdf.MergePDFs
+	switch {
+	case opts.Mode == "interleave":
+		merge = func(files []string, outputPath string) error {
+			return pdf.InterleavePDFs(files[0], files[1], outputPath, opts.ReverseSecond)
+		}
+	case opts.RenameFormFields:
+		merge = pdf.MergeFormPDFs
+	}
+	if err := merge(files, outputPath); err != nil {
 		session.Mutex.Lock()
 		session.MergeStatus = "idle"
 		session.Mutex.Unlock()
@@ -257,6 +647,21 @@ func (h *APIHandler) MergeFiles(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to merge PDFs", http.StatusInternalServerError)
 		return
 	}
+	if opts.RemoveBlank != nil && opts.Mode == "interleave" {
+		var err error
+		if blankPages, err = pdf.DropInterleavedBlankPages(files[0], files[1], outputPath, opts.ReverseSecond, *opts.RemoveBlank); err != nil {
+			session.Mutex.Lock()
+			session.MergeStatus = "idle"
+			session.Mutex.Unlock()
+			if errors.Is(err, pdf.ErrAllPagesBlank) {
+				http.Error(w, "All pages are blank", http.StatusUnprocessableEntity)
+				return
+			}
+			log.Printf("Error dropping blank pages: %v", err)
+			http.Error(w, "Failed to merge PDFs", http.StatusInternalServerError)
+			return
+		}
+	}
 	if err := pdf.RemoveBookmarks(outputPath); err != nil {
 		session.Mutex.Lock()
 		session.MergeStatus = "idle"
@@ -265,25 +670,135 @@ func (h *APIHandler) MergeFiles(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to process merged PDF", http.StatusInternalServerError)
 		return
 	}
+	if err := applyMergeOptions(files, outputPath, opts); err != nil {
+		session.Mutex.Lock()
+		session.MergeStatus = "idle"
+		session.Mutex.Unlock()
+		if errors.Is(err, pdf.ErrInvalidPageLabels) {
+			http.Error(w, fmt.Sprintf("Invalid merge options: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error applying merge options: %v", err)
+		http.Error(w, "Failed to process merged PDF", http.StatusInternalServerError)
+		return
+	}
+	if opts.PDFA != "" && opts.Split == nil {
+		conformance, err := pdf.ConvertToPDFA(outputPath, outputPath)
+		if err != nil {
+			session.Mutex.Lock()
+			session.MergeStatus = "idle"
+			session.Mutex.Unlock()
+			if errors.Is(err, pdf.ErrPDFAConversion) {
+				writePDFAError(w, conformance.Issues)
+				return
+			}
+			log.Printf("Error converting to PDF/A: %v", err)
+			http.Error(w, "Failed to process merged PDF", http.StatusInternalServerError)
+			return
+		}
+	}
+	if opts.Linearize && opts.Split == nil {
+		if err := pdf.LinearizePDF(outputPath, outputPath); err != nil {
+			session.Mutex.Lock()
+			session.MergeStatus = "idle"
+			session.Mutex.Unlock()
+			log.Printf("Error linearizing PDF: %v", err)
+			http.Error(w, "Failed to process merged PDF", http.StatusInternalServerError)
+			return
+		}
+	}
+	if opts.Split != nil {
+		// Parts are rewritten, so PDF/A and linearization are applied to each.
+		var pdfaIssues []pdf.PDFAIssue
+		finish := func(path string) error {
+			if opts.PDFA != "" {
+				conformance, err := pdf.ConvertToPDFA(path, path)
+				if err != nil {
+					if conformance != nil {
+						pdfaIssues = conformance.Issues
+					}
+					return err
+				}
+			}
+			if opts.Linearize {
+				return pdf.LinearizePDF(path, path)
+			}
+			return nil
+		}
+		// The merged PDF is only needed to cut the parts from.
+		zipFilename := strings.TrimSuffix(outputFilename, ".pdf") + ".zip"
+		zipPath := filepath.Join(h.OutputDir, zipFilename)
+		parts, err := pdf.SplitPDF(outputPath, zipPath, "merged", files, *opts.Split, finish)
+		if err != nil {
+			os.Remove(zipPath)
+			session.Mutex.Lock()
+			session.MergeStatus = "idle"
+			session.Mutex.Unlock()
+			if errors.Is(err, pdf.ErrPDFAConversion) {
+				writePDFAError(w, pdfaIssues)
+				return
+			}
+			log.Printf("Error splitting PDF: %v", err)
+			http.Error(w, "Failed to process merged PDF", http.StatusInternalServerError)
+			return
+		}
+		session.SetOutputFile(zipPath)
+		session.Mutex.Lock()
+		session.MergeStatus = "done"
+		session.Mutex.Unlock()
+		result := map[string]interface{}{
+			"downloadUrl": fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, zipFilename),
+			"parts":       parts,
+		}
+		if opts.DropDuplicates {
+			result["removedPages"] = removedPages
+		}
+		if opts.RemoveBlank != nil {
+			result["blankPages"] = blankPages
+		}
+		writeJSON(w, result)
+		return
+	}
+	merged = true
+	session.SetOutputFile(outputPath)
 	session.Mutex.Lock()
-	session.OutputFile = outputPath
 	session.MergeStatus = "done"
 	session.Mutex.Unlock()
 	downloadURL := fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, outputFilename)
+	if opts.DropDuplicates || opts.RemoveBlank != nil {
+		result := map[string]interface{}{"downloadUrl": downloadURL}
+		if opts.DropDuplicates {
+			result["removedPages"] = removedPages
+		}
+		if opts.RemoveBlank != nil {
+			result["blankPages"] = blankPages
+		}
+		writeJSON(w, result)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	fmt.Fprintf(w, `{"downloadUrl": "%s"}`, downloadURL)
 }
 
 // DownloadFile godoc
 // @Summary      Download merged PDF
-// @Description  Downloads the merged PDF file for the session
+// @Description  Downloads the merged PDF file for the session, or the ZIP archive produced by actions with several results.
+// @Description  Archives and reports made besides the output, such as extracted images, are downloaded here as well
+// @Description  under their downloadUrl; they keep the session and its output.
+// @Description  Range requests are supported so PDF viewers can load a linearized file page by page. With inline=true the
+// @Description  file is served for display in the browser; the session is then kept until it expires instead of being
+// @Description  deleted after the download, as are sessions whose file was fetched in ranges.
 // @Tags         files
-// @Produce      application/pdf
-// @Param        sessionID  path      string  true  "Session ID"
-// @Param        filename   path      string  true  "Merged PDF filename"
+// @Produce      application/pdf,application/zip
+// @Param        sessionID  path      string  true   "Session ID"
+// @Param        filename   path      string  true   "Merged PDF filename"
+// @Param        inline     query     bool    false  "Serve for display instead of as an attachment"
+// @Param        Range      header    string  false  "Byte range, e.g. bytes=0-1023"
 // @Success      200  {file}  file  "PDF file download"
+// @Success      206  {file}  file  "Requested byte range"
 // @Failure      403  {string}  string  "Unauthorized access to file"
 // @Failure      404  {string}  string  "Session or file not found"
+// @Failure      416  {string}  string  "Range not satisfiable"
 // @Router       /api/sessions/{sessionID}/files/{filename} [get]
 func (h *APIHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "sessionID")
@@ -294,24 +809,68 @@ func (h *APIHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	filepath := filepath.Join(h.OutputDir, filename)
-	if session.OutputFile != filepath {
+	artifact := session.GetOutputFile() != filepath
+	if artifact && !session.HasArtifact(filepath) {
 		http.Error(w, "Unauthorized access to file", http.StatusForbidden)
 		return
 	}
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
 		http.Error(w, "File not found", http.StatusNotFound)
 		return
 	}
-	w.Header().Set("Content-Disposition", "attachment; filename=\"merged.pdf\"")
-	w.Header().Set("Content-Type", "application/pdf")
-	http.ServeFile(w, r, filepath)
+
+	disposition := "attachment"
+	inline := r.URL.Query().Get("inline") == "true"
+	if inline {
+		disposition = "inline"
+	}
+	name, contentType := "merged.pdf", "application/pdf"
+	if strings.HasSuffix(filename, ".zip") {
+		name, contentType = "merged.zip", "application/zip"
+	}
+	if artifact {
+		name = artifactName(filename)
+	}
+	w.Header().Set("Content-Disposition", disposition+"; filename=\""+name+"\"")
+	w.Header().Set("Content-Type", contentType)
+	// Outputs get a new name whenever they change, but an ETag lets If-Range
+	// requests detect a replaced file reliably.
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(w, r, filename, info.ModTime(), f)
+
+	// Viewers fetching ranges or displaying the file inline come back for
+	// more, so only a complete download ends the session. Artifacts come
+	// beside the output, which is still to be downloaded.
+	if artifact || inline || r.Method != http.MethodGet || r.Header.Get("Range") != "" {
+		return
+	}
 	go func() {
-		time.Sleep(1 * time.Second)
+		time.Sleep(h.CleanupDelay)
 		session.Cleanup()
 		h.SessionManager.DeleteSession(sessionID)
 	}()
 }
 
+// artifactName returns the download name of an artifact stored as
+// <kind>-<uuid><ext>, which is <kind><ext>.
+func artifactName(filename string) string {
+	ext := filepath.Ext(filename)
+	kind := strings.TrimSuffix(filename, ext)
+	if n := len(kind) - 37; n > 0 && kind[n] == '-' {
+		if _, err := uuid.Parse(kind[n+1:]); err == nil {
+			kind = kind[:n]
+		}
+	}
+	return kind + ext
+}
+
 // SignPDF godoc
 // @Summary      Sign a PDF file
 // @Description  Places a previously uploaded signature image on a PDF at the exact coordinates
@@ -392,13 +951,7 @@ func (h *APIHandler) SignPDF(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update session with new output file
-	session.Mutex.Lock()
-	if session.OutputFile != "" {
-		log.Printf("Removing old output file: %s", session.OutputFile)
-		os.Remove(session.OutputFile)
-	}
-	session.OutputFile = signedPath
-	session.Mutex.Unlock()
+	session.SetOutputFile(signedPath)
 
 	// Return download URL
 	downloadURL := fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, signedFilename)
@@ -426,9 +979,9 @@ func (h *APIHandler) UploadSignature(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	const maxUploadSize = 5 * 1024 * 1024 // 5MB max for signature images
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+	const maxSignatureSize = 5 * 1024 * 1024 // 5MB max for signature images
+	r.Body = http.MaxBytesReader(w, r.Body, maxSignatureSize)
+	if err := r.ParseMultipartForm(maxSignatureSize); err != nil {
 		http.Error(w, "File too large", http.StatusBadRequest)
 		return
 	}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go-mergepdf/internal/pdf"
+	"go-mergepdf/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ExtractImages godoc
+// @Summary      Extract embedded images
+// @Description  Collects the images used by selected pages of a session file or the current output into a ZIP archive
+// @Description  with entries named page-<page>-obj-<object>.<ext>. JPEG and JPEG 2000 images keep their encoding, others
+// @Description  become PNG or TIFF. The archive is kept beside the session output, which stays as it was.
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, pages: string }"
+// @Success      200  {object}  map[string]interface{}  "{ downloadUrl: string, report: { images: int, skipped: [{ page, object, error }] } }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Failure      422  {string}  string  "No images found"
+// @Router       /api/sessions/{sessionID}/actions/extract-images [post]
+func (h *APIHandler) ExtractImages(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File  string `json:"file"`  // Filename only, empty for the current output
+		Pages string `json:"pages"` // Page selection, empty for all pages
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputFilename := fmt.Sprintf("images-%s.zip", utils.GenerateUUID())
+	outputPath := filepath.Join(h.OutputDir, outputFilename)
+	report, err := pdf.ExtractImages(sourcePath, outputPath, req.Pages)
+	if err != nil {
+		os.Remove(outputPath)
+		switch {
+		case errors.Is(err, pdf.ErrInvalidImageExtraction):
+			http.Error(w, fmt.Sprintf("Invalid image extraction: %v", err), http.StatusBadRequest)
+		case errors.Is(err, pdf.ErrNoImages):
+			http.Error(w, "No images found on the selected pages", http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to extract images: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	session.AddArtifact(outputPath)
+
+	writeJSON(w, map[string]interface{}{
+		"downloadUrl": fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, outputFilename),
+		"report":      report,
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go-mergepdf/internal/pdf"
+	"go-mergepdf/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ImposePDF godoc
+// @Summary      Impose pages n-up or as a booklet
+// @Description  Arranges the pages of a session file or the current output 2, 4, 6 or 9 per sheet, row or column first,
+// @Description  or reorders them into a saddle-stitch booklet padded with blank pages to a multiple of four.
+// @Description  The result becomes the session output.
+// @Tags         impose
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, mode: nup|booklet, n: int, paperSize: string, border: bool, order: row|column }"
+// @Success      200  {object}  map[string]string  "{ downloadUrl: string }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/impose [post]
+func (h *APIHandler) ImposePDF(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+		pdf.ImposeOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := req.ImposeOptions.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid imposition: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputFilename := fmt.Sprintf("imposed-%s.pdf", utils.GenerateUUID())
+	outputPath := filepath.Join(h.OutputDir, outputFilename)
+	if err := pdf.ImposePDF(sourcePath, outputPath, req.ImposeOptions); err != nil {
+		os.Remove(outputPath)
+		if errors.Is(err, pdf.ErrInvalidImposition) {
+			http.Error(w, fmt.Sprintf("Invalid imposition: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to impose PDF: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	session.SetOutputFile(outputPath)
+
+	writeJSON(w, map[string]string{
+		"downloadUrl": fmt.Sprintf("/api/sessions/%s/files/%s", sessionID, outputFilename),
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// InsertPages godoc
+// @Summary      Insert pages
+// @Description  Inserts blank pages, or pages of an asset or another session file such as a signature page, after a
+// @Description  given page. Inserting into an uploaded file replaces it in place, so the file keeps its position in the
+// @Description  merge order; inserting into the current output makes a new output.
+// @Tags         pages
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, after: int, source: string, pages: string, blank: int, size: string }"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, inserted: int } or { downloadUrl: string, inserted: int }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/insert [post]
+func (h *APIHandler) InsertPages(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File   string `json:"file"`   // Filename only, empty for the current output
+		Source string `json:"source"` // Asset or session file to take pages from, empty for blank pages
+		pdf.InsertOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := req.InsertOptions.Validate(req.Source != ""); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid insert: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+	var insertPath string
+	if req.Source != "" {
+		if insertPath, ok = h.inputPath(session, req.Source); !ok {
+			http.Error(w, "Source file not found in session", http.StatusNotFound)
+			return
+		}
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "inserted")
+	count, err := pdf.InsertPages(sourcePath, insertPath, outputPath, req.InsertOptions)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		if errors.Is(err, pdf.ErrInvalidInsert) {
+			http.Error(w, fmt.Sprintf("Invalid insert: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to insert pages: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"inserted": count})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SetMetadata godoc
+// @Summary      Edit document metadata
+// @Description  Sets or strips the document information and XMP metadata of a session file or the current output.
+// @Description  Editing an uploaded file replaces it in place; editing the current output makes a new output.
+// @Tags         metadata
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, title, author, subject, keywords, creator, producer, creationDate, modDate, strip }"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, metadata: object } or { downloadUrl: string, metadata: object }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/metadata [post]
+func (h *APIHandler) SetMetadata(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+		pdf.MetadataOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if req.MetadataOptions.IsZero() {
+		http.Error(w, "No metadata changes requested", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "metadata")
+	if err := pdf.SetMetadata(sourcePath, outputPath, req.MetadataOptions); err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		http.Error(w, fmt.Sprintf("Failed to set metadata: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	md, err := pdf.ReadMetadata(outputPath)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		http.Error(w, fmt.Sprintf("Failed to read metadata: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"metadata": md})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// OverlayPDF godoc
+// @Summary      Overlay a letterhead or background template
+// @Description  Stamps pages of a template PDF over or under every page of a session file or the current output.
+// @Description  The template is an uploaded asset or file; separate template pages can be chosen for the first, other
+// @Description  and last pages, with -1 leaving those pages blank. Stamping an uploaded file replaces it in place;
+// @Description  stamping the current output makes a new output.
+// @Tags         overlay
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, template: string, firstPage, otherPages, lastPage: int, underlay: bool, opacity: number }"
+// @Success      200  {object}  map[string]string  "{ filename: string } or { downloadUrl: string }"
+// @Failure      400  {string}  string  "Bad request, or a template that is not a PDF"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/overlay [post]
+func (h *APIHandler) OverlayPDF(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File     string `json:"file"`     // Filename only, empty for the current output
+		Template string `json:"template"` // Asset or uploaded filename
+		pdf.OverlayOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if req.Template == "" {
+		http.Error(w, "Template not specified", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+	templatePath, ok := h.inputPath(session, req.Template)
+	if !ok {
+		http.Error(w, "Template not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "overlay")
+	if err := pdf.OverlayPDF(sourcePath, templatePath, outputPath, req.OverlayOptions); err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		if errors.Is(err, pdf.ErrInvalidOverlay) {
+			http.Error(w, fmt.Sprintf("Invalid overlay: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to apply template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CheckPDFA godoc
+// @Summary      Check PDF/A conformance
+// @Description  Reports why a session file or the current output does not conform to PDF/A-1b, 2b or 3b: fonts that
+// @Description  are not embedded, transparency, a missing output intent or XMP identification, encryption, JavaScript
+// @Description  and other forbidden content. Issues marked fixable are resolved by the pdfa merge option. The check
+// @Description  covers the common causes of failure and does not replace a full validator.
+// @Tags         files
+// @Produce      json
+// @Param        sessionID  path      string  true   "Session ID"
+// @Param        filename   path      string  true   "Uploaded or output filename"
+// @Param        level      query     string  false  "1b, 2b or 3b (default: all three)"
+// @Success      200  {object}  pdf.PDFAReport
+// @Failure      400  {string}  string  "Unknown PDF/A level"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/files/{filename}/pdfa [get]
+func (h *APIHandler) CheckPDFA(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var levels []string
+	if level := r.URL.Query().Get("level"); level != "" {
+		if !slices.Contains(pdf.PDFALevels, level) {
+			http.Error(w, "Unknown PDF/A level", http.StatusBadRequest)
+			return
+		}
+		levels = []string{level}
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	report, err := pdf.CheckPDFA(sourcePath, levels)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to check PDF/A conformance: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, report)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RedactPDF godoc
+// @Summary      Redact areas and search terms
+// @Description  Permanently removes the text, image pixels, graphics and annotations under rectangles given in points from
+// @Description  the top left of the displayed page, and under every match of a string or regular expression, then paints
+// @Description  black boxes over them. Redacting an uploaded file replaces it in place; redacting the current output
+// @Description  makes a new output.
+// @Tags         pages
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, areas: [{ page, x, y, width, height }], search: string, regex: bool, ignoreCase: bool, pages: string }"
+// @Success      200  {object}  map[string]interface{}  "{ filename or downloadUrl: string, report: { pages: [{ page, areas, matches, characters }], matches: int } }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/redact [post]
+func (h *APIHandler) RedactPDF(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+		pdf.RedactOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if err := req.RedactOptions.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid redaction: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "redacted")
+	report, err := pdf.RedactPDF(sourcePath, outputPath, req.RedactOptions)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		if errors.Is(err, pdf.ErrInvalidRedaction) {
+			http.Error(w, fmt.Sprintf("Invalid redaction: %v", err), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to redact PDF: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"report": report})
+}
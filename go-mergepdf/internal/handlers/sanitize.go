@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SanitizePDF godoc
+// @Summary      Sanitize a PDF
+// @Description  Removes JavaScript, embedded files, hidden layers, annotations, form fields, document actions and metadata
+// @Description  from a session file or the current output. Revision history is always discarded. Sanitizing an uploaded file
+// @Description  replaces it in place; sanitizing the current output makes a new output.
+// @Tags         sanitize
+// @Accept       json
+// @Produce      json
+// @Param        sessionID  path    string  true  "Session ID"
+// @Param        request    body    object  true  "{ file: string, javascript, embeddedFiles, hiddenLayers, annotations, formFields, documentActions, metadata: bool }"
+// @Success      200  {object}  map[string]interface{}  "{ filename: string, report: object } or { downloadUrl: string, report: object }"
+// @Failure      400  {string}  string  "Bad request"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/actions/sanitize [post]
+func (h *APIHandler) SanitizePDF(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		File string `json:"file"` // Filename only, empty for the current output
+		pdf.SanitizeOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if req.SanitizeOptions.IsZero() {
+		http.Error(w, "No sanitize categories selected", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, req.File)
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	outputPath, outputFilename, inPlace := h.editPath(session, sourcePath, "sanitized")
+	report, err := pdf.SanitizePDF(sourcePath, outputPath, req.SanitizeOptions)
+	if err != nil {
+		if !inPlace {
+			os.Remove(outputPath)
+		}
+		http.Error(w, fmt.Sprintf("Failed to sanitize PDF: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeEditResult(w, session, outputPath, outputFilename, inPlace, map[string]interface{}{"report": report})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ExtractText godoc
+// @Summary      Extract text
+// @Description  Returns the text of each page of a session file or the current output. Pages without extractable text,
+// @Description  typically scans that need OCR, are flagged with noText and listed in noTextPages. With format=text the
+// @Description  pages are returned as plain text separated by form feeds, and the X-No-Text-Pages header lists those pages.
+// @Tags         files
+// @Produce      json,plain
+// @Param        sessionID  path      string  true   "Session ID"
+// @Param        filename   path      string  true   "Uploaded or output filename"
+// @Param        format     query     string  false  "json (default) or text"
+// @Success      200  {object}  map[string]interface{}  "{ pages: [{ page, text, noText }], noTextPages: [int] }"
+// @Failure      400  {string}  string  "Unknown format"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Router       /api/sessions/{sessionID}/files/{filename}/text [get]
+func (h *APIHandler) ExtractText(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "text" {
+		http.Error(w, "Unknown format", http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	pages, err := pdf.ExtractText(sourcePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to extract text: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	noTextPages := []int{}
+	for _, page := range pages {
+		if page.NoText {
+			noTextPages = append(noTextPages, page.Page)
+		}
+	}
+
+	if format == "text" {
+		texts := make([]string, len(pages))
+		numbers := make([]string, len(noTextPages))
+		for i, page := range pages {
+			texts[i] = page.Text
+		}
+		for i, n := range noTextPages {
+			numbers[i] = strconv.Itoa(n)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-No-Text-Pages", strings.Join(numbers, ","))
+		fmt.Fprint(w, strings.Join(texts, "\f"))
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"pages": pages, "noTextPages": noTextPages})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-mergepdf/internal/pdf"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxThumbnailCacheBytes = 32 * 1024 * 1024
+	thumbnailTimeout       = 10 * time.Second
+)
+
+// thumbnailCache keeps rendered thumbnails keyed by file hash, page and width,
+// dropping the oldest once maxThumbnailCacheBytes is exceeded. Keying by
+// content lets identical uploads share thumbnails and drops stale ones when
+// a file is edited in place.
+type thumbnailCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	order   []string
+	size    int
+}
+
+func newThumbnailCache() *thumbnailCache {
+	return &thumbnailCache{entries: map[string][]byte{}}
+}
+
+func (c *thumbnailCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.entries[key]
+	return b, ok
+}
+
+func (c *thumbnailCache) put(key string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = b
+	c.order = append(c.order, key)
+	c.size += len(b)
+	for c.size > maxThumbnailCacheBytes && len(c.order) > 1 {
+		oldest := c.order[0]
+		c.size -= len(c.entries[oldest])
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+	}
+}
+
+// fileHash returns the hex SHA-256 of the file at path.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetThumbnail godoc
+// @Summary      Page thumbnail
+// @Description  Renders a page of a session file or the current output as a PNG image, for previews in the reorder UI.
+// @Description  Thumbnails are cached by file content, page and width. Rendering runs on a limited number of workers
+// @Description  and is abandoned after 10 seconds; the built-in renderer draws text as gray bars.
+// @Tags         files
+// @Produce      png
+// @Param        sessionID  path   string  true   "Session ID"
+// @Param        filename   path   string  true   "Uploaded or output filename"
+// @Param        page       query  int     false  "Page number, 1 by default"
+// @Param        width      query  int     false  "Width in pixels, 32 to 400, 160 by default"
+// @Success      200  {file}    binary  "PNG image"
+// @Failure      400  {string}  string  "Invalid page or width"
+// @Failure      404  {string}  string  "Session or file not found"
+// @Failure      503  {string}  string  "Rendering timed out"
+// @Router       /api/sessions/{sessionID}/files/{filename}/thumbnail [get]
+func (h *APIHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	session, exists := h.SessionManager.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	opts := pdf.ThumbnailOptions{Page: 1, Width: pdf.DefaultThumbnailWidth, Renderer: h.ThumbnailRenderer}
+	query := r.URL.Query()
+	var err error
+	if v := query.Get("page"); v != "" {
+		if opts.Page, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("width"); v != "" {
+		if opts.Width, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid width", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sourcePath, ok := h.sourcePath(session, chi.URLParam(r, "filename"))
+	if !ok {
+		http.Error(w, "File not found in session", http.StatusNotFound)
+		return
+	}
+
+	hash, err := fileHash(sourcePath)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	key := fmt.Sprintf("%s-%d-%d", hash, opts.Page, opts.Width)
+	etag := `"` + key + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	png, cached := h.thumbnails.get(key)
+	if !cached {
+		// Only as many thumbnails as there are render slots are drawn at once.
+		select {
+		case h.renderSlots <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		png, err = h.renderThumbnail(r.Context(), sourcePath, opts)
+		<-h.renderSlots
+		if err != nil {
+			switch {
+			case errors.Is(err, pdf.ErrInvalidThumbnail):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, context.DeadlineExceeded):
+				http.Error(w, "Rendering timed out", http.StatusServiceUnavailable)
+			default:
+				http.Error(w, fmt.Sprintf("Failed to render thumbnail: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		h.thumbnails.put(key, png)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	// Files edited in place keep their name, so clients revalidate by ETag.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", etag)
+	w.Write(png)
+}
+
+// renderThumbnail renders a thumbnail within thumbnailTimeout, falling back
+// to the built-in rasterizer when the configured renderer fails.
+func (h *APIHandler) renderThumbnail(ctx context.Context, sourcePath string, opts pdf.ThumbnailOptions) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, thumbnailTimeout)
+	defer cancel()
+
+	png, err := pdf.RenderThumbnail(ctx, sourcePath, opts)
+	if err != nil && opts.Renderer != "" && !errors.Is(err, pdf.ErrInvalidThumbnail) && ctx.Err() == nil {
+		log.Printf("Thumbnail renderer failed, using built-in renderer: %v", err)
+		opts.Renderer = ""
+		png, err = pdf.RenderThumbnail(ctx, sourcePath, opts)
+	}
+	return png, err
+}
@@ -0,0 +1,256 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidAnnotationFilter is returned for an annotation filter that cannot be applied.
+var ErrInvalidAnnotationFilter = errors.New("invalid annotation filter")
+
+// Annotation describes an annotation such as a comment or highlight. Type is
+// the PDF annotation subtype, e.g. "Text" for a sticky note or "Highlight".
+type Annotation struct {
+	Page     int        `json:"page"`
+	Type     string     `json:"type"`
+	Author   string     `json:"author,omitempty"`
+	Subject  string     `json:"subject,omitempty"`
+	Contents string     `json:"contents,omitempty"`
+	Modified *time.Time `json:"modified,omitempty"`
+}
+
+// AnnotationFilter selects the annotations RemoveAnnotations and
+// FlattenAnnotations work on. Types are annotation subtypes, compared without
+// regard to case; empty selects all but links. Authors are compared the same
+// way; empty selects every author. Pages is a page selection such as "1-3,5";
+// empty selects all pages. Form fields are never selected, and pop-up notes
+// go with the annotation they belong to.
+type AnnotationFilter struct {
+	Types   []string `json:"types,omitempty"`
+	Authors []string `json:"authors,omitempty"`
+	Pages   string   `json:"pages,omitempty"`
+}
+
+// Validate reports whether f can be applied.
+func (f AnnotationFilter) Validate() error {
+	if _, err := pdfapi.ParsePageSelection(f.Pages); err != nil {
+		return fmt.Errorf("%w: invalid page selection %q", ErrInvalidAnnotationFilter, f.Pages)
+	}
+	for _, t := range f.Types {
+		switch strings.ToLower(t) {
+		case "":
+			return fmt.Errorf("%w: empty type", ErrInvalidAnnotationFilter)
+		case "widget":
+			return fmt.Errorf("%w: form fields are handled by the form actions", ErrInvalidAnnotationFilter)
+		case "popup":
+			return fmt.Errorf("%w: pop-up notes go with their annotation", ErrInvalidAnnotationFilter)
+		}
+	}
+	return nil
+}
+
+// match reports whether the annotation d is selected by f.
+func (f AnnotationFilter) match(ctx *model.Context, d types.Dict) bool {
+	subtype := d.NameEntry("Subtype")
+	if subtype == nil || *subtype == "Widget" || *subtype == "Popup" {
+		return false
+	}
+	if len(f.Types) == 0 && *subtype == "Link" {
+		return false
+	}
+	if len(f.Types) > 0 && !containsFold(f.Types, *subtype) {
+		return false
+	}
+	return len(f.Authors) == 0 || containsFold(f.Authors, annotText(ctx, d, "T"))
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotText returns the text entry key of an annotation, or "" if it has none.
+func annotText(ctx *model.Context, d types.Dict, key string) string {
+	o, ok := d.Find(key)
+	if !ok {
+		return ""
+	}
+	s, err := ctx.DereferenceText(o)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// ListAnnotations returns the annotations of the PDF at pdfPath in page
+// order. Form fields and pop-up notes, which show the contents of another
+// annotation, are left out.
+func ListAnnotations(pdfPath string) ([]Annotation, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	list := []Annotation{}
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil || pageDict == nil {
+			return nil, fmt.Errorf("failed to read page %d: %v", pageNr, err)
+		}
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read annotations of page %d: %w", pageNr, err)
+		}
+		for _, o := range annots {
+			d, err := ctx.DereferenceDict(o)
+			if err != nil || d == nil {
+				continue
+			}
+			subtype := d.NameEntry("Subtype")
+			if subtype == nil || *subtype == "Widget" || *subtype == "Popup" {
+				continue
+			}
+			a := Annotation{
+				Page:     pageNr,
+				Type:     *subtype,
+				Author:   annotText(ctx, d, "T"),
+				Subject:  annotText(ctx, d, "Subj"),
+				Contents: annotText(ctx, d, "Contents"),
+			}
+			if t, ok := types.DateTime(annotText(ctx, d, "M"), true); ok {
+				a.Modified = &t
+			}
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+// RemoveAnnotations deletes the annotations of the PDF at pdfPath selected by
+// filter and writes the result to outputPath, which may equal pdfPath. It
+// returns the number of annotations removed.
+func RemoveAnnotations(pdfPath, outputPath string, filter AnnotationFilter) (int, error) {
+	return editAnnotations(pdfPath, outputPath, filter, func(ctx *model.Context, pageNr int, match func(types.Dict) bool) (int, error) {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil || pageDict == nil {
+			return 0, err
+		}
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			return 0, err
+		}
+		kept := types.Array{}
+		for _, o := range annots {
+			if d, err := ctx.DereferenceDict(o); err == nil && d != nil && match(d) {
+				continue
+			}
+			kept = append(kept, o)
+		}
+		if len(kept) == 0 {
+			pageDict.Delete("Annots")
+		} else {
+			pageDict["Annots"] = kept
+		}
+		return len(annots) - len(kept), nil
+	})
+}
+
+// FlattenAnnotations draws the annotations of the PDF at pdfPath selected by
+// filter into the page content, as viewers show them, and removes them, so
+// they can no longer be edited. Hidden annotations and annotations without an
+// appearance have nothing to draw and stay as they are. The result is written
+// to outputPath, which may equal pdfPath. It returns the number of
+// annotations flattened.
+func FlattenAnnotations(pdfPath, outputPath string, filter AnnotationFilter) (int, error) {
+	return editAnnotations(pdfPath, outputPath, filter, func(ctx *model.Context, pageNr int, match func(types.Dict) bool) (int, error) {
+		return flattenPageAnnots(ctx, pageNr, match, true)
+	})
+}
+
+// editAnnotations applies edit to the pages selected by filter, with a match
+// function for the selected annotations, drops the pop-up notes of the
+// annotations edit removed and writes the result to outputPath.
+func editAnnotations(pdfPath, outputPath string, filter AnnotationFilter, edit func(ctx *model.Context, pageNr int, match func(types.Dict) bool) (int, error)) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	selection, _ := pdfapi.ParsePageSelection(filter.Pages)
+	pages, err := pdfapi.PagesForPageSelection(ctx.PageCount, selection, true, false)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidAnnotationFilter, err)
+	}
+
+	match := func(d types.Dict) bool { return filter.match(ctx, d) }
+	count := 0
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		if !pages[pageNr] {
+			continue
+		}
+		n, err := edit(ctx, pageNr, match)
+		if err != nil {
+			return 0, fmt.Errorf("failed to edit annotations of page %d: %w", pageNr, err)
+		}
+		if n > 0 {
+			if err := dropOrphanPopups(ctx, pageNr); err != nil {
+				return 0, fmt.Errorf("failed to edit annotations of page %d: %w", pageNr, err)
+			}
+		}
+		count += n
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return count, nil
+}
+
+// dropOrphanPopups removes the pop-up notes of a page whose parent
+// annotation is no longer on it.
+func dropOrphanPopups(ctx *model.Context, pageNr int) error {
+	pageDict, _, _, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil {
+		return err
+	}
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil || len(annots) == 0 {
+		return err
+	}
+	onPage := map[int]bool{}
+	for _, o := range annots {
+		if ir, ok := o.(types.IndirectRef); ok {
+			onPage[ir.ObjectNumber.Value()] = true
+		}
+	}
+	kept := types.Array{}
+	for _, o := range annots {
+		d, err := ctx.DereferenceDict(o)
+		if err == nil && d != nil && d.NameEntry("Subtype") != nil && *d.NameEntry("Subtype") == "Popup" {
+			if parent, ok := d["Parent"].(types.IndirectRef); ok && !onPage[parent.ObjectNumber.Value()] {
+				continue
+			}
+		}
+		kept = append(kept, o)
+	}
+	if len(kept) == 0 {
+		pageDict.Delete("Annots")
+	} else {
+		pageDict["Annots"] = kept
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrAttachmentNotFound is returned when a PDF has no attachment of the requested name.
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// ErrAttachmentTooLarge is returned for an attachment above the size limit.
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+// Attachment describes a file embedded in a PDF.
+type Attachment struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Size        int        `json:"size"`
+	Modified    *time.Time `json:"modified,omitempty"`
+}
+
+// AttachmentFile is a file to embed under Name, with an optional description.
+type AttachmentFile struct {
+	Path        string
+	Name        string
+	Description string
+}
+
+// embeddedFile is an attachment together with the stream holding its data.
+type embeddedFile struct {
+	Attachment
+	stream *types.StreamDict
+}
+
+// AddAttachments embeds files into the PDF at pdfPath and writes the result to
+// outputPath, which may equal pdfPath. A name that is already taken gets a
+// numbered suffix. With portfolio set, viewers present the document as a
+// portfolio of its attachments. It returns the names the files were stored under.
+func AddAttachments(pdfPath, outputPath string, files []AttachmentFile, portfolio bool) ([]string, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	existing, err := embeddedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachments: %w", err)
+	}
+	used := map[string]bool{}
+	for _, f := range existing {
+		used[f.Name] = true
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		name, err := addAttachment(ctx, file, uniqueName(file.Name, used), portfolio)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach %s: %w", file.Name, err)
+		}
+		names = append(names, name)
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return names, nil
+}
+
+func addAttachment(ctx *model.Context, file AttachmentFile, name string, portfolio bool) (string, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	modTime := info.ModTime()
+	a := model.Attachment{Reader: f, ID: name, FileName: name, Desc: file.Description, ModTime: &modTime}
+	return name, ctx.AddAttachment(a, portfolio)
+}
+
+// ListAttachments returns the files embedded in the PDF at pdfPath, sorted by
+// name. Sizes come from the metadata stored with each file and are 0 where
+// it is missing.
+func ListAttachments(pdfPath string) ([]Attachment, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	files, err := embeddedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachments: %w", err)
+	}
+
+	attachments := make([]Attachment, 0, len(files))
+	for _, f := range files {
+		attachments = append(attachments, f.Attachment)
+	}
+	return attachments, nil
+}
+
+// ExtractAttachment returns the data of the named attachment of the PDF at
+// pdfPath. Attachments larger than maxSize bytes are refused without being
+// decoded in full.
+func ExtractAttachment(pdfPath, name string, maxSize int) ([]byte, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	files, err := embeddedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachments: %w", err)
+	}
+
+	for _, f := range files {
+		if f.Name != name {
+			continue
+		}
+		if f.Size > maxSize {
+			return nil, ErrAttachmentTooLarge
+		}
+		return decodeLimited(f.stream, maxSize)
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// decodeLimited decodes a stream, failing with ErrAttachmentTooLarge once more
+// than maxSize bytes come out. Unfiltered and Flate encoded data, by far the
+// most common for attachments, is decoded incrementally; other filters are
+// left to pdfcpu and checked afterwards.
+func decodeLimited(sd *types.StreamDict, maxSize int) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case len(sd.FilterPipeline) == 0:
+		r = bytes.NewReader(sd.Raw)
+	case len(sd.FilterPipeline) == 1 && sd.FilterPipeline[0].Name == filter.Flate && sd.FilterPipeline[0].DecodeParms == nil:
+		zr, err := zlib.NewReader(bytes.NewReader(sd.Raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode attachment: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		if err := sd.Decode(); err != nil {
+			return nil, fmt.Errorf("failed to decode attachment: %w", err)
+		}
+		r = bytes.NewReader(sd.Content)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment: %w", err)
+	}
+	if len(data) > maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	return data, nil
+}
+
+// embeddedFiles returns the entries of the EmbeddedFiles name tree that hold
+// file data, sorted by name. Files attached to annotations on a page are not
+// included.
+func embeddedFiles(ctx *model.Context) ([]embeddedFile, error) {
+	if err := ctx.LocateNameTree("EmbeddedFiles", false); err != nil {
+		return nil, err
+	}
+	tree := ctx.Names["EmbeddedFiles"]
+	if tree == nil {
+		return nil, nil
+	}
+
+	var files []embeddedFile
+	err := tree.Process(ctx.XRefTable, func(xRefTable *model.XRefTable, key string, o *types.Object) error {
+		d, err := xRefTable.DereferenceDict(*o)
+		if err != nil || d == nil {
+			return nil
+		}
+		ef, err := xRefTable.DereferenceDict(d["EF"])
+		if err != nil || ef == nil {
+			return nil
+		}
+		sd, _, err := xRefTable.DereferenceStreamDict(ef["F"])
+		if err != nil || sd == nil {
+			return nil
+		}
+
+		f := embeddedFile{Attachment: Attachment{Name: key}, stream: sd}
+		for _, k := range []string{"UF", "F"} {
+			if s, err := xRefTable.DereferenceStringOrHexLiteral(d[k], model.V10, nil); err == nil && s != "" {
+				f.Name = s
+				break
+			}
+		}
+		f.Description, _ = xRefTable.DereferenceStringOrHexLiteral(d["Desc"], model.V10, nil)
+		if params, err := xRefTable.DereferenceDict(sd.Dict["Params"]); err == nil && params != nil {
+			if size, err := xRefTable.DereferenceInteger(params["Size"]); err == nil && size != nil {
+				f.Size = size.Value()
+			}
+			if s, err := xRefTable.DereferenceStringOrHexLiteral(params["ModDate"], model.V10, nil); err == nil {
+				if t, ok := types.DateTime(s, true); ok {
+					f.Modified = &t
+				}
+			}
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
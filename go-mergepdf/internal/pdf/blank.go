@@ -0,0 +1,367 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+var (
+	// ErrInvalidBlankOptions is returned for blank page options that cannot be applied.
+	ErrInvalidBlankOptions = errors.New("invalid blank page options")
+	// ErrAllPagesBlank is returned when removing blank pages would leave no pages.
+	ErrAllPagesBlank = errors.New("all pages are blank")
+)
+
+// Blank page detection. Pages are rendered blankRenderWidth pixels wide and
+// a pixel counts as ink when its luminance is below inkLuminance, which
+// leaves out the light gray of scanned paper.
+const (
+	DefaultBlankThreshold = 0.5
+	MaxBlankThreshold     = 10
+	blankRenderWidth      = 300
+	inkLuminance          = 160
+)
+
+// BlankPageOptions selects the pages checked for being blank. A page is
+// blank when nothing is drawn on it, or when it shows neither text nor
+// anything that cannot be rendered and less than Threshold percent of it is
+// inked; zero means DefaultBlankThreshold. Pages is a page selection such as
+// "even"; empty checks every page.
+type BlankPageOptions struct {
+	Threshold float64 `json:"threshold,omitempty"`
+	Pages     string  `json:"pages,omitempty"`
+}
+
+// Validate reports whether opts can be applied.
+func (opts BlankPageOptions) Validate() error {
+	if opts.Threshold < 0 || opts.Threshold > MaxBlankThreshold {
+		return fmt.Errorf("%w: threshold must be between 0 and %d percent", ErrInvalidBlankOptions, MaxBlankThreshold)
+	}
+	if _, err := pdfapi.ParsePageSelection(opts.Pages); err != nil {
+		return fmt.Errorf("%w: invalid page selection %q", ErrInvalidBlankOptions, opts.Pages)
+	}
+	return nil
+}
+
+func (opts BlankPageOptions) threshold() float64 {
+	if opts.Threshold == 0 {
+		return DefaultBlankThreshold
+	}
+	return opts.Threshold
+}
+
+// BlankPage is a page found to be blank. Reason is "empty" for a page with
+// nothing drawn on it and "ink" for a page inked below the threshold, such
+// as the scanned back of a sheet; Coverage is the inked percentage,
+// rounded to two decimals.
+type BlankPage struct {
+	PageRef
+	Reason   string  `json:"reason"`
+	Coverage float64 `json:"coverage"`
+}
+
+// FindBlankPages returns the blank pages of the PDF at pdfPath.
+func FindBlankPages(pdfPath string, opts BlankPageOptions) ([]BlankPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return findBlankPages(ctx, filepath.Base(pdfPath), opts)
+}
+
+// RemoveBlankPages writes the PDF at pdfPath without its blank pages to
+// outputPath, which may equal pdfPath, keeping the page labels of the other
+// pages. It returns the removed pages, numbered as in pdfPath, so they can
+// be put back.
+func RemoveBlankPages(pdfPath, outputPath string, opts BlankPageOptions) ([]BlankPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	blank, err := findBlankPages(ctx, filepath.Base(pdfPath), opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(blank) == 0 {
+		if outputPath != pdfPath {
+			if err := copyFile(pdfPath, outputPath); err != nil {
+				return nil, err
+			}
+		}
+		return blank, nil
+	}
+	if len(blank) == ctx.PageCount {
+		return nil, ErrAllPagesBlank
+	}
+	if err := keepPagesFile(ctx, pdfPath, outputPath, pagesWithout(ctx.PageCount, blank)); err != nil {
+		return nil, err
+	}
+	return blank, nil
+}
+
+// DropBlankPages leaves out the blank pages of files for a sequential merge.
+// Files that lose pages are copied to dir without them, keeping their
+// names; files that lose all pages are dropped. It returns the files to
+// merge instead and the removed pages.
+func DropBlankPages(files []string, dir string, opts BlankPageOptions) ([]string, []BlankPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+	var kept []string
+	removed := []BlankPage{}
+	for _, file := range files {
+		ctx, err := pdfapi.ReadContextFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read PDF: %w", err)
+		}
+		name := filepath.Base(file)
+		blank, err := findBlankPages(ctx, name, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed = append(removed, blank...)
+
+		switch len(blank) {
+		case 0:
+			kept = append(kept, file)
+		case ctx.PageCount:
+		default:
+			copyDir, err := os.MkdirTemp(dir, "blank-")
+			if err != nil {
+				return nil, nil, err
+			}
+			copyPath := filepath.Join(copyDir, name)
+			if err := keepPages(ctx, file, copyPath, pagesWithout(ctx.PageCount, blank)); err != nil {
+				return nil, nil, err
+			}
+			kept = append(kept, copyPath)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, nil, ErrAllPagesBlank
+	}
+	return kept, removed, nil
+}
+
+// DropInterleavedBlankPages removes the blank pages of first and second
+// from mergedPath, which InterleavePDFs made from them. Blank pages are
+// found in the source files, so a blank back still pairs with its front
+// while interleaving. It returns the removed pages, numbered as in the
+// source files.
+func DropInterleavedBlankPages(first, second, mergedPath string, reverseSecond bool, opts BlankPageOptions) ([]BlankPage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	blankFirst, countFirst, err := blankPagesOfFile(first, opts)
+	if err != nil {
+		return nil, err
+	}
+	blankSecond, countSecond, err := blankPagesOfFile(second, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages alternate while both files have pages left; the surplus of the
+	// longer file follows in order.
+	paired := min(countFirst, countSecond)
+	position := func(i int, ofSecond bool) int {
+		switch {
+		case i > paired:
+			return paired + i
+		case ofSecond:
+			return 2 * i
+		default:
+			return 2*i - 1
+		}
+	}
+	drop := map[int]bool{}
+	for _, b := range blankFirst {
+		drop[position(b.Page, false)] = true
+	}
+	for _, b := range blankSecond {
+		i := b.Page
+		if reverseSecond {
+			i = countSecond - b.Page + 1
+		}
+		drop[position(i, true)] = true
+	}
+	removed := append(blankFirst, blankSecond...)
+	if len(drop) == 0 {
+		return removed, nil
+	}
+
+	ctx, err := pdfapi.ReadContextFile(mergedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	var pages []int
+	for p := 1; p <= ctx.PageCount; p++ {
+		if !drop[p] {
+			pages = append(pages, p)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, ErrAllPagesBlank
+	}
+	if err := keepPagesFile(ctx, mergedPath, mergedPath, pages); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// blankPagesOfFile returns the blank pages and the page count of file.
+func blankPagesOfFile(file string, opts BlankPageOptions) ([]BlankPage, int, error) {
+	ctx, err := pdfapi.ReadContextFile(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	blank, err := findBlankPages(ctx, filepath.Base(file), opts)
+	return blank, ctx.PageCount, err
+}
+
+// findBlankPages returns the blank pages among those selected by opts,
+// reporting them under the file name name.
+func findBlankPages(ctx *model.Context, name string, opts BlankPageOptions) ([]BlankPage, error) {
+	selection, _ := pdfapi.ParsePageSelection(opts.Pages)
+	pages, err := pdfapi.PagesForPageSelection(ctx.PageCount, selection, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBlankOptions, err)
+	}
+
+	fonts := map[types.IndirectRef]*textFont{}
+	blank := []BlankPage{}
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		if !pages[pageNr] {
+			continue
+		}
+		reason, coverage, err := pageBlankness(ctx, pageNr, fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check page %d: %w", pageNr, err)
+		}
+		if reason == "ink" && coverage >= opts.threshold() {
+			continue
+		}
+		if reason != "" {
+			coverage = math.Round(coverage*100) / 100
+			blank = append(blank, BlankPage{PageRef: PageRef{File: name, Page: pageNr}, Reason: reason, Coverage: coverage})
+		}
+	}
+	return blank, nil
+}
+
+// pageBlankness returns "empty" for a page with nothing drawn on it, or
+// "ink" and the inked percentage of a page that may be blank. Pages with
+// text, and pages the renderer cannot draw faithfully, return "".
+func pageBlankness(ctx *model.Context, pageNr int, fonts map[types.IndirectRef]*textFont) (string, float64, error) {
+	pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil {
+		return "", 0, err
+	}
+	box := inh.MediaBox
+	if box == nil {
+		box = types.RectForFormat("A4")
+	}
+	if inh.CropBox != nil {
+		box = intersectRect(box, inh.CropBox)
+	}
+	bounds, err := pageContentBounds(ctx, pageDict, inh, box)
+	if err != nil {
+		return "", 0, err
+	}
+	annotated, err := hasDrawnAnnots(ctx, pageDict)
+	if err != nil {
+		return "", 0, err
+	}
+	if bounds == nil && !annotated {
+		return "empty", 0, nil
+	}
+
+	// Text, even in white or invisible as in scans with recognized text,
+	// means the page says something.
+	text, err := pageText(ctx, pageDict, inh, fonts)
+	if err != nil {
+		return "", 0, err
+	}
+	if strings.TrimSpace(text) != "" {
+		return "", 0, nil
+	}
+
+	// Scanned pages are single images often too large to render in full,
+	// so images are reduced to twice the rendered page width.
+	r, err := rasterizePage(ctx, pageNr, blankRenderWidth, 2*blankRenderWidth, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if r.placeholders > 0 || r.ops > maxRenderOps {
+		return "", 0, nil
+	}
+	b := r.img.Bounds()
+	inked := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := r.img.RGBAAt(x, y)
+			if (299*int(c.R)+587*int(c.G)+114*int(c.B))/1000 < inkLuminance {
+				inked++
+			}
+		}
+	}
+	return "ink", 100 * float64(inked) / float64(b.Dx()*b.Dy()), nil
+}
+
+// hasDrawnAnnots reports whether a page has annotations that may draw on
+// it; links and pop-up notes do not.
+func hasDrawnAnnots(ctx *model.Context, pageDict types.Dict) (bool, error) {
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil {
+		return false, err
+	}
+	for _, o := range annots {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil || d == nil {
+			continue
+		}
+		if subtype := d.NameEntry("Subtype"); subtype == nil || (*subtype != "Link" && *subtype != "Popup") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// pagesWithout returns the pages 1 to pageCount that are not in blank.
+func pagesWithout(pageCount int, blank []BlankPage) []int {
+	skip := map[int]bool{}
+	for _, b := range blank {
+		skip[b.Page] = true
+	}
+	var pages []int
+	for p := 1; p <= pageCount; p++ {
+		if !skip[p] {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// keepPagesFile is keepPages for an outputPath that may equal file.
+func keepPagesFile(ctx *model.Context, file, outputPath string, pages []int) error {
+	tmp := outputPath + ".tmp"
+	if err := keepPages(ctx, file, tmp, pages); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, outputPath)
+}
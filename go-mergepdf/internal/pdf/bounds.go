@@ -0,0 +1,313 @@
+package pdf
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// maxFormDepth limits how deeply nested form XObjects are followed.
+const maxFormDepth = 8
+
+// boundsState is the part of the graphics state that affects where marks land.
+type boundsState struct {
+	ctm        matrix
+	clip       *types.Rectangle
+	whiteFill  bool
+	whiteLine  bool
+	fontSize   float64
+	charSpace  float64
+	hScale     float64
+	leading    float64
+	rise       float64
+	renderMode int
+}
+
+// boundsScanner accumulates the area covered by visible marks of content streams.
+type boundsScanner struct {
+	ctx    *model.Context
+	bounds *types.Rectangle
+}
+
+// pageContentBounds returns the bounding box of the visible marks on a page in
+// default user space, clipped to the visible page area, or nil for a page
+// without marks. Paths filled or stroked in white and invisible text are not
+// marks. Text extents are estimated from the font size, since glyph widths
+// are not looked up.
+func pageContentBounds(ctx *model.Context, pageDict types.Dict, inh *model.InheritedPageAttrs, box *types.Rectangle) (*types.Rectangle, error) {
+	content, err := pageContent(ctx, pageDict)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	resources, err := ctx.DereferenceDict(pageDict["Resources"])
+	if err != nil {
+		return nil, err
+	}
+	if resources == nil && inh != nil {
+		resources = inh.Resources
+	}
+
+	s := &boundsScanner{ctx: ctx}
+	s.scan(content, resources, boundsState{ctm: identityMatrix, clip: box, hScale: 1}, 0)
+	return s.bounds, nil
+}
+
+// add records the area r, limited to the current clip.
+func (s *boundsScanner) add(r *types.Rectangle, gs boundsState) {
+	if gs.clip != nil {
+		r = intersectRect(r, gs.clip)
+	}
+	if r.Width() <= 0 && r.Height() <= 0 {
+		return
+	}
+	if s.bounds == nil {
+		s.bounds = r
+		return
+	}
+	s.bounds = types.NewRectangle(
+		math.Min(s.bounds.LL.X, r.LL.X), math.Min(s.bounds.LL.Y, r.LL.Y),
+		math.Max(s.bounds.UR.X, r.UR.X), math.Max(s.bounds.UR.Y, r.UR.Y))
+}
+
+// scan walks a content stream with the given resources and initial state.
+func (s *boundsScanner) scan(content []byte, resources types.Dict, gs boundsState, depth int) {
+	var stack []boundsState
+	var path *types.Rectangle
+	var pendingClip bool
+	var tm, tlm matrix
+
+	addPoint := func(x, y float64) {
+		x, y = gs.ctm.apply(x, y)
+		if path == nil {
+			path = types.NewRectangle(x, y, x, y)
+			return
+		}
+		path = types.NewRectangle(math.Min(path.LL.X, x), math.Min(path.LL.Y, y), math.Max(path.UR.X, x), math.Max(path.UR.Y, y))
+	}
+	endPath := func(paint bool) {
+		if path != nil {
+			if paint {
+				s.add(path, gs)
+			}
+			if pendingClip {
+				if gs.clip == nil {
+					gs.clip = path
+				} else {
+					gs.clip = intersectRect(gs.clip, path)
+				}
+			}
+		}
+		path, pendingClip = nil, false
+	}
+	showText := func(glyphs int, adjust float64) {
+		w := (float64(glyphs)*(0.5*gs.fontSize+gs.charSpace) - adjust*gs.fontSize/1000) * gs.hScale
+		if gs.renderMode != 3 && !gs.whiteFill && glyphs > 0 {
+			r := types.NewRectangle(math.Min(0, w), gs.rise-0.25*gs.fontSize, math.Max(0, w), gs.rise+gs.fontSize)
+			s.add(transformRect(r, tm.multiply(gs.ctm)), gs)
+		}
+		tm = matrix{1, 0, 0, 1, w, 0}.multiply(tm)
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = matrix{1, 0, 0, 1, tx, ty}.multiply(tlm)
+		tm = tlm
+	}
+
+	for _, op := range parseContent(content) {
+		n := numbers(op.Operands)
+		switch op.Name {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(n) == 6 {
+				gs.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.multiply(gs.ctm)
+			}
+
+		case "g", "G", "rg", "RG", "k", "K":
+			white := isWhite(op.Name, n)
+			if op.Name == strings.ToLower(op.Name) {
+				gs.whiteFill = white
+			} else {
+				gs.whiteLine = white
+			}
+		case "cs", "sc", "scn":
+			gs.whiteFill = false
+		case "CS", "SC", "SCN":
+			gs.whiteLine = false
+
+		case "m", "l":
+			if len(n) == 2 {
+				addPoint(n[0], n[1])
+			}
+		case "c", "v", "y":
+			for i := 0; i+1 < len(n); i += 2 {
+				addPoint(n[i], n[i+1])
+			}
+		case "re":
+			if len(n) == 4 {
+				addPoint(n[0], n[1])
+				addPoint(n[0]+n[2], n[1]+n[3])
+			}
+		case "W", "W*":
+			pendingClip = true
+		case "S", "s":
+			endPath(!gs.whiteLine)
+		case "f", "F", "f*":
+			endPath(!gs.whiteFill)
+		case "B", "B*", "b", "b*":
+			endPath(!gs.whiteFill || !gs.whiteLine)
+		case "n":
+			endPath(false)
+
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(n) == 1 {
+				gs.fontSize = n[0]
+			}
+		case "Tc":
+			if len(n) == 1 {
+				gs.charSpace = n[0]
+			}
+		case "Tz":
+			if len(n) == 1 {
+				gs.hScale = n[0] / 100
+			}
+		case "TL":
+			if len(n) == 1 {
+				gs.leading = n[0]
+			}
+		case "Ts":
+			if len(n) == 1 {
+				gs.rise = n[0]
+			}
+		case "Tr":
+			if len(n) == 1 {
+				gs.renderMode = int(n[0])
+			}
+		case "Td", "TD":
+			if len(n) == 2 {
+				if op.Name == "TD" {
+					gs.leading = -n[1]
+				}
+				nextLine(n[0], n[1])
+			}
+		case "Tm":
+			if len(n) == 6 {
+				tlm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -gs.leading)
+		case "Tj", "'", "\"":
+			if op.Name != "Tj" {
+				nextLine(0, -gs.leading)
+			}
+			if len(op.Operands) > 0 {
+				showText(len(stringBytes(op.Operands[len(op.Operands)-1])), 0)
+			}
+		case "TJ":
+			if len(op.Operands) == 1 {
+				glyphs, adjust := textArrayLength(op.Operands[0])
+				showText(glyphs, adjust)
+			}
+
+		case "BI":
+			s.add(transformRect(types.NewRectangle(0, 0, 1, 1), gs.ctm), gs)
+		case "sh":
+			if gs.clip != nil {
+				s.add(gs.clip, gs)
+			}
+		case "Do":
+			if len(op.Operands) == 1 {
+				s.scanXObject(strings.TrimPrefix(op.Operands[0], "/"), resources, gs, depth)
+			}
+		}
+	}
+}
+
+// scanXObject records the area covered by the named XObject.
+func (s *boundsScanner) scanXObject(name string, resources types.Dict, gs boundsState, depth int) {
+	xobjects, err := s.ctx.DereferenceDict(resources["XObject"])
+	if err != nil || xobjects == nil {
+		return
+	}
+	sd, _, err := s.ctx.DereferenceStreamDict(xobjects[name])
+	if err != nil || sd == nil {
+		return
+	}
+
+	subtype := sd.Dict.NameEntry("Subtype")
+	if subtype != nil && *subtype == "Image" {
+		s.add(transformRect(types.NewRectangle(0, 0, 1, 1), gs.ctm), gs)
+		return
+	}
+	if subtype == nil || *subtype != "Form" || depth >= maxFormDepth {
+		return
+	}
+
+	gs.ctm = matrixEntry(s.ctx, sd.Dict, "Matrix").multiply(gs.ctm)
+	if bbox, err := s.ctx.RectForArray(sd.ArrayEntry("BBox")); err == nil && bbox != nil {
+		r := transformRect(bbox, gs.ctm)
+		if gs.clip != nil {
+			r = intersectRect(r, gs.clip)
+		}
+		gs.clip = r
+	}
+	if err := sd.Decode(); err != nil {
+		return
+	}
+	formResources, err := s.ctx.DereferenceDict(sd.Dict["Resources"])
+	if err != nil || formResources == nil {
+		formResources = resources
+	}
+	s.scan(sd.Content, formResources, gs, depth+1)
+}
+
+// numbers parses the numeric operands of an operation, skipping any others.
+func numbers(operands []string) []float64 {
+	n := make([]float64, 0, len(operands))
+	for _, o := range operands {
+		if f, err := strconv.ParseFloat(o, 64); err == nil {
+			n = append(n, f)
+		}
+	}
+	return n
+}
+
+// isWhite reports whether a device color operation selects white.
+func isWhite(op string, n []float64) bool {
+	switch strings.ToLower(op) {
+	case "g":
+		return len(n) == 1 && n[0] >= 1
+	case "rg":
+		return len(n) == 3 && n[0] >= 1 && n[1] >= 1 && n[2] >= 1
+	case "k":
+		return len(n) == 4 && n[0] <= 0 && n[1] <= 0 && n[2] <= 0 && n[3] <= 0
+	}
+	return false
+}
+
+// textArrayLength returns the glyph count and total position adjustment of a TJ array token.
+func textArrayLength(tok string) (int, float64) {
+	l := &contentLexer{b: []byte(strings.TrimSuffix(strings.TrimPrefix(tok, "["), "]"))}
+	glyphs, adjust := 0, 0.0
+	for {
+		t, _, ok := l.next()
+		if !ok {
+			break
+		}
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			adjust += f
+			continue
+		}
+		glyphs += len(stringBytes(t))
+	}
+	return glyphs, adjust
+}
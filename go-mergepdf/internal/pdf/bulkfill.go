@@ -0,0 +1,285 @@
+package pdf
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-mergepdf/internal/utils"
+)
+
+// MaxBulkFillRows limits the number of CSV data rows accepted by BulkFillForms.
+const MaxBulkFillRows = 1000
+
+// ErrInvalidBulkFill is returned when the CSV or the options of a bulk fill
+// cannot be used at all, as opposed to errors in individual rows.
+var ErrInvalidBulkFill = errors.New("invalid bulk fill request")
+
+// BulkFillOptions controls how BulkFillForms turns CSV rows into documents.
+type BulkFillOptions struct {
+	// Mapping maps CSV column headers to field names or IDs. When empty, every
+	// column whose header names a field of the template is used.
+	Mapping map[string]string `json:"mapping,omitempty"`
+	// NameTemplate names the PDF of each row. {column} is replaced with the
+	// row's value of that column and {row} with the 1-based data row number.
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	Flatten      bool   `json:"flatten,omitempty"`
+	// Combine concatenates all filled rows into a single PDF inside the ZIP.
+	Combine bool `json:"combine,omitempty"`
+}
+
+// BulkRowError reports why a single CSV data row could not be filled.
+type BulkRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// BulkFillReport summarizes a bulk fill.
+type BulkFillReport struct {
+	Rows   int            `json:"rows"`
+	Filled int            `json:"filled"`
+	Files  []string       `json:"files"`
+	Errors []BulkRowError `json:"errors"`
+}
+
+const defaultNameTemplate = "row-{row}"
+
+var namePlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// BulkFillForms fills the form of templatePath once per data row of the CSV
+// read from r and writes the results as a ZIP archive to zipPath. The first
+// CSV record holds the column headers. Rows that fail are listed in the
+// report and skipped; an error is only returned if the batch cannot run at
+// all, in which case no archive is written.
+func BulkFillForms(templatePath string, r io.Reader, zipPath string, opts BulkFillOptions) (*BulkFillReport, error) {
+	fields, err := ListFormFields(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: template has no form fields", ErrInvalidBulkFill)
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read CSV header: %v", ErrInvalidBulkFill, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	columns, err := bulkFillColumns(header, fields, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	nameTemplate := opts.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = defaultNameTemplate
+	}
+	for _, m := range namePlaceholder.FindAllStringSubmatch(nameTemplate, -1) {
+		if m[1] != "row" && indexOf(header, m[1]) < 0 {
+			return nil, fmt.Errorf("%w: name template references unknown column %q", ErrInvalidBulkFill, m[1])
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bulkfill-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	// Rows are filled into one directory and moved under their final names
+	// into another, so a templated name never clashes with a pending row.
+	rowDir, outDir := filepath.Join(tmpDir, "rows"), filepath.Join(tmpDir, "out")
+	if err := os.Mkdir(rowDir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(outDir, 0o700); err != nil {
+		return nil, err
+	}
+
+	report := &BulkFillReport{Files: []string{}, Errors: []BulkRowError{}}
+	var filled []string
+	used := map[string]bool{}
+
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if row > MaxBulkFillRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidBulkFill, MaxBulkFillRows)
+		}
+		report.Rows++
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Errors = append(report.Errors, BulkRowError{Row: row, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(record) != len(header) {
+			report.Errors = append(report.Errors, BulkRowError{Row: row, Error: fmt.Sprintf("expected %d columns, got %d", len(header), len(record))})
+			continue
+		}
+
+		values := make(map[string]string, len(columns))
+		for col, field := range columns {
+			values[field] = record[col]
+		}
+
+		path := filepath.Join(rowDir, strconv.Itoa(row)+".pdf")
+		if err := FillFormFields(templatePath, path, values, opts.Flatten); err != nil {
+			report.Errors = append(report.Errors, BulkRowError{Row: row, Error: err.Error()})
+			continue
+		}
+
+		name := uniqueName(bulkFillName(nameTemplate, header, record, row), used)
+		if err := os.Rename(path, filepath.Join(outDir, name)); err != nil {
+			return nil, err
+		}
+		filled = append(filled, filepath.Join(outDir, name))
+		report.Files = append(report.Files, name)
+		report.Filled++
+	}
+
+	if report.Filled > 0 && opts.Combine {
+		combined := filepath.Join(tmpDir, "combined.pdf")
+		merge := MergeFormPDFs
+		if opts.Flatten {
+			merge = MergePDFs
+		}
+		if err := merge(filled, combined); err != nil {
+			return nil, fmt.Errorf("failed to combine rows: %w", err)
+		}
+		filled = []string{combined}
+		report.Files = []string{"combined.pdf"}
+	}
+
+	if err := writeZip(zipPath, filled); err != nil {
+		os.Remove(zipPath)
+		return nil, fmt.Errorf("failed to write ZIP: %w", err)
+	}
+	return report, nil
+}
+
+// bulkFillColumns returns the field filled from each used CSV column, keyed by column index.
+func bulkFillColumns(header []string, fields []FormField, mapping map[string]string) (map[int]string, error) {
+	isField := func(key string) bool {
+		for _, f := range fields {
+			if f.Name == key || f.ID == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	columns := map[int]string{}
+	if len(mapping) == 0 {
+		for i, h := range header {
+			if isField(h) {
+				columns[i] = h
+			}
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("%w: no CSV column matches a form field", ErrInvalidBulkFill)
+		}
+		return columns, nil
+	}
+
+	for column, field := range mapping {
+		i := indexOf(header, column)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: mapped column %q not found in CSV", ErrInvalidBulkFill, column)
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("%w: %w: %s", ErrInvalidBulkFill, ErrUnknownFormField, field)
+		}
+		columns[i] = field
+	}
+	return columns, nil
+}
+
+// bulkFillName expands the name template for a row into a safe PDF filename.
+func bulkFillName(template string, header, record []string, row int) string {
+	name := namePlaceholder.ReplaceAllStringFunc(template, func(m string) string {
+		key := m[1 : len(m)-1]
+		if key == "row" {
+			return strconv.Itoa(row)
+		}
+		return record[indexOf(header, key)]
+	})
+	name = strings.TrimSuffix(utils.SanitizeFilename(strings.TrimSuffix(name, ".pdf")), ".")
+	if name == "" || name == "_" {
+		name = "row-" + strconv.Itoa(row)
+	}
+	return name + ".pdf"
+}
+
+// uniqueName returns name, numbered before its extension if it has been used
+// before, and records it.
+func uniqueName(name string, used map[string]bool) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	used[name] = true
+	return name
+}
+
+func indexOf(list []string, s string) int {
+	for i, e := range list {
+		if e == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeZip stores files in a new ZIP archive at zipPath under their base names.
+func writeZip(zipPath string, files []string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(out)
+	for _, file := range files {
+		if err := addZipFile(zw, file); err != nil {
+			zw.Close()
+			out.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func addZipFile(zw *zip.Writer, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	w, err := zw.Create(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
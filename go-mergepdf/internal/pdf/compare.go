@@ -0,0 +1,525 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// maxDiffCells bounds the table used to match pages and lines. Longer inputs
+// only match their common beginning and end.
+const maxDiffCells = 4_000_000
+
+// CompareReport describes how a revised PDF differs from the original.
+// SameContent is set if every page has a counterpart with the same content;
+// the document information may still differ.
+type CompareReport struct {
+	SameContent       bool             `json:"sameContent"`
+	OriginalPageCount int              `json:"originalPageCount"`
+	RevisedPageCount  int              `json:"revisedPageCount"`
+	Metadata          []MetadataChange `json:"metadata"`
+	Pages             []PageChange     `json:"pages"`
+}
+
+// MetadataChange is a document information entry that differs.
+type MetadataChange struct {
+	Field    string `json:"field"`
+	Original string `json:"original"`
+	Revised  string `json:"revised"`
+}
+
+// PageChange describes a page that differs between the documents. Status is
+// "changed" for a page with a counterpart, "removed" for a page only in the
+// original and "added" for a page only in the revision. Text is compared by
+// line. OtherContent is set for changed pages whose text and images are the
+// same but that differ otherwise, e.g. in drawings, annotations or layout.
+type PageChange struct {
+	OriginalPage  int            `json:"originalPage,omitempty"`
+	RevisedPage   int            `json:"revisedPage,omitempty"`
+	Status        string         `json:"status"`
+	AddedText     []string       `json:"addedText,omitempty"`
+	RemovedText   []string       `json:"removedText,omitempty"`
+	AddedImages   []CompareImage `json:"addedImages,omitempty"`
+	RemovedImages []CompareImage `json:"removedImages,omitempty"`
+	OtherContent  bool           `json:"otherContent,omitempty"`
+}
+
+// CompareImage is an image placed on a page, compared by content.
+type CompareImage struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	hash   string // Fingerprint of the image stream
+}
+
+// comparePage is what ComparePDFs compares of a page.
+type comparePage struct {
+	lines       []string
+	text        string
+	images      []CompareImage
+	fingerprint string
+}
+
+// ComparePDFs compares the PDF at revisedPath with the one at originalPath.
+// Pages are matched by their text, so inserted and deleted pages are
+// reported as such rather than shifting every later page; unmatched pages
+// between two matches are compared in order.
+func ComparePDFs(originalPath, revisedPath string) (*CompareReport, error) {
+	original, err := readComparePages(originalPath)
+	if err != nil {
+		return nil, err
+	}
+	revised, err := readComparePages(revisedPath)
+	if err != nil {
+		return nil, err
+	}
+	report := &CompareReport{
+		OriginalPageCount: len(original),
+		RevisedPageCount:  len(revised),
+		Metadata:          []MetadataChange{},
+		Pages:             []PageChange{},
+	}
+
+	originalMD, err := ReadMetadata(originalPath)
+	if err != nil {
+		return nil, err
+	}
+	revisedMD, err := ReadMetadata(revisedPath)
+	if err != nil {
+		return nil, err
+	}
+	report.Metadata = compareMetadata(originalMD, revisedMD)
+
+	texts := func(pages []comparePage) []string {
+		out := make([]string, len(pages))
+		for i, p := range pages {
+			out[i] = p.text
+		}
+		return out
+	}
+	matches := commonSubsequence(texts(original), texts(revised))
+	matches = append(matches, [2]int{len(original), len(revised)})
+	i, j := 0, 0
+	for _, m := range matches {
+		for ; i < m[0] && j < m[1]; i, j = i+1, j+1 {
+			report.addChange(original[i], revised[j], i+1, j+1)
+		}
+		for ; i < m[0]; i++ {
+			report.Pages = append(report.Pages, PageChange{
+				OriginalPage:  i + 1,
+				Status:        "removed",
+				RemovedText:   original[i].lines,
+				RemovedImages: original[i].images,
+			})
+		}
+		for ; j < m[1]; j++ {
+			report.Pages = append(report.Pages, PageChange{
+				RevisedPage: j + 1,
+				Status:      "added",
+				AddedText:   revised[j].lines,
+				AddedImages: revised[j].images,
+			})
+		}
+		if m[0] < len(original) {
+			report.addChange(original[i], revised[j], i+1, j+1)
+			i, j = i+1, j+1
+		}
+	}
+	report.SameContent = len(report.Pages) == 0
+	return report, nil
+}
+
+// addChange compares page a of the original with page b of the revision and
+// records a change if they differ.
+func (report *CompareReport) addChange(a, b comparePage, originalPage, revisedPage int) {
+	if a.fingerprint == b.fingerprint {
+		return
+	}
+	change := PageChange{OriginalPage: originalPage, RevisedPage: revisedPage, Status: "changed"}
+
+	matched := commonSubsequence(a.lines, b.lines)
+	keptA, keptB := map[int]bool{}, map[int]bool{}
+	for _, m := range matched {
+		keptA[m[0]], keptB[m[1]] = true, true
+	}
+	for i, line := range a.lines {
+		if !keptA[i] {
+			change.RemovedText = append(change.RemovedText, line)
+		}
+	}
+	for i, line := range b.lines {
+		if !keptB[i] {
+			change.AddedText = append(change.AddedText, line)
+		}
+	}
+
+	// Images are compared as multisets of their fingerprints; count ends up
+	// holding the images of the original the revision lacks.
+	count := map[string]int{}
+	for _, img := range a.images {
+		count[img.hash]++
+	}
+	for _, img := range b.images {
+		if count[img.hash] > 0 {
+			count[img.hash]--
+			continue
+		}
+		change.AddedImages = append(change.AddedImages, img)
+	}
+	for _, img := range a.images {
+		if count[img.hash] > 0 {
+			count[img.hash]--
+			change.RemovedImages = append(change.RemovedImages, img)
+		}
+	}
+
+	change.OtherContent = len(change.AddedText) == 0 && len(change.RemovedText) == 0 &&
+		len(change.AddedImages) == 0 && len(change.RemovedImages) == 0
+	report.Pages = append(report.Pages, change)
+}
+
+// compareMetadata lists the document information entries that differ.
+func compareMetadata(a, b *Metadata) []MetadataChange {
+	fields := []struct {
+		name string
+		a, b string
+	}{
+		{"title", a.Title, b.Title},
+		{"author", a.Author, b.Author},
+		{"subject", a.Subject, b.Subject},
+		{"keywords", a.Keywords, b.Keywords},
+		{"creator", a.Creator, b.Creator},
+		{"producer", a.Producer, b.Producer},
+		{"creationDate", a.CreationDate, b.CreationDate},
+		{"modDate", a.ModDate, b.ModDate},
+	}
+	changes := []MetadataChange{}
+	for _, f := range fields {
+		if f.a != f.b {
+			changes = append(changes, MetadataChange{Field: f.name, Original: f.a, Revised: f.b})
+		}
+	}
+	return changes
+}
+
+// readComparePages reads the text lines, images and fingerprint of every
+// page of the PDF at pdfPath.
+func readComparePages(pdfPath string) ([]comparePage, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	f := newFingerprinter(ctx)
+	fonts := map[types.IndirectRef]*textFont{}
+	pages := make([]comparePage, 0, ctx.PageCount)
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+		if err != nil || pageDict == nil {
+			return nil, fmt.Errorf("failed to read page %d: %v", pageNr, err)
+		}
+		var p comparePage
+		text, err := pageText(ctx, pageDict, inh, fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", pageNr, err)
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				p.lines = append(p.lines, line)
+			}
+		}
+		p.text = strings.Join(p.lines, "\n")
+
+		resources, err := ctx.DereferenceDict(pageDict["Resources"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", pageNr, err)
+		}
+		if resources == nil {
+			resources = inh.Resources
+		}
+		if p.images, err = f.images(resources, 0); err != nil {
+			return nil, fmt.Errorf("failed to read images of page %d: %w", pageNr, err)
+		}
+		if p.fingerprint, err = f.page(pageDict, inh); err != nil {
+			return nil, fmt.Errorf("failed to fingerprint page %d: %w", pageNr, err)
+		}
+		pages = append(pages, p)
+	}
+	return pages, nil
+}
+
+// images returns the images in the XObject resources, including those of
+// form XObjects.
+func (f *fingerprinter) images(resources types.Dict, depth int) ([]CompareImage, error) {
+	if resources == nil || depth > maxFormDepth {
+		return nil, nil
+	}
+	xobjects, err := f.ctx.DereferenceDict(resources["XObject"])
+	if err != nil || xobjects == nil {
+		return nil, err
+	}
+	var images []CompareImage
+	for _, name := range sortedKeys(xobjects) {
+		sd, _, err := f.ctx.DereferenceStreamDict(xobjects[name])
+		if err != nil || sd == nil {
+			continue
+		}
+		switch subtype := sd.NameEntry("Subtype"); {
+		case subtype != nil && *subtype == "Image":
+			sum, err := f.digest(xobjects[name])
+			if err != nil {
+				return nil, err
+			}
+			img := CompareImage{hash: hex.EncodeToString(sum)}
+			if w := sd.IntEntry("Width"); w != nil {
+				img.Width = *w
+			}
+			if height := sd.IntEntry("Height"); height != nil {
+				img.Height = *height
+			}
+			images = append(images, img)
+		case subtype != nil && *subtype == "Form":
+			formResources, err := f.ctx.DereferenceDict(sd.Dict["Resources"])
+			if err != nil {
+				continue
+			}
+			nested, err := f.images(formResources, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, nested...)
+		}
+	}
+	return images, nil
+}
+
+// commonSubsequence returns the index pairs of a longest common subsequence
+// of a and b, in order. Common leading and trailing elements are matched
+// directly; if what remains is too large, only they are matched.
+func commonSubsequence(a, b []string) [][2]int {
+	var head, tail [][2]int
+	for len(head) < len(a) && len(head) < len(b) && a[len(head)] == b[len(head)] {
+		head = append(head, [2]int{len(head), len(head)})
+	}
+	for n := len(head); len(a)-len(tail) > n && len(b)-len(tail) > n && a[len(a)-1-len(tail)] == b[len(b)-1-len(tail)]; {
+		tail = append(tail, [2]int{len(a) - 1 - len(tail), len(b) - 1 - len(tail)})
+	}
+
+	matches := head
+	start := len(head)
+	ma, mb := a[start:len(a)-len(tail)], b[start:len(b)-len(tail)]
+	if len(ma) > 0 && len(mb) > 0 && len(ma)*len(mb) <= maxDiffCells {
+		// lengths[i][j] is the length of the longest common subsequence of
+		// ma[i:] and mb[j:].
+		lengths := make([][]int, len(ma)+1)
+		for i := range lengths {
+			lengths[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lengths[i][j] = lengths[i+1][j+1] + 1
+				} else {
+					lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(ma) && j < len(mb); {
+			switch {
+			case ma[i] == mb[j]:
+				matches = append(matches, [2]int{start + i, start + j})
+				i, j = i+1, j+1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		matches = append(matches, tail[i])
+	}
+	return matches
+}
+
+// Layout of the comparison report, in points.
+const (
+	reportMargin   = 50
+	reportTitle    = 16
+	reportHeading  = 12
+	reportTextSize = 9
+)
+
+// Highlight colours of the comparison report.
+var (
+	reportAdded   = [3]float64{0.8, 0.95, 0.8}
+	reportRemoved = [3]float64{1, 0.82, 0.82}
+	reportNote    = [3]float64{1, 0.95, 0.7}
+)
+
+// reportWriter lays out lines of text on A4 pages.
+type reportWriter struct {
+	box   *types.Rectangle
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// line writes text in font F1 or F2 at size, indented and optionally on a
+// highlight, wrapping it at the right margin.
+func (rw *reportWriter) line(fontName string, size, indent float64, text string, highlight *[3]float64) {
+	baseFont := "Helvetica"
+	if fontName == "F2" {
+		baseFont = "Helvetica-Bold"
+	}
+	left := rw.box.LL.X + reportMargin + indent
+	width := rw.box.UR.X - reportMargin - left
+	for _, b := range wrapText(winAnsiBytes(text), baseFont, size, width) {
+		step := size * 1.4
+		if len(rw.pages) == 0 || rw.y-step < rw.box.LL.Y+reportMargin {
+			rw.pages = append(rw.pages, &bytes.Buffer{})
+			rw.y = rw.box.UR.Y - reportMargin
+		}
+		rw.y -= step
+		content := rw.pages[len(rw.pages)-1]
+		if highlight != nil {
+			fmt.Fprintf(content, "q %.2f %.2f %.2f rg %.2f %.2f %.2f %.2f re f Q\n",
+				highlight[0], highlight[1], highlight[2], left-2, rw.y-size*0.3, width+4, step)
+		}
+		fmt.Fprintf(content, "BT /%s %.0f Tf %.2f %.2f Td %s Tj ET\n", fontName, size, left, rw.y, literalString(b))
+	}
+}
+
+// space adds vertical space before the next line.
+func (rw *reportWriter) space(points float64) {
+	rw.y -= points
+}
+
+// wrapText breaks WinAnsi encoded text into lines no wider than width,
+// between words where possible.
+func wrapText(b []byte, fontName string, size, width float64) [][]byte {
+	var lines [][]byte
+	for textWidth(b, fontName, size) > width {
+		cut := 1
+		for cut < len(b) && textWidth(b[:cut+1], fontName, size) <= width {
+			cut++
+		}
+		if space := bytes.LastIndexByte(b[:cut], ' '); space > 0 {
+			cut = space
+		}
+		lines = append(lines, b[:cut])
+		b = bytes.TrimLeft(b[cut:], " ")
+	}
+	return append(lines, b)
+}
+
+// WriteCompareReport writes report as a PDF to outputPath: a summary, the
+// differing document information and every changed page, with added text
+// and images highlighted in green, removed ones in red and other changes in
+// yellow. originalName and revisedName are shown as the document names.
+func WriteCompareReport(report *CompareReport, outputPath, originalName, revisedName string) error {
+	dim := types.PaperSize["A4"]
+	rw := &reportWriter{box: types.RectForDim(dim.Width, dim.Height)}
+
+	rw.line("F2", reportTitle, 0, "Comparison report", nil)
+	rw.space(reportTitle / 2)
+	rw.line("F1", reportTextSize+1, 0, fmt.Sprintf("Original: %s (%d pages)", originalName, report.OriginalPageCount), nil)
+	rw.line("F1", reportTextSize+1, 0, fmt.Sprintf("Revised: %s (%d pages)", revisedName, report.RevisedPageCount), nil)
+	if report.SameContent {
+		rw.line("F1", reportTextSize+1, 0, "The pages have the same content.", &reportAdded)
+	} else {
+		rw.line("F1", reportTextSize+1, 0, fmt.Sprintf("%d pages differ.", len(report.Pages)), &reportRemoved)
+	}
+
+	if len(report.Metadata) > 0 {
+		rw.space(reportHeading)
+		rw.line("F2", reportHeading, 0, "Document information", nil)
+		for _, c := range report.Metadata {
+			rw.line("F1", reportTextSize, 0, fmt.Sprintf("%s: %q -> %q", c.Field, c.Original, c.Revised), &reportNote)
+		}
+	}
+
+	for _, c := range report.Pages {
+		rw.space(reportHeading)
+		var heading string
+		switch c.Status {
+		case "added":
+			heading = fmt.Sprintf("Revised page %d: added", c.RevisedPage)
+		case "removed":
+			heading = fmt.Sprintf("Original page %d: removed", c.OriginalPage)
+		default:
+			heading = fmt.Sprintf("Original page %d, revised page %d: changed", c.OriginalPage, c.RevisedPage)
+		}
+		rw.line("F2", reportHeading, 0, heading, nil)
+		for _, line := range c.RemovedText {
+			rw.line("F1", reportTextSize, 0, "- "+line, &reportRemoved)
+		}
+		for _, line := range c.AddedText {
+			rw.line("F1", reportTextSize, 0, "+ "+line, &reportAdded)
+		}
+		for _, img := range c.RemovedImages {
+			rw.line("F1", reportTextSize, 0, fmt.Sprintf("- image %d x %d", img.Width, img.Height), &reportRemoved)
+		}
+		for _, img := range c.AddedImages {
+			rw.line("F1", reportTextSize, 0, fmt.Sprintf("+ image %d x %d", img.Width, img.Height), &reportAdded)
+		}
+		if c.OtherContent {
+			rw.line("F1", reportTextSize, 0, "Drawings, annotations or layout differ.", &reportNote)
+		}
+	}
+
+	return writeReportPages(rw.pages, rw.box, outputPath)
+}
+
+// writeReportPages writes a new PDF with a page of the size of box for each
+// content stream, using the fonts of addStandardFonts.
+func writeReportPages(pages []*bytes.Buffer, box *types.Rectangle, outputPath string) error {
+	ctx, err := pdfcpu.CreateContextWithXRefTable(model.NewDefaultConfiguration(), &types.Dim{Width: box.Width(), Height: box.Height()})
+	if err != nil {
+		return err
+	}
+	if ctx.RootDict, err = ctx.Catalog(); err != nil {
+		return err
+	}
+	pagesRef, ok := ctx.RootDict["Pages"].(types.IndirectRef)
+	if !ok {
+		return fmt.Errorf("missing page tree")
+	}
+	pagesDict, err := ctx.DereferenceDict(pagesRef)
+	if err != nil {
+		return err
+	}
+	fonts, err := addStandardFonts(ctx)
+	if err != nil {
+		return err
+	}
+
+	kids := types.Array{}
+	for _, content := range pages {
+		pageDict := types.Dict{
+			"Type":      types.Name("Page"),
+			"Parent":    pagesRef,
+			"Resources": types.Dict{"Font": fonts},
+		}
+		if err := setPageContent(ctx, pageDict, content.Bytes()); err != nil {
+			return err
+		}
+		ir, err := ctx.IndRefForNewObject(pageDict)
+		if err != nil {
+			return err
+		}
+		kids = append(kids, *ir)
+	}
+	pagesDict["Kids"] = kids
+	pagesDict["Count"] = types.Integer(len(kids))
+	ctx.PageCount = len(kids)
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
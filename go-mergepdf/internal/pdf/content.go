@@ -0,0 +1,360 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// contentOp is a single operator of a content stream together with its operands.
+// Operands are kept as raw tokens; Start and End delimit the whole operation
+// in the stream so it can be cut out or replaced.
+type contentOp struct {
+	Name     string
+	Operands []string
+	Start    int
+	End      int
+}
+
+// parseContent splits a decoded content stream into operations.
+// Inline images are returned as a single "BI" operation spanning up to EI.
+func parseContent(b []byte) []contentOp {
+	var ops []contentOp
+	var operands []string
+	start := -1
+
+	l := &contentLexer{b: b}
+	for {
+		tok, pos, ok := l.next()
+		if !ok {
+			break
+		}
+		if start < 0 {
+			start = pos
+		}
+		if isOperand(tok) {
+			operands = append(operands, tok)
+			continue
+		}
+		if tok == "BI" {
+			l.skipInlineImage()
+		}
+		ops = append(ops, contentOp{Name: tok, Operands: operands, Start: start, End: l.pos})
+		operands = nil
+		start = -1
+	}
+	return ops
+}
+
+// isOperand reports whether a raw token is an operand rather than an operator.
+func isOperand(tok string) bool {
+	switch tok[0] {
+	case '/', '(', '<', '[', '+', '-', '.', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return tok == "true" || tok == "false" || tok == "null"
+}
+
+// contentLexer produces raw tokens of a content stream.
+type contentLexer struct {
+	b   []byte
+	pos int
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// next returns the next token and its offset. Strings, hex strings, arrays
+// and dictionaries are returned whole.
+func (l *contentLexer) next() (string, int, bool) {
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		if isWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.b) {
+		return "", l.pos, false
+	}
+
+	start := l.pos
+	switch c := l.b[l.pos]; {
+	case c == '(':
+		l.skipString()
+	case c == '<' && l.pos+1 < len(l.b) && l.b[l.pos+1] == '<':
+		l.skipDict()
+	case c == '<':
+		for l.pos < len(l.b) && l.b[l.pos] != '>' {
+			l.pos++
+		}
+		l.pos++
+	case c == '[':
+		l.skipArray()
+	case c == '/':
+		l.pos++
+		for l.pos < len(l.b) && !isWhitespace(l.b[l.pos]) && !isDelimiter(l.b[l.pos]) {
+			l.pos++
+		}
+	case isDelimiter(c):
+		// Stray closing delimiter; consume it on its own.
+		l.pos++
+	default:
+		for l.pos < len(l.b) && !isWhitespace(l.b[l.pos]) && !isDelimiter(l.b[l.pos]) {
+			l.pos++
+		}
+	}
+	if l.pos > len(l.b) {
+		l.pos = len(l.b)
+	}
+	return string(l.b[start:l.pos]), start, true
+}
+
+// skipString advances past a literal string, honouring escapes and nesting.
+func (l *contentLexer) skipString() {
+	depth := 0
+	for l.pos < len(l.b) {
+		switch l.b[l.pos] {
+		case '\\':
+			l.pos++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return
+			}
+		}
+		l.pos++
+	}
+}
+
+// skipDict advances past a << >> dictionary, including nested ones.
+func (l *contentLexer) skipDict() {
+	depth := 0
+	for l.pos < len(l.b) {
+		switch {
+		case l.b[l.pos] == '(':
+			l.skipString()
+			continue
+		case bytes.HasPrefix(l.b[l.pos:], []byte("<<")):
+			depth++
+			l.pos += 2
+			continue
+		case bytes.HasPrefix(l.b[l.pos:], []byte(">>")):
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+			continue
+		}
+		l.pos++
+	}
+}
+
+// skipArray advances past a bracketed array, including nested ones.
+func (l *contentLexer) skipArray() {
+	depth := 0
+	for l.pos < len(l.b) {
+		switch l.b[l.pos] {
+		case '(':
+			l.skipString()
+			continue
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return
+			}
+		}
+		l.pos++
+	}
+}
+
+// skipInlineImage advances past the dictionary and data of an inline image.
+func (l *contentLexer) skipInlineImage() {
+	id := bytes.Index(l.b[l.pos:], []byte("ID"))
+	if id < 0 {
+		l.pos = len(l.b)
+		return
+	}
+	l.pos += id + 3
+	for l.pos < len(l.b) {
+		i := bytes.Index(l.b[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.b)
+			return
+		}
+		end := l.pos + i
+		if end > 0 && isWhitespace(l.b[end-1]) && (end+2 == len(l.b) || isWhitespace(l.b[end+2])) {
+			l.pos = end + 2
+			return
+		}
+		l.pos = end + 2
+	}
+}
+
+// pageContent returns the decoded content of a page, or nil for an empty page.
+func pageContent(ctx *model.Context, pageDict types.Dict) ([]byte, error) {
+	b, err := ctx.PageContent(pageDict)
+	if errors.Is(err, model.ErrNoContent) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// setPageContent replaces the content of a page with a single Flate encoded stream.
+func setPageContent(ctx *model.Context, pageDict types.Dict, content []byte) error {
+	sd, err := ctx.NewStreamDictForBuf(content)
+	if err != nil {
+		return err
+	}
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	ir, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return err
+	}
+	pageDict.Update("Contents", *ir)
+	return nil
+}
+
+// cutRanges returns b without the byte ranges [start, end) given in ranges,
+// which must be sorted and non-overlapping.
+func cutRanges(b []byte, ranges [][2]int) []byte {
+	var out bytes.Buffer
+	last := 0
+	for _, r := range ranges {
+		out.Write(b[last:r[0]])
+		out.WriteByte('\n')
+		last = r[1]
+	}
+	out.Write(b[last:])
+	return out.Bytes()
+}
+
+// pageResourceDict returns the resource sub-dictionary key (e.g. "XObject") of
+// a page, giving the page its own copies of inherited or shared dictionaries
+// so entries can be added without affecting other pages.
+func pageResourceDict(ctx *model.Context, pageDict types.Dict, inh *model.InheritedPageAttrs, key string) (types.Dict, error) {
+	resources, err := ctx.DereferenceDict(pageDict["Resources"])
+	if err != nil {
+		return nil, err
+	}
+	if resources == nil && inh != nil && inh.Resources != nil {
+		resources = inh.Resources
+	}
+	if resources == nil {
+		resources = types.NewDict()
+	}
+	resources = resources.Clone().(types.Dict)
+	pageDict["Resources"] = resources
+
+	sub, err := ctx.DereferenceDict(resources[key])
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		sub = types.NewDict()
+	} else {
+		sub = sub.Clone().(types.Dict)
+	}
+	resources[key] = sub
+	return sub, nil
+}
+
+// uniqueResourceName returns a name starting with prefix that is not yet used in d.
+func uniqueResourceName(d types.Dict, prefix string) string {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s%d", prefix, i)
+		if _, ok := d[name]; !ok {
+			return name
+		}
+	}
+}
+
+// matrix is an affine transformation [a b c d e f] as used by the cm operator.
+type matrix [6]float64
+
+var identityMatrix = matrix{1, 0, 0, 1, 0, 0}
+
+// apply transforms the point (x, y).
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// multiply returns m applied first, then n.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// invert returns the inverse of m, or false if m is not invertible.
+func (m matrix) invert() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if math.Abs(det) < 1e-12 {
+		return identityMatrix, false
+	}
+	a, b, c, d := m[3]/det, -m[1]/det, -m[2]/det, m[0]/det
+	return matrix{a, b, c, d, -(m[4]*a + m[5]*c), -(m[4]*b + m[5]*d)}, true
+}
+
+// transformRect returns the bounding box of r transformed by m.
+func transformRect(r *types.Rectangle, m matrix) *types.Rectangle {
+	xs := [4]float64{}
+	ys := [4]float64{}
+	xs[0], ys[0] = m.apply(r.LL.X, r.LL.Y)
+	xs[1], ys[1] = m.apply(r.UR.X, r.LL.Y)
+	xs[2], ys[2] = m.apply(r.LL.X, r.UR.Y)
+	xs[3], ys[3] = m.apply(r.UR.X, r.UR.Y)
+	minX, minY, maxX, maxY := xs[0], ys[0], xs[0], ys[0]
+	for i := 1; i < 4; i++ {
+		minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+	return types.NewRectangle(minX, minY, maxX, maxY)
+}
+
+// matrixEntry reads a six element matrix array from d, defaulting to identity.
+func matrixEntry(ctx *model.Context, d types.Dict, key string) matrix {
+	a, err := ctx.DereferenceArray(d[key])
+	if err != nil || len(a) != 6 {
+		return identityMatrix
+	}
+	var m matrix
+	for i, o := range a {
+		f, err := ctx.DereferenceNumber(o)
+		if err != nil {
+			return identityMatrix
+		}
+		m[i] = f
+	}
+	return m
+}
@@ -0,0 +1,182 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidCrop is returned for crop options that cannot be applied.
+var ErrInvalidCrop = errors.New("invalid crop options")
+
+// CropOptions describes the margins cut away by CropPDF.
+//
+// Pages is a page selection such as "1-3,5" or "even"; empty selects all
+// pages. Top, Right, Bottom and Left are cut from the visible page area as
+// displayed, so they follow the page rotation, in points or, with Unit
+// "percent", in percent of the page width or height. With Auto the margins
+// are instead trimmed to the content of each page plus Padding points.
+//
+// Box selects the page box that is set: "crop" (default) changes what viewers
+// show and what is merged, "trim" only marks the finished size for printing,
+// and "both" sets both.
+type CropOptions struct {
+	Pages   string  `json:"pages,omitempty"`
+	Box     string  `json:"box,omitempty"`
+	Unit    string  `json:"unit,omitempty"` // "pt" (default) or "percent"
+	Top     float64 `json:"top,omitempty"`
+	Right   float64 `json:"right,omitempty"`
+	Bottom  float64 `json:"bottom,omitempty"`
+	Left    float64 `json:"left,omitempty"`
+	Auto    bool    `json:"auto,omitempty"`
+	Padding float64 `json:"padding,omitempty"`
+}
+
+// Validate reports whether opts describe a usable crop.
+func (opts CropOptions) Validate() error {
+	if _, err := pdfapi.ParsePageSelection(opts.Pages); err != nil {
+		return fmt.Errorf("%w: invalid page selection %q", ErrInvalidCrop, opts.Pages)
+	}
+	switch strings.ToLower(opts.Box) {
+	case "", "crop", "trim", "both":
+	default:
+		return fmt.Errorf("%w: unknown box %q", ErrInvalidCrop, opts.Box)
+	}
+
+	margins := []float64{opts.Top, opts.Right, opts.Bottom, opts.Left}
+	for _, m := range margins {
+		if m < 0 {
+			return fmt.Errorf("%w: margins must not be negative", ErrInvalidCrop)
+		}
+	}
+	switch strings.ToLower(opts.Unit) {
+	case "", "pt":
+	case "percent":
+		if opts.Top+opts.Bottom >= 100 || opts.Left+opts.Right >= 100 {
+			return fmt.Errorf("%w: opposite margins must add up to less than 100 percent", ErrInvalidCrop)
+		}
+	default:
+		return fmt.Errorf("%w: unknown unit %q", ErrInvalidCrop, opts.Unit)
+	}
+
+	if opts.Auto {
+		if opts.Top != 0 || opts.Right != 0 || opts.Bottom != 0 || opts.Left != 0 {
+			return fmt.Errorf("%w: auto trim does not take margins", ErrInvalidCrop)
+		}
+		if opts.Padding < 0 {
+			return fmt.Errorf("%w: padding must not be negative", ErrInvalidCrop)
+		}
+	} else if opts.Top == 0 && opts.Right == 0 && opts.Bottom == 0 && opts.Left == 0 {
+		return fmt.Errorf("%w: no margins given", ErrInvalidCrop)
+	}
+	return nil
+}
+
+// CropPDF cuts margins from the selected pages of the PDF at pdfPath and
+// writes the result to outputPath, which may equal pdfPath. Only page boxes
+// change, so cropped content can be restored by editing them. It returns the
+// number of pages cropped; blank pages are left alone in auto mode.
+func CropPDF(pdfPath, outputPath string, opts CropOptions) (int, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	selection, _ := pdfapi.ParsePageSelection(opts.Pages)
+	pages, err := pdfapi.PagesForPageSelection(ctx.PageCount, selection, true, false)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidCrop, err)
+	}
+
+	cropped := 0
+	for i := 1; i <= ctx.PageCount; i++ {
+		if !pages[i] {
+			continue
+		}
+		ok, err := cropPage(ctx, i, opts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to crop page %d: %w", i, err)
+		}
+		if ok {
+			cropped++
+		}
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return cropped, nil
+}
+
+// cropPage sets the crop and/or trim box of a page and reports whether it did.
+func cropPage(ctx *model.Context, pageNr int, opts CropOptions) (bool, error) {
+	pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil {
+		return false, err
+	}
+
+	box := inh.MediaBox
+	if box == nil {
+		box = types.RectForFormat("A4")
+	}
+	if inh.CropBox != nil {
+		box = intersectRect(box, inh.CropBox)
+	}
+
+	var r *types.Rectangle
+	if opts.Auto {
+		bounds, err := pageContentBounds(ctx, pageDict, inh, box)
+		if err != nil {
+			return false, err
+		}
+		if bounds == nil {
+			return false, nil
+		}
+		p := opts.Padding
+		r = intersectRect(types.NewRectangle(bounds.LL.X-p, bounds.LL.Y-p, bounds.UR.X+p, bounds.UR.Y+p), box)
+	} else {
+		top, right, bottom, left := displayedMargins(opts, inh.Rotate)
+		if strings.EqualFold(opts.Unit, "percent") {
+			top, bottom = top*box.Height()/100, bottom*box.Height()/100
+			left, right = left*box.Width()/100, right*box.Width()/100
+		}
+		if left+right >= box.Width() || top+bottom >= box.Height() {
+			return false, fmt.Errorf("%w: margins are larger than page %d", ErrInvalidCrop, pageNr)
+		}
+		r = types.NewRectangle(box.LL.X+left, box.LL.Y+bottom, box.UR.X-right, box.UR.Y-top)
+	}
+
+	switch strings.ToLower(opts.Box) {
+	case "trim":
+		pageDict["TrimBox"] = r.Array()
+	case "both":
+		pageDict["CropBox"] = r.Array()
+		pageDict["TrimBox"] = r.Array()
+	default:
+		pageDict["CropBox"] = r.Array()
+	}
+	return true, nil
+}
+
+// displayedMargins maps margins given for the page as displayed onto the
+// unrotated page, returning top, right, bottom and left.
+func displayedMargins(opts CropOptions, rotate int) (float64, float64, float64, float64) {
+	t, r, b, l := opts.Top, opts.Right, opts.Bottom, opts.Left
+	switch (rotate%360 + 360) % 360 {
+	case 90:
+		return r, b, l, t
+	case 180:
+		return b, l, t, r
+	case 270:
+		return l, t, r, b
+	}
+	return t, r, b, l
+}
@@ -0,0 +1,252 @@
+package pdf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// PageRef identifies a page of a file by its base name and 1-based number.
+type PageRef struct {
+	File string `json:"file"`
+	Page int    `json:"page"`
+}
+
+// RemovedPage is a page DropDuplicatePages left out and the earlier page it repeats.
+type RemovedPage struct {
+	PageRef
+	DuplicateOf PageRef `json:"duplicateOf"`
+}
+
+// fingerprintSkipKeys are entries that point back up the object graph or
+// number a page within its document; they differ between copies of a page.
+var fingerprintSkipKeys = map[string]bool{"Parent": true, "P": true, "StructParent": true, "StructParents": true}
+
+// PageFingerprints returns a hash of every page of the PDF at pdfPath. The
+// hash covers the content streams and everything they use, such as fonts
+// and images, by value, so a page copied into another file keeps its
+// fingerprint.
+func PageFingerprints(pdfPath string) ([]string, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return pageFingerprints(ctx)
+}
+
+func pageFingerprints(ctx *model.Context) ([]string, error) {
+	f := newFingerprinter(ctx)
+	prints := make([]string, 0, ctx.PageCount)
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", pageNr, err)
+		}
+		print, err := f.page(pageDict, inh)
+		if err != nil {
+			return nil, err
+		}
+		prints = append(prints, print)
+	}
+	return prints, nil
+}
+
+// fingerprinter hashes PDF objects by value, resolving references.
+type fingerprinter struct {
+	ctx    *model.Context
+	memo   map[int][]byte // Hashes of indirect objects by object number
+	active map[int]bool   // Objects being hashed, to break reference cycles
+	cycles int            // Number of cycles broken so far
+}
+
+func newFingerprinter(ctx *model.Context) *fingerprinter {
+	return &fingerprinter{ctx: ctx, memo: map[int][]byte{}, active: map[int]bool{}}
+}
+
+// page returns the fingerprint of a page.
+func (f *fingerprinter) page(pageDict types.Dict, inh *model.InheritedPageAttrs) (string, error) {
+	h := sha256.New()
+	if err := f.write(h, pageDict); err != nil {
+		return "", err
+	}
+	// Inherited attributes count as if they were set on the page.
+	if err := f.write(h, inh.Resources); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "%v %v %d", inh.MediaBox, inh.CropBox, inh.Rotate)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// write adds the digest of o to h.
+func (f *fingerprinter) write(h hash.Hash, o types.Object) error {
+	sum, err := f.digest(o)
+	if err != nil {
+		return err
+	}
+	h.Write(sum)
+	return nil
+}
+
+// digest returns the hash of o. References hash like the objects they refer
+// to, so a value is hashed the same whether it is stored directly or as an
+// indirect object.
+func (f *fingerprinter) digest(o types.Object) ([]byte, error) {
+	if ir, ok := o.(types.IndirectRef); ok {
+		nr := ir.ObjectNumber.Value()
+		if f.active[nr] {
+			f.cycles++
+			sum := sha256.Sum256([]byte("cycle"))
+			return sum[:], nil
+		}
+		if sum, ok := f.memo[nr]; ok {
+			return sum, nil
+		}
+		obj, err := f.ctx.Dereference(ir)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := obj.(types.StreamDict); ok {
+			sd, _, err := f.ctx.DereferenceStreamDict(ir)
+			if err != nil {
+				return nil, err
+			}
+			if sd.Raw == nil {
+				if err := sd.Encode(); err != nil {
+					return nil, err
+				}
+			}
+			obj = *sd
+		}
+		f.active[nr] = true
+		cycles := f.cycles
+		sum, err := f.digest(obj)
+		delete(f.active, nr)
+		if err != nil {
+			return nil, err
+		}
+		// A hash that cut a cycle short depends on where it was entered.
+		if f.cycles == cycles {
+			f.memo[nr] = sum
+		}
+		return sum, nil
+	}
+
+	h := sha256.New()
+	switch o := o.(type) {
+	case nil:
+		h.Write([]byte("null"))
+	case types.Dict:
+		h.Write([]byte("<<"))
+		for _, k := range sortedKeys(o) {
+			if fingerprintSkipKeys[k] {
+				continue
+			}
+			h.Write([]byte("/" + k + " "))
+			if err := f.write(h, o[k]); err != nil {
+				return nil, err
+			}
+		}
+		h.Write([]byte(">>"))
+	case types.StreamDict:
+		d := o.Dict.Clone().(types.Dict)
+		d.Delete("Length")
+		if err := f.write(h, d); err != nil {
+			return nil, err
+		}
+		h.Write([]byte("stream" + strconv.Itoa(len(o.Raw))))
+		h.Write(o.Raw)
+	case types.Array:
+		h.Write([]byte("["))
+		for _, e := range o {
+			if err := f.write(h, e); err != nil {
+				return nil, err
+			}
+		}
+		h.Write([]byte("]"))
+	case types.Integer:
+		// Writers differ in whether they keep 0 or write 0.00.
+		fmt.Fprintf(h, "number %g", float64(o))
+	case types.Float:
+		fmt.Fprintf(h, "number %g", float64(o))
+	default:
+		fmt.Fprintf(h, "%T %s", o, o.PDFString())
+	}
+	return h.Sum(nil), nil
+}
+
+// DropDuplicatePages leaves out pages that repeat an earlier page of files,
+// in the same or an earlier file. Files that lose pages are copied to dir
+// without them, keeping their names; files that lose all pages are dropped.
+// It returns the files to merge instead and the removed pages.
+func DropDuplicatePages(files []string, dir string) ([]string, []RemovedPage, error) {
+	seen := map[string]PageRef{}
+	var kept []string
+	var removed []RemovedPage
+	for _, file := range files {
+		ctx, err := pdfapi.ReadContextFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read PDF: %w", err)
+		}
+		prints, err := pageFingerprints(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fingerprint pages: %w", err)
+		}
+
+		name := filepath.Base(file)
+		var pages []int
+		for i, print := range prints {
+			page := PageRef{File: name, Page: i + 1}
+			if first, ok := seen[print]; ok {
+				removed = append(removed, RemovedPage{PageRef: page, DuplicateOf: first})
+				continue
+			}
+			seen[print] = page
+			pages = append(pages, i+1)
+		}
+
+		switch len(pages) {
+		case len(prints):
+			kept = append(kept, file)
+		case 0:
+		default:
+			copyDir, err := os.MkdirTemp(dir, "dedup-")
+			if err != nil {
+				return nil, nil, err
+			}
+			copyPath := filepath.Join(copyDir, name)
+			if err := keepPages(ctx, file, copyPath, pages); err != nil {
+				return nil, nil, err
+			}
+			kept = append(kept, copyPath)
+		}
+	}
+	return kept, removed, nil
+}
+
+// keepPages writes the given pages of file, read into ctx, to outputPath,
+// carrying their page labels along.
+func keepPages(ctx *model.Context, file, outputPath string, pages []int) error {
+	labels, err := pageLabelsOf(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read page labels: %w", err)
+	}
+	selection := make([]string, len(pages))
+	for i, p := range pages {
+		selection[i] = strconv.Itoa(p)
+	}
+	if err := pdfapi.CollectFile(file, outputPath, selection, model.NewDefaultConfiguration()); err != nil {
+		return fmt.Errorf("failed to remove pages: %w", err)
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return SetPageLabels(outputPath, outputPath, labels.Keep(pages))
+}
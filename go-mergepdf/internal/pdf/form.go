@@ -0,0 +1,592 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/form"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+var (
+	// ErrUnknownFormField is returned when a value is given for a field the form does not have.
+	ErrUnknownFormField = errors.New("unknown form field")
+	// ErrInvalidFormValue is returned when a value does not fit the type or options of its field.
+	ErrInvalidFormValue = errors.New("invalid form field value")
+)
+
+// FormField describes a single AcroForm field. Value is a string for text,
+// date, radio button and combo box fields, a bool for check boxes and a
+// string slice for list boxes.
+type FormField struct {
+	Name     string      `json:"name"`
+	ID       string      `json:"id"`
+	Type     string      `json:"type"`
+	Value    interface{} `json:"value"`
+	Default  interface{} `json:"default,omitempty"`
+	Options  []string    `json:"options,omitempty"`
+	Pages    []int       `json:"pages"`
+	Locked   bool        `json:"locked"`
+	Multiple bool        `json:"multiple,omitempty"`
+}
+
+// fieldPrefix is prepended to the top-level field names of the n-th file when
+// forms are merged with renaming.
+func fieldPrefix(n int) string {
+	return fmt.Sprintf("doc%d_", n)
+}
+
+// prefixedFieldName matches field names that start with a fieldPrefix.
+var prefixedFieldName = regexp.MustCompile(`^doc\d+_`)
+
+// exportForm returns the form of the PDF at pdfPath, or nil if it has no fields.
+func exportForm(pdfPath string) (*form.FormGroup, error) {
+	f, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.EXPORTFORMFIELDS
+	ctx, err := pdfapi.ReadValidateAndOptimize(f, conf)
+	if err != nil {
+		return nil, err
+	}
+	if fields, err := acroFormFields(ctx); err != nil || len(fields) == 0 {
+		return nil, err
+	}
+
+	fg, ok, err := form.ExportForm(ctx.XRefTable, filepath.Base(pdfPath))
+	if err != nil || !ok || len(fg.Forms) == 0 {
+		return nil, err
+	}
+	return fg, nil
+}
+
+// ListFormFields returns the form fields of the PDF at pdfPath in document order
+// per field type. A PDF without a form yields an empty list.
+func ListFormFields(pdfPath string) ([]FormField, error) {
+	fg, err := exportForm(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form: %w", err)
+	}
+
+	fields := []FormField{}
+	if fg == nil {
+		return fields, nil
+	}
+
+	f := fg.Forms[0]
+	for _, tf := range f.TextFields {
+		fields = append(fields, FormField{Name: tf.Name, ID: tf.ID, Type: "text", Value: tf.Value, Default: omitEmpty(tf.Default), Pages: tf.Pages, Locked: tf.Locked})
+	}
+	for _, df := range f.DateFields {
+		fields = append(fields, FormField{Name: df.Name, ID: df.ID, Type: "date", Value: df.Value, Default: omitEmpty(df.Default), Pages: df.Pages, Locked: df.Locked})
+	}
+	for _, cb := range f.CheckBoxes {
+		fields = append(fields, FormField{Name: cb.Name, ID: cb.ID, Type: "checkbox", Value: cb.Value, Default: cb.Default, Pages: cb.Pages, Locked: cb.Locked})
+	}
+	for _, rb := range f.RadioButtonGroups {
+		fields = append(fields, FormField{Name: rb.Name, ID: rb.ID, Type: "radio", Value: rb.Value, Default: omitEmpty(rb.Default), Options: rb.Options, Pages: rb.Pages, Locked: rb.Locked})
+	}
+	for _, cb := range f.ComboBoxes {
+		fields = append(fields, FormField{Name: cb.Name, ID: cb.ID, Type: "combobox", Value: cb.Value, Default: omitEmpty(cb.Default), Options: cb.Options, Pages: cb.Pages, Locked: cb.Locked})
+	}
+	for _, lb := range f.ListBoxes {
+		values := lb.Values
+		if values == nil {
+			values = []string{}
+		}
+		var def interface{}
+		if len(lb.Defaults) > 0 {
+			def = lb.Defaults
+		}
+		fields = append(fields, FormField{Name: lb.Name, ID: lb.ID, Type: "listbox", Value: values, Default: def, Options: lb.Options, Pages: lb.Pages, Locked: lb.Locked, Multiple: lb.Multi})
+	}
+	return fields, nil
+}
+
+func omitEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// FillFormFields fills the form of the PDF at pdfPath and writes the result to
+// outputPath, which may equal pdfPath. values maps field names or IDs to their
+// new value; check boxes accept true/false, yes/no, on/off or 1/0 and list
+// boxes take a comma separated list of options. Fields not mentioned keep
+// their current value. With flatten set the filled fields are turned into
+// static page content.
+func FillFormFields(pdfPath, outputPath string, values map[string]string, flatten bool) error {
+	fg, err := exportForm(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read form: %w", err)
+	}
+	if fg == nil {
+		return fmt.Errorf("%w: document has no form fields", ErrUnknownFormField)
+	}
+
+	if err := setFormValues(&fg.Forms[0], values); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fg)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(pdfPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := outputPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := pdfapi.FillForm(in, bytes.NewReader(data), out, model.NewDefaultConfiguration()); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to fill form: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if flatten {
+		if _, err := FlattenForm(outputPath, outputPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFormValues applies values to the matching fields of f.
+func setFormValues(f *form.Form, values map[string]string) error {
+	for key, v := range values {
+		if err := setFormValue(f, key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setFormValue(f *form.Form, key, v string) error {
+	for _, tf := range f.TextFields {
+		if tf.Name == key || tf.ID == key {
+			tf.Value = v
+			return nil
+		}
+	}
+	for _, df := range f.DateFields {
+		if df.Name == key || df.ID == key {
+			df.Value = v
+			return nil
+		}
+	}
+	for _, cb := range f.CheckBoxes {
+		if cb.Name == key || cb.ID == key {
+			b, ok := parseCheckBoxValue(v)
+			if !ok {
+				return fmt.Errorf("%w: %s: %q is not a check box value", ErrInvalidFormValue, key, v)
+			}
+			cb.Value = b
+			return nil
+		}
+	}
+	for _, rb := range f.RadioButtonGroups {
+		if rb.Name == key || rb.ID == key {
+			if v != "" && !containsString(rb.Options, v) {
+				return fmt.Errorf("%w: %s: %q is not one of %v", ErrInvalidFormValue, key, v, rb.Options)
+			}
+			rb.Value = v
+			return nil
+		}
+	}
+	for _, cb := range f.ComboBoxes {
+		if cb.Name == key || cb.ID == key {
+			if v != "" && !cb.Editable && !containsString(cb.Options, v) {
+				return fmt.Errorf("%w: %s: %q is not one of %v", ErrInvalidFormValue, key, v, cb.Options)
+			}
+			cb.Value = v
+			return nil
+		}
+	}
+	for _, lb := range f.ListBoxes {
+		if lb.Name == key || lb.ID == key {
+			var selected []string
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s == "" {
+					continue
+				}
+				if !containsString(lb.Options, s) {
+					return fmt.Errorf("%w: %s: %q is not one of %v", ErrInvalidFormValue, key, s, lb.Options)
+				}
+				selected = append(selected, s)
+			}
+			if len(selected) > 1 && !lb.Multi {
+				return fmt.Errorf("%w: %s: only one option may be selected", ErrInvalidFormValue, key)
+			}
+			lb.Values = selected
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownFormField, key)
+}
+
+func parseCheckBoxValue(v string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "yes", "on", "1", "x":
+		return true, true
+	case "false", "no", "off", "0", "":
+		return false, true
+	}
+	return false, false
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FlattenForm draws the current appearance of every visible form field onto
+// its page and removes the form, so the values can no longer be edited.
+// Widgets without a normal appearance stream have nothing to draw and are
+// dropped with the form.
+// outputPath may equal pdfPath. It returns the number of widgets flattened.
+func FlattenForm(pdfPath, outputPath string) (int, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	count := 0
+	for i := 1; i <= ctx.PageCount; i++ {
+		n, err := flattenPageWidgets(ctx, i)
+		if err != nil {
+			return 0, fmt.Errorf("failed to flatten page %d: %w", i, err)
+		}
+		count += n
+	}
+	ctx.RootDict.Delete("AcroForm")
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return count, nil
+}
+
+// annotFlagHidden and annotFlagNoView mark annotations that are never drawn.
+const (
+	annotFlagHidden = 1 << 1
+	annotFlagNoView = 1 << 5
+)
+
+// flattenPageWidgets moves the widget annotations of a page into its content.
+func flattenPageWidgets(ctx *model.Context, pageNr int) (int, error) {
+	isWidget := func(d types.Dict) bool {
+		return d.NameEntry("Subtype") != nil && *d.NameEntry("Subtype") == "Widget"
+	}
+	return flattenPageAnnots(ctx, pageNr, isWidget, false)
+}
+
+// flattenPageAnnots draws the annotations of a page that match into its
+// content and removes them. Matching annotations that are hidden or have no
+// appearance are removed too, or kept with keepUndrawn.
+func flattenPageAnnots(ctx *model.Context, pageNr int, match func(d types.Dict) bool, keepUndrawn bool) (int, error) {
+	pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil {
+		return 0, err
+	}
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil || len(annots) == 0 {
+		return 0, err
+	}
+
+	var draw bytes.Buffer
+	var kept types.Array
+	var xobjects types.Dict
+	count := 0
+
+	for _, o := range annots {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return 0, err
+		}
+		if d == nil || !match(d) {
+			kept = append(kept, o)
+			continue
+		}
+
+		ir, bbox, m, ok, err := annotAppearance(ctx, d)
+		if err != nil {
+			return 0, err
+		}
+		var rect *types.Rectangle
+		if ok {
+			rect, err = ctx.RectForArray(d.ArrayEntry("Rect"))
+			ok = err == nil && rect != nil
+		}
+		var box *types.Rectangle
+		if ok {
+			box = transformRect(bbox, m)
+			ok = box.Width() != 0 && box.Height() != 0
+		}
+		if !ok {
+			if keepUndrawn {
+				kept = append(kept, o)
+			}
+			continue
+		}
+		sx := rect.Width() / box.Width()
+		sy := rect.Height() / box.Height()
+		tx := rect.LL.X - box.LL.X*sx
+		ty := rect.LL.Y - box.LL.Y*sy
+
+		if xobjects == nil {
+			if xobjects, err = pageResourceDict(ctx, pageDict, inh, "XObject"); err != nil {
+				return 0, err
+			}
+		}
+		name := uniqueResourceName(xobjects, "Fm")
+		xobjects[name] = *ir
+		fmt.Fprintf(&draw, "q %.4f 0 0 %.4f %.4f %.4f cm /%s Do Q\n", sx, sy, tx, ty, name)
+		count++
+	}
+
+	if len(kept) == len(annots) {
+		return 0, nil
+	}
+	if len(kept) == 0 {
+		pageDict.Delete("Annots")
+	} else {
+		pageDict["Annots"] = kept
+	}
+	if draw.Len() == 0 {
+		return count, nil
+	}
+
+	content, err := pageContent(ctx, pageDict)
+	if err != nil {
+		return 0, err
+	}
+	var b bytes.Buffer
+	if len(content) > 0 {
+		b.WriteString("q\n")
+		b.Write(content)
+		b.WriteString("\nQ\n")
+	}
+	b.Write(draw.Bytes())
+	return count, setPageContent(ctx, pageDict, b.Bytes())
+}
+
+// annotAppearance returns the normal appearance stream of a visible
+// annotation, such as a form widget, along with its bounding box and matrix.
+// ok is false for hidden annotations and annotations without an appearance.
+func annotAppearance(ctx *model.Context, d types.Dict) (*types.IndirectRef, *types.Rectangle, matrix, bool, error) {
+	if f := d.IntEntry("F"); f != nil && *f&(annotFlagHidden|annotFlagNoView) != 0 {
+		return nil, nil, identityMatrix, false, nil
+	}
+	ap, err := ctx.DereferenceDict(d["AP"])
+	if err != nil || ap == nil {
+		return nil, nil, identityMatrix, false, err
+	}
+
+	n, ok := ap.Find("N")
+	if !ok {
+		return nil, nil, identityMatrix, false, nil
+	}
+	// Check boxes and radio buttons keep one appearance per state, selected by AS.
+	if states, err := ctx.DereferenceDict(n); err == nil && states != nil {
+		as := d.NameEntry("AS")
+		if as == nil {
+			return nil, nil, identityMatrix, false, nil
+		}
+		if n, ok = states.Find(*as); !ok {
+			return nil, nil, identityMatrix, false, nil
+		}
+	}
+
+	ir, ok := n.(types.IndirectRef)
+	if !ok {
+		return nil, nil, identityMatrix, false, nil
+	}
+	sd, _, err := ctx.DereferenceStreamDict(ir)
+	if err != nil || sd == nil {
+		return nil, nil, identityMatrix, false, err
+	}
+	bbox, err := ctx.RectForArray(sd.ArrayEntry("BBox"))
+	if err != nil || bbox == nil {
+		return nil, nil, identityMatrix, false, nil
+	}
+	sd.Dict["Type"] = types.Name("XObject")
+	sd.Dict["Subtype"] = types.Name("Form")
+	return &ir, bbox, matrixEntry(ctx, sd.Dict, "Matrix"), true, nil
+}
+
+// MergeFormPDFs merges files like MergePDFs but first renames the top-level
+// form fields of the n-th file to doc<n>_<name>, so identical forms merged
+// together keep independent values.
+func MergeFormPDFs(files []string, outputPath string) error {
+	tmpDir, err := os.MkdirTemp("", "mergeform-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	renamed := make([]string, len(files))
+	for i, file := range files {
+		ctx, err := pdfapi.ReadContextFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(file), err)
+		}
+		ok, err := prefixFormFields(ctx, fieldPrefix(i+1))
+		if err != nil {
+			return fmt.Errorf("failed to rename form fields of %s: %w", filepath.Base(file), err)
+		}
+		if !ok {
+			renamed[i] = file
+			continue
+		}
+		renamed[i] = filepath.Join(tmpDir, strconv.Itoa(i)+".pdf")
+		if err := writeContextFile(ctx, renamed[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := MergePDFs(renamed, outputPath); err != nil {
+		return err
+	}
+	return unwrapMergedFields(outputPath)
+}
+
+// prefixFormFields prepends prefix to the partial name of every top-level
+// field. It reports whether the document has any fields.
+func prefixFormFields(ctx *model.Context, prefix string) (bool, error) {
+	fields, err := acroFormFields(ctx)
+	if err != nil || len(fields) == 0 {
+		return false, err
+	}
+	for _, o := range fields {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return false, err
+		}
+		if d == nil {
+			continue
+		}
+		name := ""
+		if t, ok := d.Find("T"); ok {
+			if name, err = ctx.DereferenceText(t); err != nil {
+				return false, err
+			}
+		}
+		s, err := types.EscapedUTF16String(prefix + name)
+		if err != nil {
+			return false, err
+		}
+		d["T"] = types.StringLiteral(*s)
+	}
+	return true, nil
+}
+
+// acroFormFields returns the top-level fields of the document form.
+func acroFormFields(ctx *model.Context) (types.Array, error) {
+	acroForm, err := ctx.DereferenceDict(ctx.RootDict["AcroForm"])
+	if err != nil || acroForm == nil {
+		return nil, err
+	}
+	return ctx.DereferenceArray(acroForm["Fields"])
+}
+
+// unwrapMergedFields lifts the fields out of the numbered parent fields that
+// pdfcpu wraps around the form of every merged file after the first, so the
+// renamed fields end up at the top level under their own names.
+func unwrapMergedFields(pdfPath string) error {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return err
+	}
+	acroForm, err := ctx.DereferenceDict(ctx.RootDict["AcroForm"])
+	if err != nil || acroForm == nil {
+		return err
+	}
+	fields, err := ctx.DereferenceArray(acroForm["Fields"])
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	var out types.Array
+	changed := false
+	for _, o := range fields {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil {
+			return err
+		}
+		kids := d.ArrayEntry("Kids")
+		if len(d) != 2 || kids == nil || d["T"] == nil {
+			out = append(out, o)
+			continue
+		}
+		if !renamedKids(ctx, kids) {
+			out = append(out, o)
+			continue
+		}
+		for _, k := range kids {
+			kd, err := ctx.DereferenceDict(k)
+			if err != nil {
+				return err
+			}
+			kd.Delete("Parent")
+			out = append(out, k)
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	acroForm["Fields"] = out
+	return writeContextFile(ctx, pdfPath)
+}
+
+// renamedKids reports whether every field in kids carries a MergeFormPDFs prefix.
+func renamedKids(ctx *model.Context, kids types.Array) bool {
+	for _, k := range kids {
+		kd, err := ctx.DereferenceDict(k)
+		if err != nil || kd == nil {
+			return false
+		}
+		t, ok := kd.Find("T")
+		if !ok {
+			return false
+		}
+		name, err := ctx.DereferenceText(t)
+		if err != nil || !prefixedFieldName.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// srgbProfileDescription names the profile and the PDF/A output condition.
+const srgbProfileDescription = "sRGB IEC61966-2.1"
+
+// d50 is the ICC profile connection space illuminant.
+var d50 = [3]float64{0.9642, 1.0, 0.8249}
+
+// srgbProfile builds a version 2 ICC display profile for sRGB: the sRGB
+// primaries adapted to D50 and the sRGB tone curve sampled at 1024 points.
+// It is small enough to embed in every PDF/A output intent.
+func srgbProfile() []byte {
+	xyz := func(v [3]float64) []byte {
+		var b bytes.Buffer
+		b.WriteString("XYZ \x00\x00\x00\x00")
+		for _, c := range v {
+			binary.Write(&b, binary.BigEndian, s15Fixed16(c))
+		}
+		return b.Bytes()
+	}
+
+	var desc bytes.Buffer
+	desc.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&desc, binary.BigEndian, uint32(len(srgbProfileDescription)+1))
+	desc.WriteString(srgbProfileDescription + "\x00")
+	// Empty Unicode and ScriptCode descriptions.
+	desc.Write(make([]byte, 4+4+2+1+67))
+
+	var curve bytes.Buffer
+	curve.WriteString("curv\x00\x00\x00\x00")
+	const samples = 1024
+	binary.Write(&curve, binary.BigEndian, uint32(samples))
+	for i := 0; i < samples; i++ {
+		v := float64(i) / (samples - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.Write(&curve, binary.BigEndian, uint16(math.Round(v*65535)))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc.Bytes()},
+		{"cprt", []byte("text\x00\x00\x00\x00No copyright, use freely\x00")},
+		{"wtpt", xyz(d50)},
+		{"rXYZ", xyz([3]float64{0.4361, 0.2225, 0.0139})},
+		{"gXYZ", xyz([3]float64{0.3851, 0.7169, 0.0971})},
+		{"bXYZ", xyz([3]float64{0.1431, 0.0606, 0.7141})},
+		{"rTRC", curve.Bytes()},
+		{"gTRC", nil},
+		{"bTRC", nil},
+	}
+
+	// The three tone curves share one copy of the data.
+	table := 128 + 4 + 12*len(tags)
+	var data bytes.Buffer
+	var dir bytes.Buffer
+	binary.Write(&dir, binary.BigEndian, uint32(len(tags)))
+	var curveOffset, curveSize uint32
+	for _, t := range tags {
+		if t.data == nil {
+			dir.WriteString(t.sig)
+			binary.Write(&dir, binary.BigEndian, curveOffset)
+			binary.Write(&dir, binary.BigEndian, curveSize)
+			continue
+		}
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+		offset := uint32(table + data.Len())
+		data.Write(t.data)
+		dir.WriteString(t.sig)
+		binary.Write(&dir, binary.BigEndian, offset)
+		binary.Write(&dir, binary.BigEndian, uint32(len(t.data)))
+		if t.sig == "rTRC" {
+			curveOffset, curveSize = offset, uint32(len(t.data))
+		}
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.BigEndian, uint32(table+data.Len()))
+	header.Write(make([]byte, 4))       // preferred CMM
+	header.Write([]byte{2, 0x10, 0, 0}) // version 2.1
+	header.WriteString("mntrRGB XYZ ")
+	for _, v := range []uint16{2024, 1, 1, 0, 0, 0} {
+		binary.Write(&header, binary.BigEndian, v)
+	}
+	header.WriteString("acsp")
+	header.Write(make([]byte, 4+4+4+4+8+4)) // platform, flags, device and rendering intent
+	for _, c := range d50 {
+		binary.Write(&header, binary.BigEndian, s15Fixed16(c))
+	}
+	header.Write(make([]byte, 4+44)) // creator and reserved
+
+	return append(append(header.Bytes(), dir.Bytes()...), data.Bytes()...)
+}
+
+// s15Fixed16 encodes v as an ICC signed 15.16 fixed point number.
+func s15Fixed16(v float64) int32 {
+	return int32(math.Round(v * 65536))
+}
@@ -0,0 +1,118 @@
+package pdf
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// ErrInvalidImageExtraction is returned for an invalid page selection.
+var ErrInvalidImageExtraction = errors.New("invalid image extraction options")
+
+// ErrNoImages is returned when the selected pages contain no images.
+var ErrNoImages = errors.New("no images found")
+
+// ImageExtractReport summarizes an ExtractImages run. Images that pdfcpu
+// cannot decode, e.g. because of an unsupported filter, are skipped.
+type ImageExtractReport struct {
+	Images  int            `json:"images"`
+	Skipped []SkippedImage `json:"skipped,omitempty"`
+}
+
+// SkippedImage identifies an image that could not be extracted.
+type SkippedImage struct {
+	Page   int    `json:"page"`
+	Object int    `json:"object"`
+	Error  string `json:"error"`
+}
+
+// ExtractImages writes the images used by the selected pages of pdfPath to a
+// ZIP archive at zipPath. pages is a page selection such as "1-3,5"; empty
+// selects all pages. JPEG and JPEG 2000 images keep their original encoding,
+// other images are converted to PNG or TIFF. Entries are named
+// page-<page>-obj-<object number>.<ext>; an image used on several pages is
+// stored once, under the first of them.
+func ExtractImages(pdfPath, zipPath, pages string) (*ImageExtractReport, error) {
+	selection, err := pdfapi.ParsePageSelection(pages)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid page selection %q", ErrInvalidImageExtraction, pages)
+	}
+
+	f, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	defer f.Close()
+
+	config := model.NewDefaultConfiguration()
+	config.Cmd = model.EXTRACTIMAGES
+	ctx, err := pdfapi.ReadValidateAndOptimize(f, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	selected, err := pdfapi.PagesForPageSelection(ctx.PageCount, selection, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImageExtraction, err)
+	}
+	pageNrs := make([]int, 0, len(selected))
+	for i, ok := range selected {
+		if ok {
+			pageNrs = append(pageNrs, i)
+		}
+	}
+	sort.Ints(pageNrs)
+
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+
+	report := &ImageExtractReport{}
+	done := map[int]bool{}
+	digits := len(fmt.Sprint(ctx.PageCount))
+	for _, pageNr := range pageNrs {
+		for _, objNr := range pdfcpu.ImageObjNrs(ctx, pageNr) {
+			if done[objNr] {
+				continue
+			}
+			done[objNr] = true
+
+			imageObj := ctx.Optimize.ImageObjects[objNr]
+			img, err := pdfcpu.ExtractImage(ctx, imageObj.ImageDict, false, imageObj.ResourceNames[pageNr-1], objNr, false)
+			if err == nil && (img == nil || img.Reader == nil) {
+				err = errors.New("unsupported image encoding")
+			}
+			if err != nil {
+				report.Skipped = append(report.Skipped, SkippedImage{Page: pageNr, Object: objNr, Error: err.Error()})
+				continue
+			}
+
+			w, err := zw.Create(fmt.Sprintf("page-%0*d-obj-%d.%s", digits, pageNr, objNr, img.FileType))
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(w, img); err != nil {
+				return nil, fmt.Errorf("failed to write image: %w", err)
+			}
+			report.Images++
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if report.Images == 0 && len(report.Skipped) == 0 {
+		return nil, ErrNoImages
+	}
+	return report, nil
+}
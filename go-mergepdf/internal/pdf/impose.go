@@ -0,0 +1,112 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidImposition is returned for imposition options that cannot be applied.
+var ErrInvalidImposition = errors.New("invalid imposition options")
+
+// ImposeOptions describes how pages are arranged on sheets by ImposePDF.
+//
+// Mode "nup" (default) places N pages (2, 4, 6 or 9) on each sheet, filling
+// rows first or, with Order "column", columns first. Mode "booklet" reorders
+// pages for saddle-stitch folding, two per sheet side, padding with blank pages
+// to a multiple of four.
+//
+// PaperSize is the sheet size, e.g. A4 or Letter, with an L suffix for
+// landscape (A4L); it defaults to A4.
+type ImposeOptions struct {
+	Mode      string `json:"mode,omitempty"`
+	N         int    `json:"n,omitempty"`
+	PaperSize string `json:"paperSize,omitempty"`
+	Border    bool   `json:"border,omitempty"`
+	Order     string `json:"order,omitempty"`
+}
+
+// nupValues are the page counts per sheet supported in n-up mode.
+var nupValues = []int{2, 4, 6, 9}
+
+// Validate reports whether opts describe a supported imposition.
+func (opts ImposeOptions) Validate() error {
+	_, err := opts.config()
+	return err
+}
+
+// config translates opts into a pdfcpu n-up configuration.
+func (opts ImposeOptions) config() (*model.NUp, error) {
+	paperSize := opts.PaperSize
+	if paperSize == "" {
+		paperSize = "A4"
+	}
+	if strings.ContainsAny(paperSize, ",:") {
+		return nil, fmt.Errorf("%w: unknown paper size %q", ErrInvalidImposition, paperSize)
+	}
+	desc := "formsize:" + paperSize
+
+	switch strings.ToLower(opts.Mode) {
+	case "booklet":
+		if opts.N != 0 && opts.N != 2 {
+			return nil, fmt.Errorf("%w: booklets place 2 pages per sheet side", ErrInvalidImposition)
+		}
+		if opts.Border {
+			desc += ", border:on"
+		}
+		nup, err := pdfcpu.PDFBookletConfig(2, desc, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImposition, err)
+		}
+		return nup, nil
+
+	case "", "nup":
+		if !types.IntMemberOf(opts.N, nupValues) {
+			return nil, fmt.Errorf("%w: n must be one of 2, 4, 6 or 9", ErrInvalidImposition)
+		}
+		switch strings.ToLower(opts.Order) {
+		case "", "row":
+			desc += ", orientation:rd"
+		case "column":
+			desc += ", orientation:dr"
+		default:
+			return nil, fmt.Errorf("%w: unknown order %q", ErrInvalidImposition, opts.Order)
+		}
+		if opts.Border {
+			desc += ", border:on"
+		} else {
+			desc += ", border:off"
+		}
+		nup, err := pdfcpu.PDFNUpConfig(opts.N, desc, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImposition, err)
+		}
+		return nup, nil
+	}
+	return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidImposition, opts.Mode)
+}
+
+// ImposePDF arranges the pages of pdfPath on sheets as described by opts and
+// writes the result to outputPath.
+func ImposePDF(pdfPath, outputPath string, opts ImposeOptions) error {
+	nup, err := opts.config()
+	if err != nil {
+		return err
+	}
+
+	config := model.NewDefaultConfiguration()
+	if strings.EqualFold(opts.Mode, "booklet") {
+		err = pdfapi.BookletFile([]string{pdfPath}, outputPath, nil, nup, config)
+	} else {
+		err = pdfapi.NUpFile([]string{pdfPath}, outputPath, nil, nup, config)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to impose pages: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidInsert is returned for insert options that do not fit the document,
+// such as a position past its last page.
+var ErrInvalidInsert = errors.New("invalid insert options")
+
+// InsertOptions describes where InsertPages puts new pages and which.
+//
+// After is the page the new pages follow; 0 inserts before the first page.
+// Pages selects pages of the source file, e.g. "1" or "2-3"; empty inserts
+// all of them. Without a source file Blank pages are inserted instead, sized
+// like the page they follow (or precede at the start) unless Size names a
+// paper size such as A4 or Letter, with an L suffix for landscape.
+type InsertOptions struct {
+	After int    `json:"after"`
+	Pages string `json:"pages,omitempty"`
+	Blank int    `json:"blank,omitempty"`
+	Size  string `json:"size,omitempty"`
+}
+
+// maxBlankPages limits the number of blank pages inserted at once.
+const maxBlankPages = 100
+
+// Validate reports whether opts are usable on their own; the position is
+// checked against the document by InsertPages.
+func (opts InsertOptions) Validate(withSource bool) error {
+	if opts.After < 0 {
+		return fmt.Errorf("%w: after must not be negative", ErrInvalidInsert)
+	}
+	if withSource {
+		if opts.Blank != 0 || opts.Size != "" {
+			return fmt.Errorf("%w: blank and size only apply without a source file", ErrInvalidInsert)
+		}
+		if _, err := pdfapi.ParsePageSelection(opts.Pages); err != nil {
+			return fmt.Errorf("%w: invalid page selection %q", ErrInvalidInsert, opts.Pages)
+		}
+		return nil
+	}
+
+	if opts.Pages != "" {
+		return fmt.Errorf("%w: pages needs a source file", ErrInvalidInsert)
+	}
+	if opts.Blank < 1 || opts.Blank > maxBlankPages {
+		return fmt.Errorf("%w: blank must be between 1 and %d", ErrInvalidInsert, maxBlankPages)
+	}
+	if opts.Size != "" {
+		if _, _, err := types.ParsePageFormat(opts.Size); err != nil {
+			return fmt.Errorf("%w: unknown size %q", ErrInvalidInsert, opts.Size)
+		}
+	}
+	return nil
+}
+
+// InsertPages inserts pages into the PDF at pdfPath and writes the result to
+// outputPath, which may equal pdfPath. The pages come from sourcePath, or are
+// blank when sourcePath is empty. It returns the number of pages inserted.
+func InsertPages(pdfPath, sourcePath, outputPath string, opts InsertOptions) (int, error) {
+	if err := opts.Validate(sourcePath != ""); err != nil {
+		return 0, err
+	}
+
+	pageCount, err := pdfapi.PageCountFile(pdfPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if opts.After > pageCount {
+		return 0, fmt.Errorf("%w: after page %d, document has %d pages", ErrInvalidInsert, opts.After, pageCount)
+	}
+
+	if sourcePath == "" {
+		return insertBlankPages(pdfPath, outputPath, opts)
+	}
+	return insertFilePages(pdfPath, sourcePath, outputPath, pageCount, opts)
+}
+
+// insertBlankPages inserts opts.Blank empty pages after page opts.After.
+func insertBlankPages(pdfPath, outputPath string, opts InsertOptions) (int, error) {
+	var dim *types.Dim
+	if opts.Size != "" {
+		dim, _, _ = types.ParsePageFormat(opts.Size)
+	}
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	// pdfcpu inserts next to selected pages, so the first page is used with
+	// before set when inserting at the start.
+	page, before := opts.After, false
+	if page == 0 {
+		page, before = 1, true
+	}
+	for i := 0; i < opts.Blank; i++ {
+		if err := ctx.InsertBlankPages(types.IntSet{page: true}, dim, before); err != nil {
+			return 0, fmt.Errorf("failed to insert blank page: %w", err)
+		}
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return opts.Blank, nil
+}
+
+// insertFilePages appends the selected source pages to the document and then
+// collects all pages in their final order.
+func insertFilePages(pdfPath, sourcePath, outputPath string, pageCount int, opts InsertOptions) (int, error) {
+	config := model.NewDefaultConfiguration()
+
+	if opts.Pages != "" {
+		selection, _ := pdfapi.ParsePageSelection(opts.Pages)
+		selected := outputPath + ".selected"
+		if err := pdfapi.CollectFile(sourcePath, selected, selection, config); err != nil {
+			os.Remove(selected)
+			return 0, fmt.Errorf("%w: %v", ErrInvalidInsert, err)
+		}
+		defer os.Remove(selected)
+		sourcePath = selected
+	}
+	inserted, err := pdfapi.PageCountFile(sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source PDF: %w", err)
+	}
+
+	combined := outputPath + ".combined"
+	if err := pdfapi.MergeCreateFile([]string{pdfPath, sourcePath}, combined, false, config); err != nil {
+		os.Remove(combined)
+		return 0, fmt.Errorf("failed to insert pages: %w", err)
+	}
+	defer os.Remove(combined)
+
+	var order []string
+	if opts.After > 0 {
+		order = append(order, fmt.Sprintf("1-%d", opts.After))
+	}
+	order = append(order, fmt.Sprintf("%d-%d", pageCount+1, pageCount+inserted))
+	if opts.After < pageCount {
+		order = append(order, fmt.Sprintf("%d-%d", opts.After+1, pageCount))
+	}
+	tmpPath := outputPath + ".tmp"
+	if err := pdfapi.CollectFile(combined, tmpPath, order, config); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to insert pages: %w", err)
+	}
+	return inserted, os.Rename(tmpPath, outputPath)
+}
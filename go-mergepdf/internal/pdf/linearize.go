@@ -0,0 +1,666 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"sort"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// inheritableAttrs are the page attributes that may be inherited from the
+// page tree. Linearized files carry them on every page.
+var inheritableAttrs = []string{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// LinearizePDF writes a linearized ("fast web view") copy of the PDF at
+// pdfPath to outputPath, which may equal pdfPath. The first page and
+// everything it needs come first in the file, followed by the remaining
+// pages in order, with hint tables that tell viewers where each page starts,
+// so a viewer loading the file with range requests can show page one before
+// the rest has arrived.
+func LinearizePDF(pdfPath, outputPath string) error {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if ctx.Encrypt != nil {
+		return errors.New("encrypted PDFs cannot be linearized")
+	}
+	if ctx.PageCount == 0 {
+		return errors.New("the PDF has no pages")
+	}
+
+	l := &linearizer{ctx: ctx, streams: map[int][]byte{}}
+	if err := l.flattenPageTree(); err != nil {
+		return fmt.Errorf("failed to prepare page tree: %w", err)
+	}
+	if err := l.loadStreams(); err != nil {
+		return fmt.Errorf("failed to read streams: %w", err)
+	}
+	l.plan()
+
+	tmpPath := outputPath + ".linearize"
+	defer os.Remove(tmpPath)
+	if err := os.WriteFile(tmpPath, l.write(), 0644); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return os.Rename(tmpPath, outputPath)
+}
+
+// linearizer lays out the objects of a document in linearized order. Object
+// numbers in its sections are those of ctx; write renumbers them in file
+// order.
+type linearizer struct {
+	ctx     *model.Context
+	streams map[int][]byte // encoded stream data by object number
+
+	pages    []int   // page objects in page order
+	pagesNr  int     // the flattened page tree root
+	open     []int   // the catalog and what viewers need to open the document
+	first    []int   // the first page section
+	own      [][]int // objects used only by pages 2 to n, by page index
+	shared   []int   // objects used by several of pages 2 to n
+	rest     []int   // everything else
+	used     [][]int // objects each page references, by page index
+	renumber map[int]int
+}
+
+// flattenPageTree gives every page its inherited attributes and hangs all
+// pages directly off a new page tree root, as hint tables expect pages to be
+// self-contained.
+func (l *linearizer) flattenPageTree() error {
+	ctx := l.ctx
+	kids := types.Array{}
+	seen := map[int]bool{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		d, ir, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			return err
+		}
+		if d == nil || ir == nil {
+			return fmt.Errorf("page %d is missing", i)
+		}
+		if seen[ir.ObjectNumber.Value()] {
+			return fmt.Errorf("page %d repeats an earlier page object", i)
+		}
+		seen[ir.ObjectNumber.Value()] = true
+
+		for _, key := range inheritableAttrs {
+			if _, ok := d.Find(key); ok {
+				continue
+			}
+			for parent := d["Parent"]; parent != nil; {
+				node, err := ctx.DereferenceDict(parent)
+				if err != nil || node == nil {
+					break
+				}
+				if v, ok := node.Find(key); ok {
+					d.Insert(key, v)
+					break
+				}
+				parent = node["Parent"]
+			}
+		}
+
+		l.pages = append(l.pages, ir.ObjectNumber.Value())
+		kids = append(kids, *ir)
+	}
+
+	root := types.Dict{
+		"Type":  types.Name("Pages"),
+		"Kids":  kids,
+		"Count": types.Integer(ctx.PageCount),
+	}
+	ir, err := ctx.IndRefForNewObject(root)
+	if err != nil {
+		return err
+	}
+	for _, kid := range kids {
+		d, err := ctx.DereferenceDict(kid)
+		if err != nil {
+			return err
+		}
+		d.Update("Parent", *ir)
+	}
+	ctx.RootDict.Update("Pages", *ir)
+	l.pagesNr = ir.ObjectNumber.Value()
+	return nil
+}
+
+// loadStreams collects the encoded data of every stream and makes its
+// Length direct, so that length objects are not written separately.
+func (l *linearizer) loadStreams() error {
+	for nr, entry := range l.ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		if _, ok := entry.Object.(types.StreamDict); !ok {
+			continue
+		}
+		sd, _, err := l.ctx.DereferenceStreamDict(*types.NewIndirectRef(nr, *entry.Generation))
+		if err != nil {
+			return err
+		}
+		if sd.Raw == nil {
+			if err := sd.Encode(); err != nil {
+				return err
+			}
+		}
+		sd.Update("Length", types.Integer(len(sd.Raw)))
+		l.streams[nr] = sd.Raw
+		entry.Object = *sd
+	}
+	return nil
+}
+
+// object returns the object with number nr, or nil.
+func (l *linearizer) object(nr int) types.Object {
+	entry, ok := l.ctx.Find(nr)
+	if !ok || entry == nil || entry.Free {
+		return nil
+	}
+	return entry.Object
+}
+
+// refs returns the object numbers directly referenced by o, in order.
+func refs(o types.Object) []int {
+	var out []int
+	var walk func(o types.Object)
+	walk = func(o types.Object) {
+		switch o := o.(type) {
+		case types.IndirectRef:
+			out = append(out, o.ObjectNumber.Value())
+		case types.Dict:
+			for _, k := range sortedKeys(o) {
+				walk(o[k])
+			}
+		case types.StreamDict:
+			walk(o.Dict)
+		case types.Array:
+			for _, v := range o {
+				walk(v)
+			}
+		}
+	}
+	walk(o)
+	return out
+}
+
+// reach returns the objects reachable from the roots in depth-first order,
+// not descending into objects in stop or already in seen.
+func (l *linearizer) reach(roots []int, stop, seen map[int]bool) []int {
+	var out []int
+	var visit func(nr int)
+	visit = func(nr int) {
+		if seen[nr] || stop[nr] {
+			return
+		}
+		o := l.object(nr)
+		if o == nil {
+			return
+		}
+		seen[nr] = true
+		out = append(out, nr)
+		for _, r := range refs(o) {
+			visit(r)
+		}
+	}
+	for _, nr := range roots {
+		visit(nr)
+	}
+	return out
+}
+
+// plan assigns every reachable object to a section.
+func (l *linearizer) plan() {
+	ctx := l.ctx
+	rootNr := ctx.Root.ObjectNumber.Value()
+
+	// References to other pages and the page tree never pull those in.
+	isPage := map[int]bool{l.pagesNr: true}
+	for _, nr := range l.pages {
+		isPage[nr] = true
+	}
+	without := func(m map[int]bool, nr int) map[int]bool {
+		out := map[int]bool{}
+		for k := range m {
+			if k != nr {
+				out[k] = true
+			}
+		}
+		return out
+	}
+
+	placed := map[int]bool{rootNr: true}
+	l.open = []int{rootNr}
+	for _, key := range []string{"ViewerPreferences", "OpenAction"} {
+		l.open = append(l.open, l.reach(refs(ctx.RootDict[key]), isPage, placed)...)
+	}
+
+	l.used = make([][]int, len(l.pages))
+	for i, nr := range l.pages {
+		l.used[i] = l.reach([]int{nr}, without(isPage, nr), map[int]bool{})
+	}
+
+	l.first = nil
+	for _, nr := range l.used[0] {
+		if !placed[nr] {
+			placed[nr] = true
+			l.first = append(l.first, nr)
+		}
+	}
+
+	users := map[int]int{}
+	for _, objs := range l.used[1:] {
+		for _, nr := range objs {
+			users[nr]++
+		}
+	}
+	l.own = make([][]int, len(l.pages))
+	for i, objs := range l.used {
+		if i == 0 {
+			continue
+		}
+		for _, nr := range objs {
+			if !placed[nr] && users[nr] == 1 {
+				placed[nr] = true
+				l.own[i] = append(l.own[i], nr)
+			}
+		}
+	}
+	for _, objs := range l.used[1:] {
+		for _, nr := range objs {
+			if !placed[nr] {
+				placed[nr] = true
+				l.shared = append(l.shared, nr)
+			}
+		}
+	}
+
+	roots := refs(ctx.RootDict)
+	if ctx.Info != nil {
+		roots = append(roots, ctx.Info.ObjectNumber.Value())
+	}
+	l.rest = l.reach(roots, nil, placed)
+
+	// The first page cross-reference section holds the linearization
+	// dictionary, the document-level objects, the hint stream and the first
+	// page; the main section holds everything after it.
+	l.renumber = map[int]int{}
+	next := 1
+	for _, section := range [][]int{flatten(l.own), l.shared, l.rest} {
+		for _, nr := range section {
+			l.renumber[nr] = next
+			next++
+		}
+	}
+	next++ // linearization dictionary
+	for _, nr := range l.open {
+		l.renumber[nr] = next
+		next++
+	}
+	next++ // hint stream
+	for _, nr := range l.first {
+		l.renumber[nr] = next
+		next++
+	}
+}
+
+// write returns the linearized file.
+func (l *linearizer) write() []byte {
+	ctx := l.ctx
+	mainCount := len(flatten(l.own)) + len(l.shared) + len(l.rest) + 1 // including object 0
+	linNr := mainCount
+	hintNr := linNr + 1 + len(l.open)
+	size := hintNr + 1 + len(l.first)
+
+	bodies := map[int][]byte{}
+	body := func(nr int) []byte {
+		if b, ok := bodies[nr]; ok {
+			return b
+		}
+		b := l.body(nr)
+		bodies[nr] = b
+		return b
+	}
+
+	id := ctx.ID
+	if len(id) != 2 {
+		h := md5.New()
+		for _, section := range [][]int{l.open, l.first, flatten(l.own), l.shared, l.rest} {
+			for _, nr := range section {
+				h.Write(body(nr))
+			}
+		}
+		sum := types.HexLiteral(fmt.Sprintf("%x", h.Sum(nil)))
+		id = types.Array{sum, sum}
+	}
+	info := ""
+	if ctx.Info != nil {
+		if nr, ok := l.renumber[ctx.Info.ObjectNumber.Value()]; ok {
+			info = fmt.Sprintf(" /Info %d 0 R", nr)
+		}
+	}
+
+	version := ctx.HeaderVersion
+	if version == nil {
+		v := ctx.XRefTable.Version()
+		version = &v
+	}
+	header := fmt.Sprintf("%%PDF-%s\n%%\xe2\xe3\xcf\xd3\n", version.String())
+
+	// The linearization dictionary and the first trailer are padded to a
+	// fixed width so that offsets can be laid out before their values are
+	// known.
+	linDict := func(length, hintOffset, hintLength, end, mainXRef int) string {
+		d := fmt.Sprintf("<< /Linearized 1 /L %d /H [ %d %d ] /O %d /E %d /N %d /T %d >>",
+			length, hintOffset, hintLength, l.renumber[l.pages[0]], end, len(l.pages), mainXRef)
+		return fmt.Sprintf("%d 0 obj\n%-160s\nendobj\n", linNr, d)
+	}
+	trailer := func(prev int) string {
+		return fmt.Sprintf("<< /Size %d /Root %d 0 R%s /ID %s /Prev %d >>",
+			size, l.renumber[ctx.Root.ObjectNumber.Value()], info, id.PDFString(), prev)
+	}
+	trailerWidth := len(trailer(0)) + 20
+	firstSection := func(offsets map[int]int, prev int) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "xref\n%d %d\n", linNr, size-linNr)
+		for nr := linNr; nr < size; nr++ {
+			fmt.Fprintf(&b, "%010d 00000 n \n", offsets[nr])
+		}
+		fmt.Fprintf(&b, "trailer\n%-*s\nstartxref\n0\n%%%%EOF\n", trailerWidth, trailer(prev))
+		return b.String()
+	}
+
+	// Offsets are laid out as if there were no hint stream, which is how
+	// hint tables record them; objects after it are shifted afterwards.
+	firstXRef := len(header) + len(linDict(0, 0, 0, 0, 0))
+	prefix := firstXRef + len(firstSection(nil, 0))
+	offsets := map[int]int{}
+	pos := prefix
+	place := func(section []int) {
+		for _, nr := range section {
+			offsets[nr] = pos
+			pos += len(body(nr))
+		}
+	}
+	place(l.open)
+	hintOffset := pos
+	place(l.first)
+	firstEnd := pos
+	pageStarts := []int{offsets[l.first[0]]}
+	for _, objs := range l.own[1:] {
+		pageStarts = append(pageStarts, pos)
+		place(objs)
+	}
+	sharedStart := pos
+	place(l.shared)
+	place(l.rest)
+	mainXRef := pos
+
+	hint := l.hintStream(hintNr, offsets, pageStarts, sharedStart, firstEnd)
+	shift := len(hint)
+	for nr, off := range offsets {
+		if off >= hintOffset {
+			offsets[nr] = off + shift
+		}
+	}
+	firstEnd += shift
+	mainXRef += shift
+
+	var main strings.Builder
+	fmt.Fprintf(&main, "xref\n0 %d\n0000000000 65535 f \n", mainCount)
+	newOffsets := map[int]int{linNr: len(header), hintNr: hintOffset}
+	for old, nr := range l.renumber {
+		newOffsets[nr] = offsets[old]
+	}
+	for nr := 1; nr < mainCount; nr++ {
+		fmt.Fprintf(&main, "%010d 00000 n \n", newOffsets[nr])
+	}
+	fmt.Fprintf(&main, "trailer\n<< /Size %d >>\nstartxref\n%d\n%%%%EOF\n", mainCount, firstXRef)
+
+	length := mainXRef + main.Len()
+	t := mainXRef + len(fmt.Sprintf("xref\n0 %d", mainCount))
+
+	var out bytes.Buffer
+	out.WriteString(header)
+	out.WriteString(linDict(length, hintOffset, len(hint), firstEnd, t))
+	out.WriteString(firstSection(newOffsets, mainXRef))
+
+	for _, nr := range l.open {
+		out.Write(body(nr))
+	}
+	out.Write(hint)
+	for _, section := range [][]int{l.first, flatten(l.own), l.shared, l.rest} {
+		for _, nr := range section {
+			out.Write(body(nr))
+		}
+	}
+	out.WriteString(main.String())
+	return out.Bytes()
+}
+
+// body returns the serialized object nr under its new number.
+func (l *linearizer) body(nr int) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d 0 obj\n", l.renumber[nr])
+	switch o := l.object(nr).(type) {
+	case types.StreamDict:
+		b.WriteString(l.rewrite(o.Dict).PDFString())
+		b.WriteString("\nstream\n")
+		b.Write(l.streams[nr])
+		b.WriteString("\nendstream")
+	default:
+		b.WriteString(l.rewrite(o).PDFString())
+	}
+	b.WriteString("\nendobj\n")
+	return b.Bytes()
+}
+
+// rewrite returns a copy of o with references renumbered. References to
+// objects that are not written become null.
+func (l *linearizer) rewrite(o types.Object) types.Object {
+	switch o := o.(type) {
+	case types.IndirectRef:
+		nr, ok := l.renumber[o.ObjectNumber.Value()]
+		if !ok {
+			return nil
+		}
+		return *types.NewIndirectRef(nr, 0)
+	case types.Dict:
+		d := types.Dict{}
+		for k, v := range o {
+			d[k] = l.rewrite(v)
+		}
+		return d
+	case types.Array:
+		a := make(types.Array, len(o))
+		for i, v := range o {
+			a[i] = l.rewrite(v)
+		}
+		return a
+	}
+	return o
+}
+
+// hintStream returns the primary hint stream object with the page offset
+// and shared object hint tables (ISO 32000-1, annex F.4). Offsets are those
+// computed without the hint stream.
+func (l *linearizer) hintStream(nr int, offsets map[int]int, pageStarts []int, sharedStart, firstEnd int) []byte {
+	n := len(l.pages)
+	pageEnds := append(append([]int{}, pageStarts[1:]...), sharedStart)
+	pageEnds[0] = firstEnd
+	counts := make([]int, n)
+	lengths := make([]int, n)
+	for i := range l.pages {
+		lengths[i] = pageEnds[i] - pageStarts[i]
+		if i == 0 {
+			counts[i] = len(l.first)
+		} else {
+			counts[i] = len(l.own[i])
+		}
+	}
+
+	// Shared object entries: every object of the first page, then the
+	// shared objects section, one object per group.
+	groups := append(append([]int{}, l.first...), l.shared...)
+	index := map[int]int{}
+	groupLengths := make([]int, len(groups))
+	for i, g := range groups {
+		index[g] = i
+		groupLengths[i] = len(l.body(g))
+	}
+	sharedRefs := make([][]int, n)
+	for i := 1; i < n; i++ {
+		for _, nr := range l.used[i] {
+			if idx, ok := index[nr]; ok {
+				sharedRefs[i] = append(sharedRefs[i], idx)
+			}
+		}
+		sort.Ints(sharedRefs[i])
+	}
+
+	minCount, countBits := spread(counts)
+	minLength, lengthBits := spread(lengths)
+	maxRefs := 0
+	for _, r := range sharedRefs {
+		maxRefs = max(maxRefs, len(r))
+	}
+	idBits := bitsFor(len(groups) - 1)
+
+	var w bitWriter
+	w.write(uint64(minCount), 32)
+	w.write(uint64(offsets[l.pages[0]]), 32)
+	w.write(uint64(countBits), 16)
+	w.write(uint64(minLength), 32)
+	w.write(uint64(lengthBits), 16)
+	w.write(0, 32) // least content stream offset; readers ignore content stream items
+	w.write(0, 16)
+	w.write(uint64(minLength), 32)
+	w.write(uint64(lengthBits), 16)
+	w.write(uint64(bitsFor(maxRefs)), 16)
+	w.write(uint64(idBits), 16)
+	w.write(0, 16) // numerator bits
+	w.write(1, 16) // denominator
+	for _, c := range counts {
+		w.write(uint64(c-minCount), countBits)
+	}
+	w.flush()
+	for _, length := range lengths {
+		w.write(uint64(length-minLength), lengthBits)
+	}
+	w.flush()
+	for _, r := range sharedRefs {
+		w.write(uint64(len(r)), bitsFor(maxRefs))
+	}
+	w.flush()
+	for _, r := range sharedRefs {
+		for _, idx := range r {
+			w.write(uint64(idx), idBits)
+		}
+	}
+	w.flush()
+	// Numerators and content stream offsets take no bits. As in other
+	// writers, the content stream length is the page length.
+	for _, length := range lengths {
+		w.write(uint64(length-minLength), lengthBits)
+	}
+	w.flush()
+	sharedOffset := w.buf.Len()
+
+	firstShared, sharedLocation := 0, 0
+	if len(l.shared) > 0 {
+		firstShared = l.renumber[l.shared[0]]
+		sharedLocation = offsets[l.shared[0]]
+	}
+	minGroup, groupBits := spread(groupLengths)
+	w.write(uint64(firstShared), 32)
+	w.write(uint64(sharedLocation), 32)
+	w.write(uint64(len(l.first)), 32)
+	w.write(uint64(len(groups)), 32)
+	w.write(0, 16) // one object per group
+	w.write(uint64(minGroup), 32)
+	w.write(uint64(groupBits), 16)
+	for _, length := range groupLengths {
+		w.write(uint64(length-minGroup), groupBits)
+	}
+	w.flush()
+	for range groupLengths {
+		w.write(0, 1) // no signature
+	}
+	w.flush()
+
+	var data bytes.Buffer
+	zw := zlib.NewWriter(&data)
+	zw.Write(w.buf.Bytes())
+	zw.Close()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d 0 obj\n<< /Filter /FlateDecode /Length %d /S %d >>\nstream\n", nr, data.Len(), sharedOffset)
+	b.Write(data.Bytes())
+	b.WriteString("\nendstream\nendobj\n")
+	return b.Bytes()
+}
+
+// bitWriter packs unsigned values most significant bit first.
+type bitWriter struct {
+	buf   bytes.Buffer
+	cur   byte
+	nbits int
+}
+
+func (w *bitWriter) write(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i)&1)
+		w.nbits++
+		if w.nbits == 8 {
+			w.buf.WriteByte(w.cur)
+			w.cur, w.nbits = 0, 0
+		}
+	}
+}
+
+// flush pads the current byte with zero bits.
+func (w *bitWriter) flush() {
+	if w.nbits > 0 {
+		w.write(0, 8-w.nbits)
+	}
+}
+
+// bitsFor returns the number of bits needed to represent v.
+func bitsFor(v int) int {
+	if v <= 0 {
+		return 0
+	}
+	return bits.Len(uint(v))
+}
+
+// spread returns the least value and the bits needed for the differences
+// from it.
+func spread(values []int) (least, nbits int) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	least, most := values[0], values[0]
+	for _, v := range values {
+		least, most = min(least, v), max(most, v)
+	}
+	return least, bitsFor(most - least)
+}
+
+// flatten concatenates the sections.
+func flatten(sections [][]int) []int {
+	var out []int
+	for _, s := range sections {
+		out = append(out, s...)
+	}
+	return out
+}
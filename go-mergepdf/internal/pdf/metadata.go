@@ -0,0 +1,258 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Metadata is the document information of a PDF as reported back to clients.
+// Dates are RFC 3339 when they can be parsed, otherwise the raw PDF date string.
+type Metadata struct {
+	Title        string `json:"title,omitempty"`
+	Author       string `json:"author,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	Keywords     string `json:"keywords,omitempty"`
+	Creator      string `json:"creator,omitempty"`
+	Producer     string `json:"producer,omitempty"`
+	CreationDate string `json:"creationDate,omitempty"`
+	ModDate      string `json:"modDate,omitempty"`
+	HasXMP       bool   `json:"hasXmp"`
+}
+
+// MetadataOptions describes changes to the document information of a PDF.
+// Empty fields are left untouched. Strip removes the existing Info dictionary
+// and all XMP metadata streams before the remaining fields are applied.
+type MetadataOptions struct {
+	Title        string     `json:"title,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	Subject      string     `json:"subject,omitempty"`
+	Keywords     string     `json:"keywords,omitempty"`
+	Creator      string     `json:"creator,omitempty"`
+	Producer     string     `json:"producer,omitempty"`
+	CreationDate *time.Time `json:"creationDate,omitempty"`
+	ModDate      *time.Time `json:"modDate,omitempty"`
+	Strip        bool       `json:"strip,omitempty"`
+}
+
+// IsZero reports whether opts would leave a document unchanged.
+func (opts MetadataOptions) IsZero() bool {
+	return opts == MetadataOptions{}
+}
+
+// fields returns the Info dictionary entries pdfcpu keeps across a full write.
+func (opts MetadataOptions) fields() map[string]string {
+	entries := map[string]string{
+		"Title":    opts.Title,
+		"Author":   opts.Author,
+		"Subject":  opts.Subject,
+		"Keywords": opts.Keywords,
+		"Creator":  opts.Creator,
+	}
+	for k, v := range entries {
+		if v == "" {
+			delete(entries, k)
+		}
+	}
+	return entries
+}
+
+// stampedFields returns the Info dictionary entries pdfcpu overwrites on every
+// full write and which therefore have to go into an incremental update.
+func (opts MetadataOptions) stampedFields() map[string]string {
+	entries := map[string]string{}
+	if opts.Producer != "" {
+		entries["Producer"] = opts.Producer
+	}
+	if opts.CreationDate != nil {
+		entries["CreationDate"] = types.DateString(*opts.CreationDate)
+	}
+	if opts.ModDate != nil {
+		entries["ModDate"] = types.DateString(*opts.ModDate)
+	}
+	return entries
+}
+
+// SetMetadata applies opts to the PDF at pdfPath and writes the result to
+// outputPath, which may equal pdfPath. Setting any field also drops the
+// document XMP packet, since viewers prefer XMP over the Info dictionary and
+// would otherwise keep showing stale values.
+func SetMetadata(pdfPath, outputPath string, opts MetadataOptions) error {
+	if opts.IsZero() && pdfPath == outputPath {
+		return nil
+	}
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	fields := opts.fields()
+	stamped := opts.stampedFields()
+
+	if opts.Strip {
+		if err := stripMetadata(ctx); err != nil {
+			return fmt.Errorf("failed to strip metadata: %w", err)
+		}
+	} else if len(fields) > 0 || len(stamped) > 0 {
+		ctx.RootDict.Delete("Metadata")
+	}
+
+	if len(fields) > 0 {
+		if err := setInfoEntries(ctx, fields); err != nil {
+			return fmt.Errorf("failed to set metadata: %w", err)
+		}
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	if !opts.Strip && len(stamped) == 0 {
+		return nil
+	}
+
+	// pdfcpu stamps its own Producer and dates on every full write. Stripped
+	// documents must not carry those either, so the final Info dictionary is
+	// written as an incremental update on top.
+	if err := writeInfoIncrement(outputPath, fields, stamped, opts.Strip); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// ReadMetadata returns the document information of the PDF at pdfPath.
+func ReadMetadata(pdfPath string) (*Metadata, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	md := &Metadata{}
+	if _, ok := ctx.RootDict.Find("Metadata"); ok {
+		md.HasXMP = true
+	}
+	if ctx.Info == nil {
+		return md, nil
+	}
+
+	d, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || d == nil {
+		return md, err
+	}
+
+	text := func(key string) string {
+		o, ok := d.Find(key)
+		if !ok {
+			return ""
+		}
+		s, err := ctx.DereferenceText(o)
+		if err != nil {
+			return ""
+		}
+		return s
+	}
+	date := func(key string) string {
+		s := text(key)
+		if t, ok := types.DateTime(s, true); ok {
+			return t.Format(time.RFC3339)
+		}
+		return s
+	}
+
+	md.Title = text("Title")
+	md.Author = text("Author")
+	md.Subject = text("Subject")
+	md.Keywords = text("Keywords")
+	md.Creator = text("Creator")
+	md.Producer = text("Producer")
+	md.CreationDate = date("CreationDate")
+	md.ModDate = date("ModDate")
+	return md, nil
+}
+
+// stripMetadata drops the Info dictionary and every XMP stream attached to the
+// catalog or to individual pages.
+func stripMetadata(ctx *model.Context) error {
+	ctx.Info = nil
+	ctx.RootDict.Delete("Metadata")
+	for i := 1; i <= ctx.PageCount; i++ {
+		d, _, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			d.Delete("Metadata")
+		}
+	}
+	return nil
+}
+
+// setInfoEntries writes entries into the Info dictionary of ctx, creating it if needed.
+func setInfoEntries(ctx *model.Context, entries map[string]string) error {
+	var d types.Dict
+	if ctx.Info != nil {
+		var err error
+		if d, err = ctx.DereferenceDict(*ctx.Info); err != nil {
+			return err
+		}
+	}
+	if d == nil {
+		d = types.NewDict()
+		ir, err := ctx.IndRefForNewObject(d)
+		if err != nil {
+			return err
+		}
+		ctx.Info = ir
+	}
+
+	for k, v := range entries {
+		s, err := types.EscapedUTF16String(v)
+		if err != nil {
+			return err
+		}
+		d[k] = types.StringLiteral(*s)
+	}
+	return nil
+}
+
+// writeInfoIncrement appends an incremental update to pdfPath whose Info
+// dictionary holds fields and stamped. With replace set the dictionary holds
+// nothing else; otherwise the existing entries are kept.
+func writeInfoIncrement(pdfPath string, fields, stamped map[string]string, replace bool) error {
+	f, err := os.OpenFile(pdfPath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	config := model.NewDefaultConfiguration()
+	ctx, err := pdfapi.ReadAndValidate(f, config)
+	if err != nil {
+		return err
+	}
+
+	if replace {
+		ctx.Info = nil
+	}
+
+	entries := map[string]string{}
+	for k, v := range fields {
+		entries[k] = v
+	}
+	for k, v := range stamped {
+		entries[k] = v
+	}
+	if err := setInfoEntries(ctx, entries); err != nil {
+		return err
+	}
+
+	ctx.Write.Increment = true
+	ctx.Write.Offset = ctx.Read.FileSize
+	ctx.Write.IncrementWithObjNr(ctx.Info.ObjectNumber.Value())
+	return pdfapi.WriteIncr(ctx, f, config)
+}
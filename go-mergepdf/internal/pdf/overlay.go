@@ -0,0 +1,115 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidOverlay is returned for overlay options that do not fit the template, such as a page it does not have,
+// and for a template that is not a readable PDF.
+var ErrInvalidOverlay = errors.New("invalid overlay options")
+
+// OverlayOptions selects which template page is stamped onto which document
+// pages. Page numbers are 1-based; 0 inherits (FirstPage defaults to 1,
+// OtherPages to FirstPage and LastPage to OtherPages) and -1 leaves those
+// pages unstamped. A single-page document uses FirstPage.
+type OverlayOptions struct {
+	FirstPage  int     `json:"firstPage,omitempty"`
+	OtherPages int     `json:"otherPages,omitempty"`
+	LastPage   int     `json:"lastPage,omitempty"`
+	Underlay   bool    `json:"underlay,omitempty"` // Place the template behind the page content
+	Opacity    float64 `json:"opacity,omitempty"`  // 0 < opacity <= 1, defaults to 1
+}
+
+// resolve fills in inherited template pages.
+func (opts OverlayOptions) resolve() OverlayOptions {
+	if opts.FirstPage == 0 {
+		opts.FirstPage = 1
+	}
+	if opts.OtherPages == 0 {
+		opts.OtherPages = opts.FirstPage
+	}
+	if opts.LastPage == 0 {
+		opts.LastPage = opts.OtherPages
+	}
+	if opts.Opacity == 0 {
+		opts.Opacity = 1
+	}
+	return opts
+}
+
+// OverlayPDF stamps pages of templatePath, such as a letterhead, over or under
+// the pages of pdfPath and writes the result to outputPath. Each template page
+// is scaled to fit the page it is stamped on.
+func OverlayPDF(pdfPath, templatePath, outputPath string, opts OverlayOptions) error {
+	opts = opts.resolve()
+	if opts.Opacity < 0 || opts.Opacity > 1 {
+		return fmt.Errorf("%w: opacity must be between 0 and 1", ErrInvalidOverlay)
+	}
+
+	templateCount, err := pdfapi.PageCountFile(templatePath)
+	if err != nil {
+		// Templates come from assets, which may be any kind of file.
+		return fmt.Errorf("%w: template is not a readable PDF: %v", ErrInvalidOverlay, err)
+	}
+	pageCount, err := pdfapi.PageCountFile(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	// pdfcpu loads a PDF watermark per Watermark value, so each template
+	// page is parsed once and shared by all pages it applies to.
+	watermarks := map[int]*model.Watermark{}
+	watermark := func(templatePage int) (*model.Watermark, error) {
+		if wm, ok := watermarks[templatePage]; ok {
+			return wm, nil
+		}
+		if templatePage < 1 || templatePage > templateCount {
+			return nil, fmt.Errorf("%w: template page %d, template has %d pages", ErrInvalidOverlay, templatePage, templateCount)
+		}
+		desc := fmt.Sprintf("scale:1 rel, pos:c, rot:0, op:%.2f", opts.Opacity)
+		wm, err := pdfcpu.ParsePDFWatermarkDetails(fmt.Sprintf("%s:%d", templatePath, templatePage), desc, !opts.Underlay, types.POINTS)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to parse template: %v", ErrInvalidOverlay, err)
+		}
+		watermarks[templatePage] = wm
+		return wm, nil
+	}
+
+	m := map[int]*model.Watermark{}
+	for i := 1; i <= pageCount; i++ {
+		templatePage := opts.OtherPages
+		switch {
+		case i == 1:
+			templatePage = opts.FirstPage
+		case i == pageCount:
+			templatePage = opts.LastPage
+		}
+		if templatePage == -1 {
+			continue
+		}
+		wm, err := watermark(templatePage)
+		if err != nil {
+			return err
+		}
+		m[i] = wm
+	}
+
+	if len(m) == 0 {
+		if outputPath == pdfPath {
+			return nil
+		}
+		return copyFile(pdfPath, outputPath)
+	}
+
+	config := model.NewDefaultConfiguration()
+	if err := pdfapi.AddWatermarksMapFile(pdfPath, outputPath, m, config); err != nil {
+		return fmt.Errorf("failed to apply template: %w", err)
+	}
+	return nil
+}
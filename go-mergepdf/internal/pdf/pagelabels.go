@@ -0,0 +1,337 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidPageLabels is returned for page label ranges that cannot be applied.
+var ErrInvalidPageLabels = errors.New("invalid page labels")
+
+// pageLabelStyles maps label styles to their PDF numbering style names.
+// "roman" and "letters" are upper case, as in "Exhibit A".
+var pageLabelStyles = map[string]string{
+	"decimal":       "D",
+	"upper-roman":   "R",
+	"roman":         "R",
+	"lower-roman":   "r",
+	"upper-letters": "A",
+	"letters":       "A",
+	"lower-letters": "a",
+	"none":          "",
+}
+
+// PageLabelRange labels the pages from Page up to the next range. Labels
+// are Prefix followed by the page number in Style, counting from Start:
+//   - decimal (default): 1, 2, 3
+//   - upper-roman or roman, lower-roman: I, II, III or i, ii, iii
+//   - upper-letters or letters, lower-letters: A to Z, AA to ZZ and so on
+//   - none: the prefix alone
+type PageLabelRange struct {
+	Page   int    `json:"page"` // First page of the range, 1-based
+	Style  string `json:"style,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Start  int    `json:"start,omitempty"` // Number of the first page, 1 if zero
+}
+
+// PageLabels are the page label ranges of a document, ordered by page.
+type PageLabels []PageLabelRange
+
+// Validate reports whether the ranges can be applied to a document.
+func (labels PageLabels) Validate() error {
+	pages := map[int]bool{}
+	for _, r := range labels {
+		if r.Page < 1 {
+			return fmt.Errorf("%w: page must be 1 or more", ErrInvalidPageLabels)
+		}
+		if pages[r.Page] {
+			return fmt.Errorf("%w: several ranges start at page %d", ErrInvalidPageLabels, r.Page)
+		}
+		pages[r.Page] = true
+		if _, ok := pageLabelStyles[r.Style]; !ok && r.Style != "" {
+			return fmt.Errorf("%w: unknown style %q", ErrInvalidPageLabels, r.Style)
+		}
+		if r.Start < 0 {
+			return fmt.Errorf("%w: start must be 1 or more", ErrInvalidPageLabels)
+		}
+	}
+	return nil
+}
+
+// With returns labels with the ranges of other added, replacing ranges that
+// start on the same page.
+func (labels PageLabels) With(other PageLabels) PageLabels {
+	byPage := map[int]PageLabelRange{}
+	for _, r := range labels {
+		byPage[r.Page] = r
+	}
+	for _, r := range other {
+		byPage[r.Page] = r
+	}
+	out := PageLabels{}
+	for _, r := range byPage {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Page < out[j].Page })
+	return out
+}
+
+// Slice returns the labels of pages first to last, 1-based, for a document
+// made of just those pages.
+func (labels PageLabels) Slice(first, last int) PageLabels {
+	pages := make([]int, 0, last-first+1)
+	for p := first; p <= last; p++ {
+		pages = append(pages, p)
+	}
+	return labels.Keep(pages)
+}
+
+// Keep returns the labels of the given pages, in ascending order, for a
+// document made of just those pages. Each page keeps its label.
+func (labels PageLabels) Keep(pages []int) PageLabels {
+	out := PageLabels{}
+	if len(labels) == 0 {
+		return out
+	}
+	for i, p := range pages {
+		r := labels.rangeAt(p)
+		if i > 0 && pages[i-1] == p-1 && r.Page != p {
+			continue // The previous range goes on.
+		}
+		r.Start = max(r.Start, 1) + p - r.Page
+		if r.Start == 1 {
+			r.Start = 0
+		}
+		r.Page = i + 1
+		out = append(out, r)
+	}
+	return out
+}
+
+// rangeAt returns the range page belongs to. Pages before the first range
+// are numbered from 1, as viewers do.
+func (labels PageLabels) rangeAt(page int) PageLabelRange {
+	r := PageLabelRange{Page: 1, Style: "decimal"}
+	for _, l := range labels {
+		if l.Page <= page {
+			r = l
+		}
+	}
+	return r
+}
+
+// Label returns the label of page, 1-based, as viewers show it, or the page
+// number if there are no labels.
+func (labels PageLabels) Label(page int) string {
+	r := labels.rangeAt(page)
+	n := max(r.Start, 1) + page - r.Page
+	switch pageLabelStyles[r.Style] {
+	case "R":
+		return r.Prefix + romanNumeral(n)
+	case "r":
+		return r.Prefix + strings.ToLower(romanNumeral(n))
+	case "A":
+		return r.Prefix + letterNumeral(n)
+	case "a":
+		return r.Prefix + strings.ToLower(letterNumeral(n))
+	}
+	if r.Style == "none" {
+		return r.Prefix
+	}
+	return r.Prefix + strconv.Itoa(n)
+}
+
+// romanNumeral returns n in upper case roman numerals.
+func romanNumeral(n int) string {
+	var b strings.Builder
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	for i, v := range values {
+		for ; n >= v; n -= v {
+			b.WriteString(symbols[i])
+		}
+	}
+	return b.String()
+}
+
+// letterNumeral returns n as PDF letter numbering: A to Z, then AA to ZZ,
+// AAA to ZZZ and so on.
+func letterNumeral(n int) string {
+	return strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+}
+
+// ReadPageLabels returns the page label ranges of the PDF at pdfPath, or an
+// empty list if it has none.
+func ReadPageLabels(pdfPath string) (PageLabels, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return pageLabelsOf(ctx)
+}
+
+// MergedPageLabels returns the page labels that files merged in order would
+// have if each kept its own labels. Files without labels are numbered from
+// 1, as viewers show them on their own. The result is empty if no file has
+// labels.
+func MergedPageLabels(files []string) (PageLabels, error) {
+	merged := PageLabels{}
+	labelled := false
+	offset := 0
+	for _, file := range files {
+		ctx, err := pdfapi.ReadContextFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PDF: %w", err)
+		}
+		labels, err := pageLabelsOf(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page labels: %w", err)
+		}
+		if len(labels) == 0 {
+			labels = PageLabels{{Page: 1, Style: "decimal"}}
+		} else {
+			labelled = true
+		}
+		for _, r := range labels {
+			if r.Page > ctx.PageCount {
+				continue
+			}
+			r.Page += offset
+			merged = append(merged, r)
+		}
+		offset += ctx.PageCount
+	}
+	if !labelled {
+		return PageLabels{}, nil
+	}
+	return merged, nil
+}
+
+// SetPageLabels replaces the page labels of the PDF at pdfPath with labels
+// and writes the result to outputPath, which may equal pdfPath. Pages before
+// the first range are numbered from 1; empty labels remove all labels.
+func SetPageLabels(pdfPath, outputPath string, labels PageLabels) error {
+	if err := labels.Validate(); err != nil {
+		return err
+	}
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	if err := setPageLabels(ctx, labels); err != nil {
+		return err
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// setPageLabels replaces the page labels number tree of ctx.
+func setPageLabels(ctx *model.Context, labels PageLabels) error {
+	if len(labels) == 0 {
+		ctx.RootDict.Delete("PageLabels")
+		return nil
+	}
+	labels = PageLabels{{Page: 1, Style: "decimal"}}.With(labels)
+	nums := types.Array{}
+	for _, r := range labels {
+		if r.Page > ctx.PageCount {
+			return fmt.Errorf("%w: page %d is beyond the last page %d", ErrInvalidPageLabels, r.Page, ctx.PageCount)
+		}
+		d := types.Dict{"Type": types.Name("PageLabel")}
+		if s := pageLabelStyles[r.Style]; s != "" || r.Style == "" {
+			if s == "" {
+				s = "D"
+			}
+			d["S"] = types.Name(s)
+		}
+		if r.Prefix != "" {
+			s, err := types.EscapedUTF16String(r.Prefix)
+			if err != nil {
+				return err
+			}
+			d["P"] = types.StringLiteral(*s)
+		}
+		if r.Start > 1 {
+			d["St"] = types.Integer(r.Start)
+		}
+		nums = append(nums, types.Integer(r.Page-1), d)
+	}
+	ir, err := ctx.IndRefForNewObject(types.Dict{"Nums": nums})
+	if err != nil {
+		return err
+	}
+	ctx.RootDict.Update("PageLabels", *ir)
+	return nil
+}
+
+// pageLabelsOf reads the page labels number tree of ctx.
+func pageLabelsOf(ctx *model.Context) (PageLabels, error) {
+	labels := PageLabels{}
+	styles := map[string]string{}
+	for style, s := range pageLabelStyles {
+		if style != "roman" && style != "letters" {
+			styles[s] = style
+		}
+	}
+
+	var walk func(o types.Object, depth int) error
+	walk = func(o types.Object, depth int) error {
+		node, err := ctx.DereferenceDict(o)
+		if err != nil || node == nil || depth > maxFormDepth {
+			return err
+		}
+		if kids, err := ctx.DereferenceArray(node["Kids"]); err == nil {
+			for _, kid := range kids {
+				if err := walk(kid, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		nums, err := ctx.DereferenceArray(node["Nums"])
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(nums); i += 2 {
+			index, ok := nums[i].(types.Integer)
+			if !ok {
+				continue
+			}
+			d, err := ctx.DereferenceDict(nums[i+1])
+			if err != nil || d == nil {
+				continue
+			}
+			r := PageLabelRange{Page: int(index) + 1, Style: "none"}
+			if s := d.NameEntry("S"); s != nil {
+				if style, ok := styles[*s]; ok {
+					r.Style = style
+				}
+			}
+			if p, ok := d.Find("P"); ok {
+				if r.Prefix, err = ctx.DereferenceText(p); err != nil {
+					return err
+				}
+			}
+			if st := d.IntEntry("St"); st != nil && *st > 1 {
+				r.Start = *st
+			}
+			labels = append(labels, r)
+		}
+		return nil
+	}
+	if err := walk(ctx.RootDict["PageLabels"], 0); err != nil {
+		return nil, err
+	}
+	return PageLabels{}.With(labels), nil
+}
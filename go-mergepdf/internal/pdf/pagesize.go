@@ -0,0 +1,208 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidPageSize is returned for page size options that cannot be applied.
+var ErrInvalidPageSize = errors.New("invalid page size options")
+
+// PageSizeOptions describes the uniform page size applied by NormalizePageSizes.
+// Size is a paper name such as A4, Letter or Legal, or "custom" with Width and
+// Height in points. Mode is one of:
+//   - fit: scale each page to fit inside the margins (default)
+//   - fill: scale each page to cover the area inside the margins, cropping overflow
+//   - center: keep the original scale and center the page
+//
+// Aspect ratios are always preserved, and landscape pages get a landscape target.
+type PageSizeOptions struct {
+	Size   string  `json:"size"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	Mode   string  `json:"mode,omitempty"`
+	Margin float64 `json:"margin,omitempty"` // Points on every side
+}
+
+// dimensions returns the portrait target size in points.
+func (opts PageSizeOptions) dimensions() (float64, float64, error) {
+	if strings.EqualFold(opts.Size, "custom") {
+		if opts.Width <= 0 || opts.Height <= 0 {
+			return 0, 0, fmt.Errorf("%w: custom size needs a positive width and height", ErrInvalidPageSize)
+		}
+		return math.Min(opts.Width, opts.Height), math.Max(opts.Width, opts.Height), nil
+	}
+	for name, dim := range types.PaperSize {
+		if strings.EqualFold(name, opts.Size) {
+			return math.Min(dim.Width, dim.Height), math.Max(dim.Width, dim.Height), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: unknown size %q", ErrInvalidPageSize, opts.Size)
+}
+
+// Validate reports whether opts describe a usable page size.
+func (opts PageSizeOptions) Validate() error {
+	w, _, err := opts.dimensions()
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(opts.Mode) {
+	case "", "fit", "fill", "center":
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidPageSize, opts.Mode)
+	}
+	if opts.Margin < 0 || 2*opts.Margin >= w {
+		return fmt.Errorf("%w: margin must be non-negative and less than half the page width", ErrInvalidPageSize)
+	}
+	return nil
+}
+
+// NormalizePageSizes scales every page of the PDF at pdfPath onto a page of
+// the size given by opts and writes the result to outputPath, which may equal
+// pdfPath. Page rotation is kept; annotations move with the page content.
+func NormalizePageSizes(pdfPath, outputPath string, opts PageSizeOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	pw, ph, _ := opts.dimensions()
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	moved := map[int]bool{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		if err := normalizePage(ctx, i, pw, ph, opts, moved); err != nil {
+			return fmt.Errorf("failed to resize page %d: %w", i, err)
+		}
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// normalizePage places the visible area of a page onto a pw x ph page,
+// swapping the target orientation for landscape pages. moved records the
+// annotations already transformed, since they may be shared between pages.
+func normalizePage(ctx *model.Context, pageNr int, pw, ph float64, opts PageSizeOptions, moved map[int]bool) error {
+	pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil {
+		return err
+	}
+
+	box := inh.MediaBox
+	if box == nil {
+		box = types.RectForFormat("A4")
+	}
+	if inh.CropBox != nil {
+		box = intersectRect(box, inh.CropBox)
+	}
+	w, h := box.Width(), box.Height()
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+
+	// Compare in unrotated page space: /Rotate turns page and target alike.
+	tw, th := pw, ph
+	if w > h {
+		tw, th = ph, pw
+	}
+	aw, ah := tw-2*opts.Margin, th-2*opts.Margin
+
+	var s float64
+	switch strings.ToLower(opts.Mode) {
+	case "fill":
+		s = math.Max(aw/w, ah/h)
+	case "center":
+		s = 1
+	default:
+		s = math.Min(aw/w, ah/h)
+	}
+	m := matrix{s, 0, 0, s, opts.Margin + (aw-w*s)/2 - box.LL.X*s, opts.Margin + (ah-h*s)/2 - box.LL.Y*s}
+
+	// Clip to the visible source area within the margins.
+	clip := intersectRect(transformRect(box, m), types.NewRectangle(opts.Margin, opts.Margin, tw-opts.Margin, th-opts.Margin))
+
+	content, err := pageContent(ctx, pageDict)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "q %.4f %.4f %.4f %.4f re W n %.6f 0 0 %.6f %.4f %.4f cm\n",
+		clip.LL.X, clip.LL.Y, clip.Width(), clip.Height(), m[0], m[3], m[4], m[5])
+	b.Write(content)
+	b.WriteString("\nQ\n")
+	if err := setPageContent(ctx, pageDict, b.Bytes()); err != nil {
+		return err
+	}
+
+	pageDict["MediaBox"] = types.NewRectangle(0, 0, tw, th).Array()
+	for _, key := range []string{"CropBox", "BleedBox", "TrimBox", "ArtBox"} {
+		pageDict.Delete(key)
+	}
+
+	return moveAnnotations(ctx, pageDict, m, moved)
+}
+
+// moveAnnotations applies m to the rectangles and quad points of a page's annotations.
+func moveAnnotations(ctx *model.Context, pageDict types.Dict, m matrix, moved map[int]bool) error {
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil {
+		return err
+	}
+	for _, o := range annots {
+		if ir, ok := o.(types.IndirectRef); ok {
+			if moved[ir.ObjectNumber.Value()] {
+				continue
+			}
+			moved[ir.ObjectNumber.Value()] = true
+		}
+		d, err := ctx.DereferenceDict(o)
+		if err != nil || d == nil {
+			return err
+		}
+		if r, err := ctx.RectForArray(d.ArrayEntry("Rect")); err == nil && r != nil {
+			d["Rect"] = transformRect(r, m).Array()
+		}
+		if qp := d.ArrayEntry("QuadPoints"); len(qp)%2 == 0 {
+			out := make(types.Array, 0, len(qp))
+			for i := 0; i+1 < len(qp); i += 2 {
+				x, err1 := ctx.DereferenceNumber(qp[i])
+				y, err2 := ctx.DereferenceNumber(qp[i+1])
+				if err1 != nil || err2 != nil {
+					out = nil
+					break
+				}
+				x, y = m.apply(x, y)
+				out = append(out, types.Float(x), types.Float(y))
+			}
+			if len(out) > 0 {
+				d["QuadPoints"] = out
+			}
+		}
+	}
+	return nil
+}
+
+// intersectRect returns the overlap of a and b, which is empty if they are disjoint.
+func intersectRect(a, b *types.Rectangle) *types.Rectangle {
+	llx, lly := math.Max(a.LL.X, b.LL.X), math.Max(a.LL.Y, b.LL.Y)
+	urx, ury := math.Min(a.UR.X, b.UR.X), math.Min(a.UR.Y, b.UR.Y)
+	if urx < llx {
+		urx = llx
+	}
+	if ury < lly {
+		ury = lly
+	}
+	return types.NewRectangle(llx, lly, urx, ury)
+}
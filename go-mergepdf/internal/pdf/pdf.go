@@ -4,17 +4,148 @@
 //   - MergePDFs: Merges multiple PDF files into a single output file.
 //     Inputs: slice of PDF file paths, output file path.
 //     Output: error if merge fails.
+//   - InterleavePDFs: Merges two PDF files page by page, e.g. front and back scans.
+//     Inputs: first and second PDF file paths, output file path, whether to reverse the second file.
+//     Output: error if merge fails.
 //   - RemoveBookmarks: Removes bookmarks from a PDF file in-place.
 //     Input: PDF file path.
 //     Output: error if operation fails.
+//   - SetMetadata: Sets or strips document information and XMP metadata.
+//     Inputs: PDF file path, output file path, metadata options.
+//     Output: error if operation fails.
+//   - ReadMetadata: Reads the document information of a PDF file.
+//     Input: PDF file path.
+//     Output: metadata, error if the file cannot be read.
+//   - SanitizePDF: Removes active and hidden content from a PDF file.
+//     Inputs: PDF file path, output file path, categories to remove.
+//     Output: report of removed content, error if operation fails.
+//   - ListFormFields: Lists the AcroForm fields of a PDF file.
+//     Input: PDF file path.
+//     Output: fields with type, value and options, error if the file cannot be read.
+//   - FillFormFields: Fills form fields by name or ID, optionally flattening them.
+//     Inputs: PDF file path, output file path, field values, flatten flag.
+//     Output: error if a field is unknown, a value is invalid or the operation fails.
+//   - FlattenForm: Draws form fields into the page content and removes the form.
+//     Inputs: PDF file path, output file path.
+//     Output: number of flattened widgets, error if operation fails.
+//   - BulkFillForms: Fills a form once per CSV row and writes the results to a ZIP archive.
+//     Inputs: template PDF path, CSV reader, ZIP output path, column mapping and naming options.
+//     Output: report with per-row errors, error if the batch cannot run.
+//   - MergeFormPDFs: Merges PDF files, prefixing form fields per file to avoid collisions.
+//     Inputs: slice of PDF file paths, output file path.
+//     Output: error if merge fails.
+//   - OverlayPDF: Stamps template pages such as a letterhead over or under each page.
+//     Inputs: PDF file path, template PDF path, output file path, template page selection.
+//     Output: error if a template page is invalid or the operation fails.
+//   - NormalizePageSizes: Scales every page onto a uniform paper size.
+//     Inputs: PDF file path, output file path, target size, mode and margin.
+//     Output: error if the options are invalid or the operation fails.
+//   - ImposePDF: Arranges pages n-up on sheets or reorders them into a booklet.
+//     Inputs: PDF file path, output file path, imposition options.
+//     Output: error if the options are invalid or the operation fails.
+//   - CropPDF: Cuts margins from selected pages or trims them to their content.
+//     Inputs: PDF file path, output file path, crop options.
+//     Output: number of cropped pages, error if the options are invalid or the operation fails.
+//   - InsertPages: Inserts blank pages or pages of another PDF after a given page.
+//     Inputs: PDF file path, source PDF path (empty for blank pages), output file path, insert options.
+//     Output: number of inserted pages, error if the options are invalid or the operation fails.
+//   - ExtractImages: Writes the images used by selected pages to a ZIP archive.
+//     Inputs: PDF file path, ZIP output path, page selection.
+//     Output: report of extracted and skipped images, error if there are none or the operation fails.
+//   - ExtractText: Extracts the text of every page.
+//     Input: PDF file path.
+//     Output: text per page with a flag for pages without text, error if the file cannot be read.
+//   - RenderThumbnail: Renders a page as a small PNG image.
+//     Inputs: context, PDF file path, page, width and optional external renderer.
+//     Output: PNG data, error if the page is invalid or rendering fails or is cancelled.
+//   - AddAttachments: Embeds files into a PDF, optionally as a portfolio.
+//     Inputs: PDF file path, output file path, files with names and descriptions, portfolio flag.
+//     Output: names the files were stored under, error if operation fails.
+//   - ListAttachments: Lists the files embedded in a PDF.
+//     Input: PDF file path.
+//     Output: attachments with description, size and date, error if the file cannot be read.
+//   - ExtractAttachment: Returns the data of an embedded file.
+//     Inputs: PDF file path, attachment name, size limit.
+//     Output: file data, error if the attachment is missing, too large or cannot be decoded.
+//   - RedactPDF: Removes content under rectangles and search matches and paints black boxes over them.
+//     Inputs: PDF file path, output file path, redaction options.
+//     Output: report of redactions per page, error if the options are invalid or the operation fails.
+//   - ValidatePDF: Checks a PDF in relaxed and strict mode and diagnoses a damaged cross-reference section.
+//     Input: PDF file path.
+//     Output: validation report with issues, error if the file cannot be read.
+//   - RepairPDF: Rewrites a damaged PDF, rebuilding the cross-reference table and trailer if needed.
+//     Inputs: PDF file path, output file path.
+//     Output: error if the file cannot be repaired.
+//   - CheckPDFA: Reports why a PDF does not conform to PDF/A-1b, 2b or 3b.
+//     Inputs: PDF file path, levels to check (all if empty).
+//     Output: report with issues per level, error if the file cannot be read.
+//   - ConvertToPDFA: Converts a PDF to PDF/A-2b, adding an sRGB output intent and XMP identification.
+//     Inputs: PDF file path, output file path.
+//     Output: conformance of the result or the issues that cannot be fixed, error if conversion fails.
+//   - LinearizePDF: Rewrites a PDF for fast web view, first page first and with hint tables.
+//     Inputs: PDF file path, output file path.
+//     Output: error if the file is encrypted or the operation fails.
+//   - ReadPageLabels: Reads the page label ranges of a PDF.
+//     Input: PDF file path.
+//     Output: label ranges, error if the file cannot be read.
+//   - MergedPageLabels: Combines the page labels of PDF files as they would be merged in sequence.
+//     Input: slice of PDF file paths.
+//     Output: label ranges (empty if no file has labels), error if a file cannot be read.
+//   - SetPageLabels: Replaces the page labels of a PDF.
+//     Inputs: PDF file path, output file path, label ranges.
+//     Output: error if a range is invalid or the operation fails.
+//   - TOCEntries: Lists PDF files and their top-level bookmarks with the pages they would start on when merged.
+//     Inputs: slice of PDF file paths, titles, bookmarks flag.
+//     Output: contents entries, error if a file cannot be read.
+//   - AddTOC: Inserts contents pages with links to the listed pages in front of a PDF.
+//     Inputs: PDF file path, output file path, contents entries, contents options.
+//     Output: number of contents pages, error if operation fails.
+//   - SplitPDF: Cuts a PDF into consecutive parts within page and byte limits and writes them to a ZIP archive.
+//     Inputs: PDF file path, ZIP output path, part name, source file paths, split options, function applied to each part.
+//     Output: parts with their pages and sizes, error if the options are invalid or the operation fails.
+//   - PageFingerprints: Hashes the content of every page, including the resources it uses.
+//     Input: PDF file path.
+//     Output: one fingerprint per page, error if the file cannot be read.
+//   - DropDuplicatePages: Leaves out pages that repeat an earlier page of the same or an earlier file.
+//     Inputs: slice of PDF file paths, directory for copies without the repeated pages.
+//     Output: files to merge instead, removed pages with the pages they repeat, error if operation fails.
+//   - ComparePDFs: Compares a revised PDF with the original by page count, document information, page text and images.
+//     Inputs: original PDF file path, revised PDF file path.
+//     Output: report of differing metadata and added, removed and changed pages, error if a file cannot be read.
+//   - WriteCompareReport: Writes a comparison report as a PDF with highlighted changes.
+//     Inputs: comparison report, output file path, names of the original and revised documents.
+//     Output: error if operation fails.
+//   - ListAnnotations: Lists the annotations of a PDF with their type, author and contents.
+//     Input: PDF file path.
+//     Output: annotations per page, error if the file cannot be read.
+//   - RemoveAnnotations: Deletes annotations selected by type, author and page.
+//     Inputs: PDF file path, output file path, annotation filter.
+//     Output: number of removed annotations, error if the filter is invalid or the operation fails.
+//   - FlattenAnnotations: Draws annotations selected by type, author and page into the page content.
+//     Inputs: PDF file path, output file path, annotation filter.
+//     Output: number of flattened annotations, error if the filter is invalid or the operation fails.
+//   - FindBlankPages: Finds pages with nothing drawn on them or inked below a threshold, such as scanned backs.
+//     Inputs: PDF file path, threshold and page selection.
+//     Output: blank pages with the reason and inked percentage, error if the options are invalid.
+//   - RemoveBlankPages: Writes a PDF without its blank pages, keeping the labels of the others.
+//     Inputs: PDF file path, output file path, threshold and page selection.
+//     Output: removed pages, error if all pages are blank or the operation fails.
+//   - DropBlankPages: Leaves out the blank pages of files for a sequential merge.
+//     Inputs: slice of PDF file paths, directory for copies without the blank pages, blank page options.
+//     Output: files to merge instead, removed pages, error if all pages are blank or the operation fails.
+//   - DropInterleavedBlankPages: Removes the blank pages of two interleaved files from the merged PDF.
+//     Inputs: first and second file paths, merged PDF path, whether the second file was reversed, blank page options.
+//     Output: removed pages numbered within their files, error if all pages are blank or the operation fails.
 //
 // These functions are used by the API handlers to process user-uploaded files.
 package pdf
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 
 	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
@@ -27,9 +158,43 @@ func MergePDFs(files []string, outputPath string) error {
 	return pdfapi.MergeCreateFile(files, outputPath, false, config)
 }
 
+// InterleavePDFs merges two files by alternating their pages: page 1 of first,
+// page 1 of second, page 2 of first and so on. Surplus pages of the longer
+// file are appended. With reverseSecond set the second file is read back to
+// front, as produced by scanning the backs of a stack in one pass.
+func InterleavePDFs(first, second, outputPath string, reverseSecond bool) error {
+	config := model.NewDefaultConfiguration()
+	if reverseSecond {
+		pageCount, err := pdfapi.PageCountFile(second)
+		if err != nil {
+			return fmt.Errorf("failed to read PDF: %w", err)
+		}
+		pages := make([]string, 0, pageCount)
+		for i := pageCount; i >= 1; i-- {
+			pages = append(pages, strconv.Itoa(i))
+		}
+
+		reversed := outputPath + ".reversed"
+		if err := pdfapi.CollectFile(second, reversed, pages, config); err != nil {
+			return fmt.Errorf("failed to reverse pages: %w", err)
+		}
+		defer os.Remove(reversed)
+		second = reversed
+	}
+
+	if err := pdfapi.MergeCreateZipFile(first, second, outputPath, model.NewDefaultConfiguration()); err != nil {
+		return fmt.Errorf("failed to interleave pages: %w", err)
+	}
+	return nil
+}
+
 func RemoveBookmarks(pdfPath string) error {
 	config := model.NewDefaultConfiguration()
-	return pdfapi.RemoveBookmarksFile(pdfPath, pdfPath, config)
+	// Interleaved merges come back without an outline, which is fine here.
+	if err := pdfapi.RemoveBookmarksFile(pdfPath, pdfPath, config); err != nil && !errors.Is(err, pdfapi.ErrNoOutlines) {
+		return err
+	}
+	return nil
 }
 
 // SignPDF stamps a signature image onto a PDF at the specified page, coordinates, and scale.
@@ -69,6 +234,17 @@ func SignPDF(pdfPath, sigImgPath string, pageNum int, x, y, scale float64, outpu
 	return nil
 }
 
+// writeContextFile writes ctx to outputPath through a temporary file, so
+// outputPath may be the file ctx was read from.
+func writeContextFile(ctx *model.Context, outputPath string) error {
+	tmpPath := outputPath + ".tmp"
+	if err := pdfapi.WriteContextFile(ctx, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, outputPath)
+}
+
 // copyFile copies a file from src to dst.
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
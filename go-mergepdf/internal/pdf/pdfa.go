@@ -0,0 +1,903 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrPDFAConversion is returned when a document cannot be converted to PDF/A.
+var ErrPDFAConversion = errors.New("cannot convert to PDF/A")
+
+// PDFALevels are the conformance levels CheckPDFA reports on.
+var PDFALevels = []string{"1b", "2b", "3b"}
+
+// PDFAIssue is a reason a document does not conform to a PDF/A level.
+// Fixable issues are resolved by ConvertToPDFA.
+type PDFAIssue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Fixable bool   `json:"fixable"`
+}
+
+// PDFAConformance lists the issues found for one PDF/A level.
+type PDFAConformance struct {
+	Level      string      `json:"level"`
+	Conformant bool        `json:"conformant"`
+	Issues     []PDFAIssue `json:"issues"`
+}
+
+// PDFAReport is the result of CheckPDFA. Claimed is the level the document
+// identifies itself as in its XMP metadata, such as "2B".
+type PDFAReport struct {
+	Claimed string            `json:"claimed,omitempty"`
+	Levels  []PDFAConformance `json:"levels"`
+}
+
+// pdfaForbiddenActions are the action types PDF/A does not allow.
+// JavaScript is reported separately.
+var pdfaForbiddenActions = map[string]bool{
+	"Launch": true, "Sound": true, "Movie": true, "ResetForm": true, "ImportData": true,
+	"Hide": true, "SetOCGState": true, "Rendition": true, "Trans": true, "GoTo3DView": true,
+}
+
+// pdfaForbiddenAnnotations are the annotation subtypes PDF/A-2 and PDF/A-3
+// do not allow. PDF/A-1 also rules out file attachments.
+var pdfaForbiddenAnnotations = map[string]bool{
+	"Sound": true, "Movie": true, "Screen": true, "3D": true, "RichMedia": true,
+}
+
+// Annotation flags PDF/A requires to be set or cleared.
+const (
+	annotInvisible    = 1
+	annotHidden       = 2
+	annotPrint        = 4
+	annotNoView       = 32
+	annotToggleNoView = 256
+)
+
+var (
+	pdfaPartPattern        = regexp.MustCompile(`pdfaid:part(?:>|\s*=\s*["'])\s*(\d)`)
+	pdfaConformancePattern = regexp.MustCompile(`pdfaid:conformance(?:>|\s*=\s*["'])\s*([ABUabu])`)
+)
+
+// pdfaEmbeddedFile is a file specification with an embedded file stream.
+type pdfaEmbeddedFile struct {
+	name         string
+	pdf          bool
+	relationship bool
+}
+
+// pdfaFacts collects the properties of a document that decide its PDF/A
+// conformance, so that every level can be judged from one pass.
+type pdfaFacts struct {
+	encrypted     bool
+	noID          bool
+	claimed       string
+	outputIntent  bool
+	javaScript    int
+	actions       map[string]int
+	fonts         map[string]bool
+	transparency  map[string]bool
+	embedded      []pdfaEmbeddedFile
+	annotations   map[string]int
+	badFlags      int
+	noAppearance  int
+	lzw           int
+	jpx           int
+	interpolate   int
+	transfer      int
+	postScript    int
+	optional      bool
+	needAppearing bool
+}
+
+// CheckPDFA reports how the PDF at pdfPath falls short of the given PDF/A
+// levels, or of all PDFALevels if levels is empty. It covers the common
+// reasons documents fail, not every clause of ISO 19005, and does not
+// replace a full validator.
+func CheckPDFA(pdfPath string, levels []string) (*PDFAReport, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if len(levels) == 0 {
+		levels = PDFALevels
+	}
+
+	facts, err := inspectPDFA(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect PDF: %w", err)
+	}
+	report := &PDFAReport{Claimed: facts.claimed, Levels: []PDFAConformance{}}
+	for _, level := range levels {
+		report.Levels = append(report.Levels, facts.conformance(level))
+	}
+	return report, nil
+}
+
+// ConvertToPDFA writes a PDF/A-2b version of the PDF at pdfPath to
+// outputPath, which may equal pdfPath. It embeds an sRGB output intent and
+// XMP identification, decrypts the document and removes what PDF/A forbids:
+// JavaScript and other active content, embedded files, hidden annotations,
+// LZW compression and transfer functions. Documents with problems it cannot
+// fix, such as fonts that are not embedded, are left alone; the returned
+// conformance then lists those problems and the error wraps
+// ErrPDFAConversion.
+func ConvertToPDFA(pdfPath, outputPath string) (*PDFAConformance, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	facts, err := inspectPDFA(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect PDF: %w", err)
+	}
+	blockers := PDFAConformance{Level: "2b", Issues: []PDFAIssue{}}
+	for _, issue := range facts.conformance("2b").Issues {
+		if !issue.Fixable {
+			blockers.Issues = append(blockers.Issues, issue)
+		}
+	}
+	if len(blockers.Issues) > 0 {
+		return &blockers, fmt.Errorf("%w: %d issues cannot be fixed", ErrPDFAConversion, len(blockers.Issues))
+	}
+
+	info, err := readInfoEntries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	if err := fixPDFA(ctx); err != nil {
+		return nil, fmt.Errorf("failed to convert PDF: %w", err)
+	}
+
+	tmpPath := outputPath + ".pdfa"
+	defer os.Remove(tmpPath)
+	if err := writeContextFile(ctx, tmpPath); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	// pdfcpu stamps its own Producer and dates on every full write, and
+	// PDF/A requires the Info dictionary and the XMP packet to agree, so both
+	// go into an incremental update on top.
+	if err := writePDFAIncrement(tmpPath, info, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	report, err := CheckPDFA(tmpPath, []string{"2b"})
+	if err != nil {
+		return nil, err
+	}
+	result := report.Levels[0]
+	if !result.Conformant {
+		return &result, fmt.Errorf("%w: %d issues remain after conversion", ErrPDFAConversion, len(result.Issues))
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// conformance judges the facts against one PDF/A level ("1b", "2b" or "3b").
+func (f *pdfaFacts) conformance(level string) PDFAConformance {
+	part := strings.TrimSuffix(level, "b")
+	issues := []PDFAIssue{}
+	add := func(code string, fixable bool, format string, args ...interface{}) {
+		issues = append(issues, PDFAIssue{Code: code, Message: fmt.Sprintf(format, args...), Fixable: fixable})
+	}
+
+	if f.encrypted {
+		add("encryption", true, "The document is encrypted")
+	}
+	if f.noID {
+		add("file-id", true, "The trailer has no file identifier")
+	}
+	if !f.outputIntent {
+		add("output-intent", true, "There is no PDF/A output intent with an embedded ICC profile")
+	}
+	switch {
+	case f.claimed == "":
+		add("xmp", true, "The XMP metadata does not identify the document as PDF/A")
+	case f.claimed[:1] != part:
+		add("xmp", true, "The XMP metadata identifies the document as PDF/A-%s", f.claimed)
+	}
+	if len(f.fonts) > 0 {
+		add("fonts", false, "Fonts are not embedded: %s", strings.Join(sortedKeys(f.fonts), ", "))
+	}
+	if f.javaScript > 0 {
+		add("javascript", true, "The document contains %d JavaScript actions or scripts", f.javaScript)
+	}
+	if len(f.actions) > 0 {
+		add("actions", true, "Forbidden actions: %s", countList(f.actions))
+	}
+
+	annotations := map[string]int{}
+	for subtype, n := range f.annotations {
+		if pdfaForbiddenAnnotations[subtype] || (part == "1" && subtype == "FileAttachment") {
+			annotations[subtype] = n
+		}
+	}
+	if len(annotations) > 0 {
+		add("annotations", true, "Forbidden annotations: %s", countList(annotations))
+	}
+	if f.badFlags > 0 {
+		add("annotation-flags", true, "%d annotations are hidden or not set to print", f.badFlags)
+	}
+	if f.noAppearance > 0 {
+		add("appearance", false, "%d annotations have no appearance stream", f.noAppearance)
+	}
+	if f.needAppearing {
+		add("need-appearances", true, "The form asks viewers to generate field appearances (NeedAppearances)")
+	}
+
+	var files []string
+	for _, e := range f.embedded {
+		switch {
+		case part == "1":
+			files = append(files, e.name)
+		case part == "2" && !e.pdf:
+			files = append(files, e.name)
+		case part == "3" && !e.relationship:
+			files = append(files, e.name)
+		}
+	}
+	if len(files) > 0 {
+		sort.Strings(files)
+		switch part {
+		case "1":
+			add("embedded-files", true, "Embedded files are not allowed: %s", strings.Join(files, ", "))
+		case "2":
+			add("embedded-files", true, "Embedded files must be PDF/A documents: %s", strings.Join(files, ", "))
+		default:
+			add("embedded-files", true, "Embedded files have no AFRelationship: %s", strings.Join(files, ", "))
+		}
+	}
+
+	if f.lzw > 0 {
+		add("lzw", true, "%d streams use LZW compression", f.lzw)
+	}
+	if f.interpolate > 0 {
+		add("interpolate", true, "%d images request interpolation", f.interpolate)
+	}
+	if f.transfer > 0 {
+		add("transfer-functions", true, "%d graphics states use transfer functions", f.transfer)
+	}
+	if f.postScript > 0 {
+		add("postscript", false, "%d PostScript XObjects are used", f.postScript)
+	}
+	if part == "1" {
+		if len(f.transparency) > 0 {
+			add("transparency", false, "Transparency is not allowed: %s", strings.Join(sortedKeys(f.transparency), ", "))
+		}
+		if f.jpx > 0 {
+			add("jpeg2000", false, "%d images use JPEG 2000 compression", f.jpx)
+		}
+		if f.optional {
+			add("optional-content", false, "Optional content (layers) is not allowed")
+		}
+	}
+
+	return PDFAConformance{Level: level, Conformant: len(issues) == 0, Issues: issues}
+}
+
+// inspectPDFA gathers the PDF/A relevant facts about ctx.
+func inspectPDFA(ctx *model.Context) (*pdfaFacts, error) {
+	f := &pdfaFacts{
+		encrypted:    ctx.Encrypt != nil,
+		noID:         ctx.ID == nil,
+		actions:      map[string]int{},
+		fonts:        map[string]bool{},
+		transparency: map[string]bool{},
+		annotations:  map[string]int{},
+	}
+
+	if err := ctx.LocateNameTree("JavaScript", false); err != nil {
+		return nil, err
+	}
+	if ctx.Names["JavaScript"] != nil {
+		if err := ctx.Names["JavaScript"].Process(ctx.XRefTable, func(*model.XRefTable, string, *types.Object) error {
+			f.javaScript++
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if sd, _, err := ctx.DereferenceStreamDict(ctx.RootDict["Metadata"]); err == nil && sd != nil && sd.Decode() == nil {
+		part := pdfaPartPattern.FindSubmatch(sd.Content)
+		conformance := pdfaConformancePattern.FindSubmatch(sd.Content)
+		if part != nil && conformance != nil {
+			f.claimed = string(part[1]) + strings.ToUpper(string(conformance[1]))
+		}
+	}
+	f.outputIntent = hasPDFAOutputIntent(ctx)
+	_, f.optional = ctx.RootDict.Find("OCProperties")
+	if acroForm, err := ctx.DereferenceDict(ctx.RootDict["AcroForm"]); err == nil && acroForm != nil {
+		if b := acroForm.BooleanEntry("NeedAppearances"); b != nil && *b {
+			f.needAppearing = true
+		}
+	}
+
+	eachDict(ctx, func(d types.Dict) {
+		f.inspectDict(ctx, d)
+	})
+
+	for i := 1; i <= ctx.PageCount; i++ {
+		d, _, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			continue
+		}
+		annots, err := ctx.DereferenceArray(d["Annots"])
+		if err != nil {
+			continue
+		}
+		for _, a := range annots {
+			ad, err := ctx.DereferenceDict(a)
+			if err != nil || ad == nil {
+				continue
+			}
+			f.inspectAnnotation(ctx, ad)
+		}
+	}
+	return f, nil
+}
+
+// inspectDict records the facts a single dictionary contributes.
+func (f *pdfaFacts) inspectDict(ctx *model.Context, d types.Dict) {
+	for _, key := range []string{"A", "OpenAction", "Next"} {
+		action, err := ctx.DereferenceDict(d[key])
+		if err != nil || action == nil {
+			continue
+		}
+		if s := action.NameEntry("S"); s != nil {
+			if *s == "JavaScript" {
+				f.javaScript++
+			} else if pdfaForbiddenActions[*s] {
+				f.actions[*s]++
+			}
+		}
+	}
+	if _, ok := d.Find("AA"); ok {
+		f.actions["additional actions (AA)"]++
+	}
+
+	typ, subtype := d.NameEntry("Type"), d.NameEntry("Subtype")
+	switch {
+	case typ != nil && *typ == "Font" && subtype != nil:
+		switch *subtype {
+		case "Type1", "MMType1", "TrueType", "CIDFontType0", "CIDFontType2":
+			fd, err := ctx.DereferenceDict(d["FontDescriptor"])
+			embedded := false
+			if err == nil && fd != nil {
+				for _, key := range []string{"FontFile", "FontFile2", "FontFile3"} {
+					if _, ok := fd.Find(key); ok {
+						embedded = true
+					}
+				}
+			}
+			if !embedded {
+				name := "unnamed"
+				if n := d.NameEntry("BaseFont"); n != nil {
+					name = *n
+				}
+				f.fonts[name] = true
+			}
+		}
+	case subtype != nil && *subtype == "Image":
+		if _, ok := d.Find("SMask"); ok {
+			f.transparency["image soft masks"] = true
+		}
+		if b := d.BooleanEntry("Interpolate"); b != nil && *b {
+			f.interpolate++
+		}
+		if hasFilter(d, filter.JPX) {
+			f.jpx++
+		}
+	case subtype != nil && *subtype == "PS":
+		f.postScript++
+	case typ != nil && *typ == "Filespec":
+		if _, ok := d.Find("EF"); ok {
+			name := fileSpecName(ctx, d)
+			f.embedded = append(f.embedded, pdfaEmbeddedFile{
+				name:         name,
+				pdf:          strings.HasSuffix(strings.ToLower(name), ".pdf"),
+				relationship: d.NameEntry("AFRelationship") != nil,
+			})
+		}
+	}
+
+	if hasFilter(d, filter.LZW) {
+		f.lzw++
+	}
+	if g, err := ctx.DereferenceDict(d["Group"]); err == nil && g != nil {
+		if s := g.NameEntry("S"); s != nil && *s == "Transparency" {
+			f.transparency["transparency groups"] = true
+		}
+	}
+
+	states, err := ctx.DereferenceDict(d["ExtGState"])
+	if err != nil || states == nil {
+		return
+	}
+	for _, o := range states {
+		gs, err := ctx.DereferenceDict(o)
+		if err != nil || gs == nil {
+			continue
+		}
+		if smask, ok := gs.Find("SMask"); ok {
+			if n, ok := smask.(types.Name); !ok || n != "None" {
+				f.transparency["soft masks"] = true
+			}
+		}
+		for _, key := range []string{"CA", "ca"} {
+			if v, ok := gs.Find(key); ok {
+				if alpha, err := ctx.DereferenceNumber(v); err == nil && alpha < 1 {
+					f.transparency["constant alpha below 1"] = true
+				}
+			}
+		}
+		if bm, ok := gs.Find("BM"); ok {
+			if n, ok := bm.(types.Name); !ok || (n != "Normal" && n != "Compatible") {
+				f.transparency["blend modes"] = true
+			}
+		}
+		if _, ok := gs.Find("TR"); ok {
+			f.transfer++
+		} else if tr2, ok := gs.Find("TR2"); ok {
+			if n, ok := tr2.(types.Name); !ok || n != "Default" {
+				f.transfer++
+			}
+		}
+	}
+}
+
+// inspectAnnotation records the facts of a page annotation.
+func (f *pdfaFacts) inspectAnnotation(ctx *model.Context, d types.Dict) {
+	subtype := "Unknown"
+	if s := d.NameEntry("Subtype"); s != nil {
+		subtype = *s
+	}
+	f.annotations[subtype]++
+	if subtype == "Popup" {
+		return
+	}
+
+	flags := 0
+	if i := d.IntEntry("F"); i != nil {
+		flags = *i
+	}
+	if flags&annotPrint == 0 || flags&(annotInvisible|annotHidden|annotNoView|annotToggleNoView) != 0 {
+		f.badFlags++
+	}
+
+	if subtype == "Link" {
+		return
+	}
+	if rect, err := ctx.RectForArray(d.ArrayEntry("Rect")); err == nil && rect != nil && (rect.Width() == 0 || rect.Height() == 0) {
+		return
+	}
+	ap, err := ctx.DereferenceDict(d["AP"])
+	if err != nil || ap == nil {
+		f.noAppearance++
+		return
+	}
+	if _, ok := ap.Find("N"); !ok {
+		f.noAppearance++
+	}
+}
+
+// fixPDFA removes or repairs everything in ctx that keeps it from being
+// PDF/A-2b, short of the metadata written by writePDFAIncrement.
+func fixPDFA(ctx *model.Context) error {
+	ctx.Encrypt = nil
+	ctx.EncKey = nil
+
+	if _, err := removeJavaScript(ctx); err != nil {
+		return err
+	}
+	if _, err := removeEmbeddedFiles(ctx); err != nil {
+		return err
+	}
+	for _, key := range []string{"AF", "Collection", "Metadata"} {
+		ctx.RootDict.Delete(key)
+	}
+	if acroForm, err := ctx.DereferenceDict(ctx.RootDict["AcroForm"]); err == nil && acroForm != nil {
+		acroForm.Delete("NeedAppearances")
+	}
+
+	eachDict(ctx, func(d types.Dict) {
+		for _, key := range []string{"A", "OpenAction", "Next"} {
+			action, err := ctx.DereferenceDict(d[key])
+			if err != nil || action == nil {
+				continue
+			}
+			if s := action.NameEntry("S"); s != nil && pdfaForbiddenActions[*s] {
+				d.Delete(key)
+			}
+		}
+		d.Delete("AA")
+		if s := d.NameEntry("Subtype"); s != nil && *s == "Image" {
+			d.Delete("Interpolate")
+		}
+		if states, err := ctx.DereferenceDict(d["ExtGState"]); err == nil && states != nil {
+			for _, o := range states {
+				if gs, err := ctx.DereferenceDict(o); err == nil && gs != nil {
+					gs.Delete("TR")
+					gs.Delete("TR2")
+				}
+			}
+		}
+	})
+
+	if err := fixPDFAAnnotations(ctx); err != nil {
+		return err
+	}
+	if err := recompressLZW(ctx); err != nil {
+		return err
+	}
+	return setPDFAOutputIntent(ctx)
+}
+
+// fixPDFAAnnotations drops forbidden and file attachment annotations and
+// makes the remaining ones visible and printable.
+func fixPDFAAnnotations(ctx *model.Context) error {
+	for i := 1; i <= ctx.PageCount; i++ {
+		d, _, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			continue
+		}
+		annots, err := ctx.DereferenceArray(d["Annots"])
+		if err != nil || annots == nil {
+			continue
+		}
+
+		kept := types.Array{}
+		for _, a := range annots {
+			ad, err := ctx.DereferenceDict(a)
+			if err != nil || ad == nil {
+				continue
+			}
+			subtype := ""
+			if s := ad.NameEntry("Subtype"); s != nil {
+				subtype = *s
+			}
+			if pdfaForbiddenAnnotations[subtype] || subtype == "FileAttachment" {
+				continue
+			}
+			if subtype != "Popup" {
+				flags := 0
+				if f := ad.IntEntry("F"); f != nil {
+					flags = *f
+				}
+				flags = flags&^(annotInvisible|annotHidden|annotNoView|annotToggleNoView) | annotPrint
+				ad.Update("F", types.Integer(flags))
+			}
+			kept = append(kept, a)
+		}
+
+		if len(kept) == 0 {
+			d.Delete("Annots")
+		} else {
+			d.Update("Annots", kept)
+		}
+	}
+	return nil
+}
+
+// recompressLZW re-encodes LZW compressed streams with Flate.
+func recompressLZW(ctx *model.Context) error {
+	for nr, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		if sd, ok := entry.Object.(types.StreamDict); !ok || !hasFilter(sd.Dict, filter.LZW) {
+			continue
+		}
+		sd, _, err := ctx.DereferenceStreamDict(*types.NewIndirectRef(nr, *entry.Generation))
+		if err != nil || sd == nil {
+			return err
+		}
+		if !decodableFilters(sd.FilterPipeline) {
+			// Image codecs cannot be re-encoded; the final check reports these.
+			continue
+		}
+		if err := sd.Decode(); err != nil {
+			return err
+		}
+		sd.Delete("DecodeParms")
+		sd.InsertName("Filter", filter.Flate)
+		sd.FilterPipeline = []types.PDFFilter{{Name: filter.Flate}}
+		if err := sd.Encode(); err != nil {
+			return err
+		}
+		entry.Object = *sd
+	}
+	return nil
+}
+
+// decodableFilters reports whether pdfcpu can fully decode a stream with
+// the given filters.
+func decodableFilters(pipeline []types.PDFFilter) bool {
+	for _, f := range pipeline {
+		switch f.Name {
+		case filter.LZW, filter.Flate, filter.ASCII85, filter.ASCIIHex, filter.RunLength:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// hasPDFAOutputIntent reports whether the catalog has a PDF/A output intent
+// with an embedded ICC profile.
+func hasPDFAOutputIntent(ctx *model.Context) bool {
+	intents, err := ctx.DereferenceArray(ctx.RootDict["OutputIntents"])
+	if err != nil {
+		return false
+	}
+	for _, o := range intents {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil || d == nil {
+			continue
+		}
+		if s := d.NameEntry("S"); s != nil && *s == "GTS_PDFA1" {
+			if _, ok := d.Find("DestOutputProfile"); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setPDFAOutputIntent gives ctx an sRGB PDF/A output intent unless it
+// already has one.
+func setPDFAOutputIntent(ctx *model.Context) error {
+	if hasPDFAOutputIntent(ctx) {
+		return nil
+	}
+
+	sd, err := ctx.NewStreamDictForBuf(srgbProfile())
+	if err != nil {
+		return err
+	}
+	sd.InsertInt("N", 3)
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	profile, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return err
+	}
+
+	intent := types.Dict{
+		"Type":                      types.Name("OutputIntent"),
+		"S":                         types.Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": types.StringLiteral(srgbProfileDescription),
+		"Info":                      types.StringLiteral(srgbProfileDescription),
+		"RegistryName":              types.StringLiteral("http://www.color.org"),
+		"DestOutputProfile":         *profile,
+	}
+	ctx.RootDict.Update("OutputIntents", types.Array{intent})
+	return nil
+}
+
+// readInfoEntries returns the standard text entries of the Info dictionary
+// of ctx, with dates as parsed times.
+func readInfoEntries(ctx *model.Context) (map[string]interface{}, error) {
+	entries := map[string]interface{}{}
+	if ctx.Info == nil {
+		return entries, nil
+	}
+	d, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || d == nil {
+		return entries, err
+	}
+	for _, key := range []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer", "CreationDate"} {
+		o, ok := d.Find(key)
+		if !ok {
+			continue
+		}
+		s, err := ctx.DereferenceText(o)
+		if err != nil || s == "" {
+			continue
+		}
+		if key == "CreationDate" {
+			if t, ok := types.DateTime(s, true); ok {
+				entries[key] = t
+			}
+			continue
+		}
+		entries[key] = s
+	}
+	return entries, nil
+}
+
+// writePDFAIncrement appends an incremental update to pdfPath holding an
+// Info dictionary with the given entries and a matching XMP packet that
+// identifies the document as PDF/A-2b. modified becomes the ModDate.
+func writePDFAIncrement(pdfPath string, entries map[string]interface{}, modified time.Time) error {
+	f, err := os.OpenFile(pdfPath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	config := model.NewDefaultConfiguration()
+	ctx, err := pdfapi.ReadAndValidate(f, config)
+	if err != nil {
+		return err
+	}
+
+	modified = modified.Truncate(time.Second)
+	created, ok := entries["CreationDate"].(time.Time)
+	if !ok {
+		created = modified
+	}
+	text := map[string]string{
+		"CreationDate": types.DateString(created),
+		"ModDate":      types.DateString(modified),
+	}
+	for k, v := range entries {
+		if s, ok := v.(string); ok {
+			text[k] = s
+		}
+	}
+
+	ctx.Info = nil
+	if err := setInfoEntries(ctx, text); err != nil {
+		return err
+	}
+
+	xmp := pdfaXMP(text, created, modified)
+	sd := types.StreamDict{Dict: types.Dict{
+		"Type":    types.Name("Metadata"),
+		"Subtype": types.Name("XML"),
+	}, Content: []byte(xmp)}
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	metadata, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+	ctx.RootDict.Update("Metadata", *metadata)
+
+	ctx.Write.Increment = true
+	ctx.Write.Offset = ctx.Read.FileSize
+	ctx.Write.IncrementWithObjNr(ctx.Root.ObjectNumber.Value())
+	ctx.Write.IncrementWithObjNr(ctx.Info.ObjectNumber.Value())
+	ctx.Write.IncrementWithObjNr(metadata.ObjectNumber.Value())
+	return pdfapi.WriteIncr(ctx, f, config)
+}
+
+// pdfaXMP returns an XMP packet mirroring the Info entries in text and
+// identifying the document as PDF/A-2b.
+func pdfaXMP(text map[string]string, created, modified time.Time) string {
+	esc := html.EscapeString
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"\n")
+	b.WriteString("    xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\"\n")
+	b.WriteString("    xmlns:dc=\"http://purl.org/dc/elements/1.1/\"\n")
+	b.WriteString("    xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\"\n")
+	b.WriteString("    xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	b.WriteString("   <pdfaid:part>2</pdfaid:part>\n")
+	b.WriteString("   <pdfaid:conformance>B</pdfaid:conformance>\n")
+	if s := text["Title"]; s != "" {
+		fmt.Fprintf(&b, "   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", esc(s))
+	}
+	if s := text["Author"]; s != "" {
+		fmt.Fprintf(&b, "   <dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", esc(s))
+	}
+	if s := text["Subject"]; s != "" {
+		fmt.Fprintf(&b, "   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", esc(s))
+	}
+	if s := text["Keywords"]; s != "" {
+		fmt.Fprintf(&b, "   <pdf:Keywords>%s</pdf:Keywords>\n", esc(s))
+	}
+	if s := text["Producer"]; s != "" {
+		fmt.Fprintf(&b, "   <pdf:Producer>%s</pdf:Producer>\n", esc(s))
+	}
+	if s := text["Creator"]; s != "" {
+		fmt.Fprintf(&b, "   <xmp:CreatorTool>%s</xmp:CreatorTool>\n", esc(s))
+	}
+	fmt.Fprintf(&b, "   <xmp:CreateDate>%s</xmp:CreateDate>\n", created.Format(time.RFC3339))
+	fmt.Fprintf(&b, "   <xmp:ModifyDate>%s</xmp:ModifyDate>\n", modified.Format(time.RFC3339))
+	fmt.Fprintf(&b, "   <xmp:MetadataDate>%s</xmp:MetadataDate>\n", modified.Format(time.RFC3339))
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.String()
+}
+
+// eachDict calls fn for every dictionary in ctx, including stream
+// dictionaries and dictionaries nested in other objects.
+func eachDict(ctx *model.Context, fn func(d types.Dict)) {
+	var walk func(o types.Object)
+	walk = func(o types.Object) {
+		switch o := o.(type) {
+		case types.Dict:
+			fn(o)
+			for _, v := range o {
+				walk(v)
+			}
+		case types.StreamDict:
+			walk(o.Dict)
+		case types.Array:
+			for _, v := range o {
+				walk(v)
+			}
+		}
+	}
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		walk(entry.Object)
+	}
+}
+
+// hasFilter reports whether the stream dictionary d uses the named filter.
+func hasFilter(d types.Dict, name string) bool {
+	switch f := d["Filter"].(type) {
+	case types.Name:
+		return string(f) == name
+	case types.Array:
+		for _, o := range f {
+			if n, ok := o.(types.Name); ok && string(n) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileSpecName returns the file name of a file specification dictionary.
+func fileSpecName(ctx *model.Context, d types.Dict) string {
+	for _, key := range []string{"UF", "F"} {
+		if o, ok := d.Find(key); ok {
+			if s, err := ctx.DereferenceText(o); err == nil && s != "" {
+				return s
+			}
+		}
+	}
+	return "unnamed"
+}
+
+// countList formats counts as "Name (n), ..." sorted by name.
+func countList(counts map[string]int) string {
+	parts := make([]string, 0, len(counts))
+	for _, k := range sortedKeys(counts) {
+		parts = append(parts, fmt.Sprintf("%s (%d)", k, counts[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
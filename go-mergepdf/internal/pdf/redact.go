@@ -0,0 +1,836 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidRedaction is returned for redaction options that cannot be applied.
+var ErrInvalidRedaction = errors.New("invalid redaction")
+
+// RedactArea is a rectangle to redact, in points from the top left corner of
+// the page as displayed, so it follows the page rotation.
+type RedactArea struct {
+	Page   int     `json:"page"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// RedactOptions selects what RedactPDF removes.
+//
+// Areas are redacted on their pages. Search redacts every occurrence of a
+// string, or with Regex of a regular expression such as `\d{3}-\d{2}-\d{4}`,
+// on the pages selected by Pages (all by default). It is matched against the
+// text as ExtractText returns it, with words separated by single spaces and
+// lines by line breaks.
+type RedactOptions struct {
+	Areas      []RedactArea `json:"areas,omitempty"`
+	Search     string       `json:"search,omitempty"`
+	Regex      bool         `json:"regex,omitempty"`
+	IgnoreCase bool         `json:"ignoreCase,omitempty"`
+	Pages      string       `json:"pages,omitempty"`
+}
+
+// Validate reports whether opts describe a usable redaction; area pages are
+// checked against the document by RedactPDF.
+func (opts RedactOptions) Validate() error {
+	if len(opts.Areas) == 0 && opts.Search == "" {
+		return fmt.Errorf("%w: no areas or search given", ErrInvalidRedaction)
+	}
+	for _, a := range opts.Areas {
+		if a.Page < 1 {
+			return fmt.Errorf("%w: page must be at least 1", ErrInvalidRedaction)
+		}
+		if a.Width <= 0 || a.Height <= 0 {
+			return fmt.Errorf("%w: areas must have a positive width and height", ErrInvalidRedaction)
+		}
+	}
+	if opts.Search != "" {
+		if _, err := pdfapi.ParsePageSelection(opts.Pages); err != nil {
+			return fmt.Errorf("%w: invalid page selection %q", ErrInvalidRedaction, opts.Pages)
+		}
+		re, err := opts.pattern()
+		if err != nil {
+			return fmt.Errorf("%w: invalid regular expression: %v", ErrInvalidRedaction, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("%w: search must not match empty text", ErrInvalidRedaction)
+		}
+	}
+	return nil
+}
+
+// pattern compiles the search of opts.
+func (opts RedactOptions) pattern() (*regexp.Regexp, error) {
+	expr := opts.Search
+	if !opts.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// PageRedactions counts what was redacted on a page: the areas given for it,
+// the search matches found on it and the characters removed from its text.
+type PageRedactions struct {
+	Page       int `json:"page"`
+	Areas      int `json:"areas"`
+	Matches    int `json:"matches"`
+	Characters int `json:"characters"`
+}
+
+// RedactReport lists the pages that were redacted.
+type RedactReport struct {
+	Pages   []PageRedactions `json:"pages"`
+	Matches int              `json:"matches"`
+}
+
+// RedactPDF removes the content under the areas and search matches of opts
+// from the PDF at pdfPath, paints black boxes over them and writes the result
+// to outputPath, which may equal pdfPath.
+//
+// Characters whose glyphs are centered in a box are deleted from the text,
+// leaving the remaining text in place. Image pixels under a box are blacked
+// out, or the whole image is dropped if it cannot be decoded. Paths lying
+// inside a box and annotations overlapping one are removed, together with
+// form field values. Form XObjects are rewritten as copies for the page, so
+// other pages using them are unchanged.
+func RedactPDF(pdfPath, outputPath string, opts RedactOptions) (*RedactReport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	areas := map[int][]RedactArea{}
+	for _, a := range opts.Areas {
+		if a.Page > ctx.PageCount {
+			return nil, fmt.Errorf("%w: page %d, document has %d pages", ErrInvalidRedaction, a.Page, ctx.PageCount)
+		}
+		areas[a.Page] = append(areas[a.Page], a)
+	}
+	var re *regexp.Regexp
+	var searchPages types.IntSet
+	if opts.Search != "" {
+		re, _ = opts.pattern()
+		selection, _ := pdfapi.ParsePageSelection(opts.Pages)
+		if searchPages, err = pdfapi.PagesForPageSelection(ctx.PageCount, selection, true, false); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRedaction, err)
+		}
+	}
+
+	report := &RedactReport{Pages: []PageRedactions{}}
+	fonts := map[types.IndirectRef]*textFont{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		var pageRe *regexp.Regexp
+		if searchPages[i] {
+			pageRe = re
+		}
+		if len(areas[i]) == 0 && pageRe == nil {
+			continue
+		}
+		pr, err := redactPage(ctx, i, areas[i], pageRe, fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact page %d: %w", i, err)
+		}
+		if pr.Areas > 0 || pr.Matches > 0 {
+			report.Pages = append(report.Pages, pr)
+			report.Matches += pr.Matches
+		}
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return report, nil
+}
+
+// redactPage redacts the given areas and the matches of re, if any, on a page.
+func redactPage(ctx *model.Context, pageNr int, areas []RedactArea, re *regexp.Regexp, fonts map[types.IndirectRef]*textFont) (PageRedactions, error) {
+	pr := PageRedactions{Page: pageNr, Areas: len(areas)}
+	pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil {
+		return pr, err
+	}
+
+	box := inh.MediaBox
+	if box == nil {
+		box = types.RectForFormat("A4")
+	}
+	if inh.CropBox != nil {
+		box = intersectRect(box, inh.CropBox)
+	}
+	display, _, _ := displayMatrix(box, inh.Rotate)
+	toUser, _ := display.invert()
+	var boxes []*types.Rectangle
+	for _, a := range areas {
+		boxes = append(boxes, transformRect(types.NewRectangle(a.X, a.Y, a.X+a.Width, a.Y+a.Height), toUser))
+	}
+
+	content, err := pageContent(ctx, pageDict)
+	if err != nil {
+		return pr, err
+	}
+	resources, err := ctx.DereferenceDict(pageDict["Resources"])
+	if err != nil {
+		return pr, err
+	}
+	if resources == nil && inh != nil {
+		resources = inh.Resources
+	}
+	ts := textState{ctm: identityMatrix, hScale: 1}
+
+	if re != nil && content != nil {
+		rd := &redactor{ctx: ctx, fonts: fonts, collect: true}
+		rd.scan(content, resources, ts, nil, 0)
+		matched, n := matchGlyphs(rd.glyphs, re)
+		boxes = append(boxes, matched...)
+		pr.Matches = n
+	}
+	if len(boxes) == 0 {
+		return pr, nil
+	}
+
+	rd := &redactor{ctx: ctx, fonts: fonts, areas: boxes}
+	var xobjects types.Dict
+	pageXObjects := func() (types.Dict, error) {
+		if xobjects == nil {
+			d, err := pageResourceDict(ctx, pageDict, inh, "XObject")
+			if err != nil {
+				return nil, err
+			}
+			xobjects = d
+		}
+		return xobjects, nil
+	}
+	var redacted []byte
+	if content != nil {
+		redacted = rd.scan(content, resources, ts, pageXObjects, 0)
+		if rd.err != nil {
+			return pr, rd.err
+		}
+		if redacted == nil {
+			redacted = content
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("q\n")
+	b.Write(redacted)
+	b.WriteString("\nQ\nq 0 g\n")
+	for _, r := range boxes {
+		fmt.Fprintf(&b, "%.4f %.4f %.4f %.4f re\n", r.LL.X, r.LL.Y, r.Width(), r.Height())
+	}
+	b.WriteString("f\nQ\n")
+	if err := setPageContent(ctx, pageDict, b.Bytes()); err != nil {
+		return pr, err
+	}
+	pr.Characters = rd.removed
+
+	return pr, redactAnnotations(ctx, pageDict, boxes)
+}
+
+// redactGlyph is a glyph shown on a page, collected to search the page text.
+type redactGlyph struct {
+	text       string
+	box        *types.Rectangle
+	x, y       float64 // Origin in user space
+	endX, endY float64 // Origin of the next glyph
+	height     float64
+}
+
+// redactor removes content under areas from content streams, or with collect
+// set only gathers the glyphs they show.
+type redactor struct {
+	ctx     *model.Context
+	fonts   map[types.IndirectRef]*textFont
+	areas   []*types.Rectangle
+	collect bool
+	glyphs  []redactGlyph
+	removed int
+	err     error
+}
+
+// font returns the named font of resources, loading each font once per document.
+func (rd *redactor) font(resources types.Dict, name string) *textFont {
+	fontDicts, err := rd.ctx.DereferenceDict(resources["Font"])
+	if err != nil || fontDicts == nil {
+		return fallbackFont
+	}
+	o := fontDicts[name]
+	ir, isRef := o.(types.IndirectRef)
+	if f, ok := rd.fonts[ir]; isRef && ok {
+		return f
+	}
+	d, err := rd.ctx.DereferenceDict(o)
+	if err != nil || d == nil {
+		return fallbackFont
+	}
+	f := loadTextFont(rd.ctx, d)
+	if isRef {
+		rd.fonts[ir] = f
+	}
+	return f
+}
+
+// covers reports whether (x, y) lies in one of the areas.
+func (rd *redactor) covers(x, y float64) bool {
+	for _, r := range rd.areas {
+		if x >= r.LL.X && x <= r.UR.X && y >= r.LL.Y && y <= r.UR.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether r overlaps one of the areas.
+func (rd *redactor) overlaps(r *types.Rectangle) bool {
+	for _, a := range rd.areas {
+		if r.LL.X < a.UR.X && r.UR.X > a.LL.X && r.LL.Y < a.UR.Y && r.UR.Y > a.LL.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// inside reports whether r lies entirely within one of the areas.
+func (rd *redactor) inside(r *types.Rectangle) bool {
+	for _, a := range rd.areas {
+		if r.LL.X >= a.LL.X && r.UR.X <= a.UR.X && r.LL.Y >= a.LL.Y && r.UR.Y <= a.UR.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// scan walks a content stream and returns it with the content under the
+// areas removed, or nil if nothing changed. New XObjects are added to the
+// dictionary returned by xobjects, which is only called when needed.
+func (rd *redactor) scan(content []byte, resources types.Dict, ts textState, xobjects func() (types.Dict, error), depth int) []byte {
+	var stack []textState
+	var tm, tlm matrix
+	var path *types.Rectangle
+	pathStart, clip := -1, false
+
+	var out bytes.Buffer
+	last := 0
+	replace := func(start, end int, s string) {
+		out.Write(content[last:start])
+		out.WriteString("\n" + s + "\n")
+		last = end
+	}
+
+	nextLine := func(tx, ty float64) {
+		tlm = matrix{1, 0, 0, 1, tx, ty}.multiply(tlm)
+		tm = tlm
+	}
+	addPoint := func(x, y float64) {
+		x, y = ts.ctm.apply(x, y)
+		if path == nil {
+			path = types.NewRectangle(x, y, x, y)
+			return
+		}
+		path = types.NewRectangle(math.Min(path.LL.X, x), math.Min(path.LL.Y, y), math.Max(path.UR.X, x), math.Max(path.UR.Y, y))
+	}
+
+	// show walks the strings and adjustments of a text array and returns
+	// the array without the glyphs under the areas, or "" if none are.
+	show := func(array string) string {
+		font := ts.font
+		if font == nil {
+			font = fallbackFont
+		}
+		var elems []string
+		var kept []byte
+		adjust := 0.0
+		removed := false
+		flushKept := func() {
+			if len(kept) > 0 {
+				elems = append(elems, "<"+hex.EncodeToString(kept)+">")
+				kept = nil
+			}
+		}
+		flushAdjust := func() {
+			if adjust != 0 {
+				elems = append(elems, strconv.FormatFloat(math.Round(adjust*1000)/1000, 'f', -1, 64))
+				adjust = 0
+			}
+		}
+
+		l := &contentLexer{b: []byte(strings.TrimSuffix(strings.TrimPrefix(array, "["), "]"))}
+		for {
+			tok, _, ok := l.next()
+			if !ok {
+				break
+			}
+			if f := numbers([]string{tok}); len(f) == 1 {
+				tm = matrix{1, 0, 0, 1, -f[0] / 1000 * ts.fontSize * ts.hScale, 0}.multiply(tm)
+				flushKept()
+				adjust += f[0]
+				continue
+			}
+			font.codes(stringBytes(tok), func(code []byte, text string, width float64, space bool) {
+				advance := width/1000*ts.fontSize + ts.charSpace
+				if space {
+					advance += ts.wordSpace
+				}
+				trm := matrix{ts.fontSize * ts.hScale, 0, 0, ts.fontSize, 0, ts.rise}.multiply(tm).multiply(ts.ctm)
+				tm = matrix{1, 0, 0, 1, advance * ts.hScale, 0}.multiply(tm)
+
+				if rd.collect {
+					x, y := trm.apply(0, 0)
+					ex, ey := matrix{ts.fontSize * ts.hScale, 0, 0, ts.fontSize, 0, ts.rise}.multiply(tm).multiply(ts.ctm).apply(0, 0)
+					rd.glyphs = append(rd.glyphs, redactGlyph{
+						text: text,
+						box:  transformRect(types.NewRectangle(0, -0.25, width/1000, 1), trm),
+						x:    x, y: y, endX: ex, endY: ey,
+						height: math.Hypot(trm[2], trm[3]),
+					})
+					return
+				}
+				if rd.covers(trm.apply(width/2000, 0.35)) {
+					removed = true
+					rd.removed++
+					flushKept()
+					if ts.fontSize != 0 {
+						adjust -= advance * 1000 / ts.fontSize
+					}
+					return
+				}
+				flushAdjust()
+				kept = append(kept, code...)
+			})
+		}
+		if !removed {
+			return ""
+		}
+		flushKept()
+		flushAdjust()
+		return "[" + strings.Join(elems, " ") + "] TJ"
+	}
+
+	for _, op := range parseContent(content) {
+		n := numbers(op.Operands)
+		switch op.Name {
+		case "q":
+			stack = append(stack, ts)
+		case "Q":
+			if len(stack) > 0 {
+				ts, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(n) == 6 {
+				ts.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.multiply(ts.ctm)
+			}
+
+		case "m", "l", "c", "v", "y", "re", "h":
+			if pathStart < 0 {
+				pathStart = op.Start
+			}
+			if op.Name == "re" && len(n) == 4 {
+				addPoint(n[0], n[1])
+				addPoint(n[0]+n[2], n[1]+n[3])
+				break
+			}
+			for i := 0; i+1 < len(n); i += 2 {
+				addPoint(n[i], n[i+1])
+			}
+		case "W", "W*":
+			clip = true
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+			if op.Name != "n" && !clip && !rd.collect && pathStart >= last && path != nil && rd.inside(path) {
+				replace(pathStart, op.End, "")
+			}
+			path, pathStart, clip = nil, -1, false
+
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(op.Operands) == 2 && len(n) == 1 {
+				ts.font = rd.font(resources, strings.TrimPrefix(op.Operands[0], "/"))
+				ts.fontSize = n[0]
+			}
+		case "Tc":
+			if len(n) == 1 {
+				ts.charSpace = n[0]
+			}
+		case "Tw":
+			if len(n) == 1 {
+				ts.wordSpace = n[0]
+			}
+		case "Tz":
+			if len(n) == 1 {
+				ts.hScale = n[0] / 100
+			}
+		case "TL":
+			if len(n) == 1 {
+				ts.leading = n[0]
+			}
+		case "Ts":
+			if len(n) == 1 {
+				ts.rise = n[0]
+			}
+		case "Td", "TD":
+			if len(n) == 2 {
+				if op.Name == "TD" {
+					ts.leading = -n[1]
+				}
+				nextLine(n[0], n[1])
+			}
+		case "Tm":
+			if len(n) == 6 {
+				tlm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -ts.leading)
+		case "Tj", "'", "\"", "TJ":
+			if len(op.Operands) == 0 {
+				break
+			}
+			// ' and " move to the next line first, which the replacement
+			// does with T*.
+			prefix := ""
+			switch op.Name {
+			case "'":
+				nextLine(0, -ts.leading)
+				prefix = "T* "
+			case "\"":
+				if len(op.Operands) == 3 && len(n) >= 2 {
+					ts.wordSpace, ts.charSpace = n[0], n[1]
+					prefix = op.Operands[0] + " Tw " + op.Operands[1] + " Tc "
+				}
+				nextLine(0, -ts.leading)
+				prefix += "T* "
+			}
+			s := op.Operands[len(op.Operands)-1]
+			if op.Name != "TJ" {
+				s = "[" + s + "]"
+			}
+			if r := show(s); r != "" {
+				replace(op.Start, op.End, prefix+r)
+			}
+
+		case "BI":
+			if !rd.collect && rd.overlaps(transformRect(types.NewRectangle(0, 0, 1, 1), ts.ctm)) {
+				replace(op.Start, op.End, "")
+			}
+		case "Do":
+			if len(op.Operands) == 1 {
+				if r, ok := rd.xobject(strings.TrimPrefix(op.Operands[0], "/"), resources, ts, xobjects, depth); ok {
+					replace(op.Start, op.End, r)
+				}
+			}
+		}
+	}
+
+	if last == 0 {
+		return nil
+	}
+	out.Write(content[last:])
+	return out.Bytes()
+}
+
+// xobject redacts the named XObject drawn with ts. It returns the operation
+// replacing the Do, drawing a redacted copy or nothing, and whether the
+// XObject needed redacting.
+func (rd *redactor) xobject(name string, resources types.Dict, ts textState, xobjects func() (types.Dict, error), depth int) (string, bool) {
+	dicts, err := rd.ctx.DereferenceDict(resources["XObject"])
+	if err != nil || dicts == nil {
+		return "", false
+	}
+	sd, _, err := rd.ctx.DereferenceStreamDict(dicts[name])
+	if err != nil || sd == nil {
+		return "", false
+	}
+
+	var redacted *types.StreamDict
+	switch subtype := sd.Dict.NameEntry("Subtype"); {
+	case subtype != nil && *subtype == "Image":
+		if rd.collect || !rd.overlaps(transformRect(types.NewRectangle(0, 0, 1, 1), ts.ctm)) {
+			return "", false
+		}
+		if redacted = rd.redactImage(sd, ts.ctm); redacted == nil {
+			return "", true
+		}
+	case subtype != nil && *subtype == "Form" && depth < maxFormDepth:
+		if redacted = rd.redactForm(sd, resources, ts, depth); redacted == nil {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	ir, err := rd.ctx.IndRefForNewObject(*redacted)
+	if err != nil {
+		rd.err = err
+		return "", false
+	}
+	d, err := xobjects()
+	if err != nil {
+		rd.err = err
+		return "", false
+	}
+	newName := uniqueResourceName(d, "Redacted")
+	d[newName] = *ir
+	return "/" + newName + " Do", true
+}
+
+// redactForm returns a redacted copy of a form XObject, or nil if nothing
+// in it is under the areas.
+func (rd *redactor) redactForm(sd *types.StreamDict, resources types.Dict, ts textState, depth int) *types.StreamDict {
+	ts.ctm = matrixEntry(rd.ctx, sd.Dict, "Matrix").multiply(ts.ctm)
+	if bbox, err := rd.ctx.RectForArray(sd.ArrayEntry("BBox")); !rd.collect && err == nil && bbox != nil {
+		if !rd.overlaps(transformRect(bbox, ts.ctm)) {
+			return nil
+		}
+	}
+	if err := sd.Decode(); err != nil {
+		return nil
+	}
+	formResources, err := rd.ctx.DereferenceDict(sd.Dict["Resources"])
+	if err != nil || formResources == nil {
+		formResources = resources
+	}
+
+	// Redacted XObjects of the form go into a copy of its resources.
+	var copyResources, copyXObjects types.Dict
+	formXObjects := func() (types.Dict, error) {
+		if copyXObjects == nil {
+			copyResources = formResources.Clone().(types.Dict)
+			d, err := rd.ctx.DereferenceDict(copyResources["XObject"])
+			if err != nil {
+				return nil, err
+			}
+			if d == nil {
+				d = types.NewDict()
+			} else {
+				d = d.Clone().(types.Dict)
+			}
+			copyResources["XObject"] = d
+			copyXObjects = d
+		}
+		return copyXObjects, nil
+	}
+
+	content := rd.scan(sd.Content, formResources, ts, formXObjects, depth+1)
+	if content == nil || rd.err != nil {
+		return nil
+	}
+	form, err := rd.ctx.NewStreamDictForBuf(content)
+	if err != nil {
+		rd.err = err
+		return nil
+	}
+	for k, v := range sd.Dict {
+		switch k {
+		case "Length", "Filter", "DecodeParms", "DL":
+		default:
+			form.Dict[k] = v
+		}
+	}
+	if copyResources != nil {
+		form.Dict["Resources"] = copyResources
+	}
+	if err := form.Encode(); err != nil {
+		rd.err = err
+		return nil
+	}
+	return form
+}
+
+// redactImage returns a copy of an image XObject drawn with ctm in which the
+// pixels under the areas are black, or nil if the image cannot be decoded.
+func (rd *redactor) redactImage(sd *types.StreamDict, ctm matrix) (out *types.StreamDict) {
+	// pdfcpu panics on some malformed image dictionaries.
+	defer func() {
+		if recover() != nil {
+			out = nil
+		}
+	}()
+
+	if mask := sd.Dict.BooleanEntry("ImageMask"); mask != nil && *mask {
+		return nil
+	}
+	w, err := rd.ctx.DereferenceInteger(sd.Dict["Width"])
+	if err != nil || w == nil {
+		return nil
+	}
+	h, err := rd.ctx.DereferenceInteger(sd.Dict["Height"])
+	if err != nil || h == nil || w.Value()*h.Value() <= 0 || w.Value()*h.Value() > maxImagePixels {
+		return nil
+	}
+	inv, ok := ctm.invert()
+	if !ok {
+		return nil
+	}
+
+	extracted, err := pdfcpu.ExtractImage(rd.ctx, sd, false, "redact", 0, false)
+	if err != nil || extracted == nil || extracted.Reader == nil {
+		return nil
+	}
+	img, _, err := image.Decode(extracted)
+	if err != nil {
+		return nil
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(b)
+	draw.Draw(rgba, b, img, b.Min, draw.Src)
+
+	// Image rows run top down over the unit square.
+	iw, ih := float64(b.Dx()), float64(b.Dy())
+	for _, a := range rd.areas {
+		u := transformRect(a, inv)
+		px := image.Rect(
+			int(math.Floor(u.LL.X*iw)), int(math.Floor((1-u.UR.Y)*ih)),
+			int(math.Ceil(u.UR.X*iw)), int(math.Ceil((1-u.LL.Y)*ih)),
+		).Add(b.Min).Intersect(b)
+		draw.Draw(rgba, px, image.Black, image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rgba); err != nil {
+		return nil
+	}
+	out, _, _, err = model.CreateImageStreamDict(rd.ctx.XRefTable, &buf, false, false)
+	if err != nil {
+		return nil
+	}
+
+	// A soft mask could still show the shapes under the areas.
+	if _, ok := out.Dict["SMask"]; !ok {
+		if smask, _, err := rd.ctx.DereferenceStreamDict(sd.Dict["SMask"]); err == nil && smask != nil {
+			redacted := rd.redactImage(smask, ctm)
+			if redacted == nil {
+				return nil
+			}
+			ir, err := rd.ctx.IndRefForNewObject(*redacted)
+			if err != nil {
+				return nil
+			}
+			out.Dict["SMask"] = *ir
+		}
+	}
+	return out
+}
+
+// matchGlyphs finds the matches of re in the text shown by glyphs, which is
+// assembled like ExtractText does. It returns boxes covering the matches,
+// one per line of each, and the number of matches.
+func matchGlyphs(glyphs []redactGlyph, re *regexp.Regexp) ([]*types.Rectangle, int) {
+	var b strings.Builder
+	starts := make([]int, len(glyphs))
+	ends := make([]int, len(glyphs))
+	prev := -1
+	for i, g := range glyphs {
+		if g.text != "" && prev >= 0 {
+			p := glyphs[prev]
+			h := math.Max(math.Min(g.height, p.height), 1)
+			switch {
+			case math.Abs(g.y-p.endY) > 0.5*h:
+				b.WriteString("\n")
+			case math.Abs(g.x-p.endX) > 0.15*h && !strings.HasSuffix(p.text, " ") && !strings.HasPrefix(g.text, " "):
+				b.WriteString(" ")
+			}
+		}
+		starts[i] = b.Len()
+		b.WriteString(g.text)
+		ends[i] = b.Len()
+		if g.text != "" {
+			prev = i
+		}
+	}
+
+	var boxes []*types.Rectangle
+	matches := 0
+	for _, m := range re.FindAllStringIndex(b.String(), -1) {
+		var cur *types.Rectangle
+		found := false
+		for i, g := range glyphs {
+			if g.text == "" || starts[i] >= m[1] || ends[i] <= m[0] {
+				continue
+			}
+			found = true
+			r := g.box
+			if cur != nil && sameLine(cur, r) {
+				cur = types.NewRectangle(math.Min(cur.LL.X, r.LL.X), math.Min(cur.LL.Y, r.LL.Y), math.Max(cur.UR.X, r.UR.X), math.Max(cur.UR.Y, r.UR.Y))
+				continue
+			}
+			if cur != nil {
+				boxes = append(boxes, cur)
+			}
+			cur = r
+		}
+		if cur != nil {
+			boxes = append(boxes, cur)
+		}
+		if found {
+			matches++
+		}
+	}
+	return boxes, matches
+}
+
+// sameLine reports whether two glyph boxes overlap vertically by at least
+// half the height of the smaller one.
+func sameLine(a, b *types.Rectangle) bool {
+	overlap := math.Min(a.UR.Y, b.UR.Y) - math.Max(a.LL.Y, b.LL.Y)
+	return overlap >= 0.5*math.Min(a.Height(), b.Height())
+}
+
+// redactAnnotations removes the annotations of a page that overlap the areas.
+// Their appearances, contents and form field values are deleted as well, as
+// the annotation dictionaries may still be referenced by the form.
+func redactAnnotations(ctx *model.Context, pageDict types.Dict, areas []*types.Rectangle) error {
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil || annots == nil {
+		return err
+	}
+	rd := &redactor{areas: areas}
+	kept := types.Array{}
+	for _, o := range annots {
+		d, err := ctx.DereferenceDict(o)
+		if err != nil || d == nil {
+			continue
+		}
+		r, err := ctx.RectForArray(d.ArrayEntry("Rect"))
+		if err != nil || r == nil || !rd.overlaps(r) {
+			kept = append(kept, o)
+			continue
+		}
+		for _, key := range []string{"AP", "V", "Contents", "RC"} {
+			d.Delete(key)
+		}
+		if parent, err := ctx.DereferenceDict(d["Parent"]); err == nil && parent != nil {
+			parent.Delete("V")
+		}
+	}
+
+	if len(kept) == 0 {
+		pageDict.Delete("Annots")
+	} else {
+		pageDict.Update("Annots", kept)
+	}
+	return nil
+}
@@ -0,0 +1,880 @@
+package pdf
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // Decoders for the images pdfcpu extracts
+	_ "image/png"
+	"math"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	_ "golang.org/x/image/tiff"
+	"golang.org/x/image/vector"
+)
+
+// Limits that keep a single page from tying up the rasterizer.
+const (
+	maxRenderOps   = 200000   // Content stream operations per page, including forms and annotations
+	maxImagePixels = 16 << 20 // Image pixels decoded per page
+	maxScanPixels  = 64 << 20 // Pixels of a single image decoded at reduced size, such as a 600 dpi scan
+	curveSegments  = 8        // Line segments per Bézier curve
+)
+
+var (
+	black            = color.RGBA{0, 0, 0, 255}
+	placeholderColor = color.RGBA{0xd9, 0xd9, 0xd9, 255} // Images and shadings that are not decoded
+	fallbackFont     = &textFont{codeBytes: 1, defaultWidth: 500}
+)
+
+// renderState is the graphics state tracked by the rasterizer.
+type renderState struct {
+	ctm          matrix // User space to pixels
+	clip         image.Rectangle
+	fillSpace    string // As classified by colorSpace
+	strokeSpace  string
+	fill, stroke color.RGBA
+	fillAlpha    float64 // Constant alpha from the ExtGState ca and CA entries
+	strokeAlpha  float64
+	lineWidth    float64
+	font         *textFont
+	fontSize     float64
+	charSpace    float64
+	wordSpace    float64
+	hScale       float64
+	leading      float64
+	rise         float64
+	renderMode   int
+}
+
+// pageRenderer draws the marks of a page onto an image. Text is drawn as
+// bars the size of its words, which is what text looks like at thumbnail
+// size, so glyph outlines are never loaded.
+type pageRenderer struct {
+	ctx    *model.Context
+	done   <-chan struct{}
+	img    *image.RGBA
+	raster vector.Rasterizer
+	fonts  map[types.IndirectRef]*textFont
+	images map[int]image.Image
+	ops    int
+	pixels int
+
+	// reduceImages, if not zero, is the largest width and height an image
+	// is kept at. Images are then decoded one at a time up to maxScanPixels
+	// each and scaled down right away, which is enough to measure ink.
+	reduceImages int
+
+	// placeholders counts images and shadings drawn as gray boxes.
+	placeholders int
+
+	// Word bars are batched per color and clip, since every fill costs a
+	// pass over the clip area.
+	textBars  [][][2]float64
+	textColor color.RGBA
+	textClip  image.Rectangle
+}
+
+// renderPage rasterizes the visible area of a page at width pixels, or less
+// for pages taller than maxThumbnailAspect times their width. Rendering
+// ends early when done is closed.
+func renderPage(ctx *model.Context, pageNr, width int, done <-chan struct{}) (*image.RGBA, error) {
+	r, err := rasterizePage(ctx, pageNr, width, 0, done)
+	if err != nil {
+		return nil, err
+	}
+	return r.img, nil
+}
+
+// rasterizePage renders a page like renderPage and returns the renderer,
+// which tells how much of the page could not be drawn faithfully. A
+// non-zero reduceImages keeps images at most that many pixels wide and
+// high, see pageRenderer.
+func rasterizePage(ctx *model.Context, pageNr, width, reduceImages int, done <-chan struct{}) (*pageRenderer, error) {
+	pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	box := inh.MediaBox
+	if box == nil {
+		box = types.RectForFormat("A4")
+	}
+	if inh.CropBox != nil {
+		box = intersectRect(box, inh.CropBox)
+	}
+	display, dw, dh := displayMatrix(box, inh.Rotate)
+	scale := float64(width) / dw
+	if dh*scale > maxThumbnailAspect*float64(width) {
+		scale = maxThumbnailAspect * float64(width) / dh
+	}
+	device := display.multiply(matrix{scale, 0, 0, scale, 0, 0})
+
+	img := image.NewRGBA(image.Rect(0, 0, max(int(math.Round(dw*scale)), 1), max(int(math.Round(dh*scale)), 1)))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	r := &pageRenderer{
+		ctx:    ctx,
+		done:   done,
+		img:    img,
+		fonts:  map[types.IndirectRef]*textFont{},
+		images: map[int]image.Image{},
+
+		reduceImages: reduceImages,
+	}
+	if pageDict == nil {
+		return r, nil
+	}
+	content, err := pageContent(ctx, pageDict)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := ctx.DereferenceDict(pageDict["Resources"])
+	if err != nil {
+		return nil, err
+	}
+	if resources == nil && inh != nil {
+		resources = inh.Resources
+	}
+
+	gs := renderState{ctm: device, clip: img.Bounds(), fill: black, stroke: black, fillAlpha: 1, strokeAlpha: 1, lineWidth: 1, hScale: 1}
+	if content != nil {
+		r.scan(content, resources, gs, 0)
+	}
+	r.drawAnnotations(pageDict, gs)
+	r.flushText()
+	return r, nil
+}
+
+// displayMatrix maps user space onto the visible page area box as a viewer
+// displays it: turned by rotate, in points from the top left corner with y
+// pointing down. It also returns the displayed width and height.
+func displayMatrix(box *types.Rectangle, rotate int) (matrix, float64, float64) {
+	w, h := math.Max(box.Width(), 1), math.Max(box.Height(), 1)
+	rot, dw, dh := identityMatrix, w, h
+	switch (rotate%360 + 360) % 360 {
+	case 90:
+		rot, dw, dh = matrix{0, -1, 1, 0, 0, w}, h, w
+	case 180:
+		rot = matrix{-1, 0, 0, -1, w, h}
+	case 270:
+		rot, dw, dh = matrix{0, 1, -1, 0, h, 0}, h, w
+	}
+	m := matrix{1, 0, 0, 1, -box.LL.X, -box.LL.Y}.multiply(rot).multiply(matrix{1, 0, 0, -1, 0, dh})
+	return m, dw, dh
+}
+
+// stop counts an operation and reports whether rendering should end because
+// the operation limit is reached or rendering was cancelled.
+func (r *pageRenderer) stop() bool {
+	r.ops++
+	if r.ops%1024 == 0 {
+		select {
+		case <-r.done:
+			r.ops = maxRenderOps + 1
+		default:
+		}
+	}
+	return r.ops > maxRenderOps
+}
+
+// font returns the named font of resources, loading each font once per page.
+func (r *pageRenderer) font(resources types.Dict, name string) *textFont {
+	fontDicts, err := r.ctx.DereferenceDict(resources["Font"])
+	if err != nil || fontDicts == nil {
+		return fallbackFont
+	}
+	o := fontDicts[name]
+	ir, isRef := o.(types.IndirectRef)
+	if f, ok := r.fonts[ir]; isRef && ok {
+		return f
+	}
+	d, err := r.ctx.DereferenceDict(o)
+	if err != nil || d == nil {
+		return fallbackFont
+	}
+	f := loadTextFont(r.ctx, d)
+	if isRef {
+		r.fonts[ir] = f
+	}
+	return f
+}
+
+// scan walks a content stream with the given resources and initial state.
+func (r *pageRenderer) scan(content []byte, resources types.Dict, gs renderState, depth int) {
+	var stack []renderState
+	var path [][][2]float64 // Subpaths in pixels
+	var cx, cy float64      // Current point in user space
+	var pendingClip bool
+	var tm, tlm matrix
+
+	moveTo := func(x, y float64) {
+		px, py := gs.ctm.apply(x, y)
+		path = append(path, [][2]float64{{px, py}})
+		cx, cy = x, y
+	}
+	lineTo := func(x, y float64) {
+		if len(path) == 0 {
+			moveTo(x, y)
+			return
+		}
+		px, py := gs.ctm.apply(x, y)
+		path[len(path)-1] = append(path[len(path)-1], [2]float64{px, py})
+		cx, cy = x, y
+	}
+	curveTo := func(x1, y1, x2, y2, x3, y3 float64) {
+		x0, y0 := cx, cy
+		for i := 1; i <= curveSegments; i++ {
+			t := float64(i) / curveSegments
+			u := 1 - t
+			lineTo(u*u*u*x0+3*u*u*t*x1+3*u*t*t*x2+t*t*t*x3, u*u*u*y0+3*u*u*t*y1+3*u*t*t*y2+t*t*t*y3)
+		}
+	}
+	closePath := func() {
+		if len(path) > 0 {
+			sub := path[len(path)-1]
+			path[len(path)-1] = append(sub, sub[0])
+		}
+	}
+	endPath := func(fill, stroke bool) {
+		if fill || stroke {
+			r.flushText()
+		}
+		if fill {
+			r.fillPolygons(path, fade(gs.fill, gs.fillAlpha), gs.clip)
+		}
+		if stroke {
+			r.fillPolygons(strokePolygons(path, strokeWidth(gs)), fade(gs.stroke, gs.strokeAlpha), gs.clip)
+		}
+		if pendingClip {
+			gs.clip = gs.clip.Intersect(polygonBounds(path))
+		}
+		path, pendingClip = nil, false
+	}
+
+	show := func(tok string) {
+		font := gs.font
+		if font == nil {
+			font = fallbackFont
+		}
+		visible := gs.renderMode != 3 && gs.renderMode != 7
+		c := fade(gs.fill, gs.fillAlpha)
+		if gs.renderMode == 1 || gs.renderMode == 5 {
+			c = fade(gs.stroke, gs.strokeAlpha)
+		}
+
+		var start matrix
+		inWord := false
+		endWord := func() {
+			if inWord && visible {
+				r.addTextBar(wordBar(start, gs.textMatrix(tm)), c, gs.clip)
+			}
+			inWord = false
+		}
+		font.decode(stringBytes(tok), func(text string, width float64, space bool) {
+			blank := space || text != "" && strings.TrimSpace(text) == ""
+			if blank {
+				endWord()
+			} else if !inWord {
+				start, inWord = gs.textMatrix(tm), true
+			}
+			advance := width/1000*gs.fontSize + gs.charSpace
+			if space {
+				advance += gs.wordSpace
+			}
+			tm = matrix{1, 0, 0, 1, advance * gs.hScale, 0}.multiply(tm)
+		})
+		endWord()
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = matrix{1, 0, 0, 1, tx, ty}.multiply(tlm)
+		tm = tlm
+	}
+
+	for _, op := range parseContent(content) {
+		if r.stop() {
+			return
+		}
+		n := numbers(op.Operands)
+		switch op.Name {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(n) == 6 {
+				gs.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.multiply(gs.ctm)
+			}
+		case "w":
+			if len(n) == 1 {
+				gs.lineWidth = n[0]
+			}
+		case "gs":
+			if len(op.Operands) == 1 {
+				r.setExtGState(resources, strings.TrimPrefix(op.Operands[0], "/"), &gs)
+			}
+
+		case "g", "rg", "k":
+			gs.fillSpace, gs.fill = "", deviceColor("", n)
+		case "G", "RG", "K":
+			gs.strokeSpace, gs.stroke = "", deviceColor("", n)
+		case "cs":
+			if len(op.Operands) == 1 {
+				gs.fillSpace, gs.fill = r.colorSpace(resources, op.Operands[0]), black
+			}
+		case "CS":
+			if len(op.Operands) == 1 {
+				gs.strokeSpace, gs.stroke = r.colorSpace(resources, op.Operands[0]), black
+			}
+		case "sc", "scn":
+			gs.fill = deviceColor(gs.fillSpace, n)
+		case "SC", "SCN":
+			gs.stroke = deviceColor(gs.strokeSpace, n)
+
+		case "m":
+			if len(n) == 2 {
+				moveTo(n[0], n[1])
+			}
+		case "l":
+			if len(n) == 2 {
+				lineTo(n[0], n[1])
+			}
+		case "c":
+			if len(n) == 6 {
+				curveTo(n[0], n[1], n[2], n[3], n[4], n[5])
+			}
+		case "v":
+			if len(n) == 4 {
+				curveTo(cx, cy, n[0], n[1], n[2], n[3])
+			}
+		case "y":
+			if len(n) == 4 {
+				curveTo(n[0], n[1], n[2], n[3], n[2], n[3])
+			}
+		case "h":
+			closePath()
+		case "re":
+			if len(n) == 4 {
+				moveTo(n[0], n[1])
+				lineTo(n[0]+n[2], n[1])
+				lineTo(n[0]+n[2], n[1]+n[3])
+				lineTo(n[0], n[1]+n[3])
+				closePath()
+			}
+		case "W", "W*":
+			pendingClip = true
+		case "S":
+			endPath(false, true)
+		case "s":
+			closePath()
+			endPath(false, true)
+		case "f", "F", "f*":
+			endPath(true, false)
+		case "B", "B*":
+			endPath(true, true)
+		case "b", "b*":
+			closePath()
+			endPath(true, true)
+		case "n":
+			endPath(false, false)
+
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "ET":
+			r.flushText()
+		case "Tf":
+			if len(op.Operands) == 2 && len(n) == 1 {
+				gs.font = r.font(resources, strings.TrimPrefix(op.Operands[0], "/"))
+				gs.fontSize = n[0]
+			}
+		case "Tc":
+			if len(n) == 1 {
+				gs.charSpace = n[0]
+			}
+		case "Tw":
+			if len(n) == 1 {
+				gs.wordSpace = n[0]
+			}
+		case "Tz":
+			if len(n) == 1 {
+				gs.hScale = n[0] / 100
+			}
+		case "TL":
+			if len(n) == 1 {
+				gs.leading = n[0]
+			}
+		case "Ts":
+			if len(n) == 1 {
+				gs.rise = n[0]
+			}
+		case "Tr":
+			if len(n) == 1 {
+				gs.renderMode = int(n[0])
+			}
+		case "Td", "TD":
+			if len(n) == 2 {
+				if op.Name == "TD" {
+					gs.leading = -n[1]
+				}
+				nextLine(n[0], n[1])
+			}
+		case "Tm":
+			if len(n) == 6 {
+				tlm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -gs.leading)
+		case "Tj", "'", "\"":
+			if op.Name != "Tj" {
+				nextLine(0, -gs.leading)
+			}
+			if op.Name == "\"" && len(n) >= 2 {
+				gs.wordSpace, gs.charSpace = n[0], n[1]
+			}
+			if len(op.Operands) > 0 {
+				show(op.Operands[len(op.Operands)-1])
+			}
+		case "TJ":
+			if len(op.Operands) == 1 {
+				l := &contentLexer{b: []byte(strings.TrimSuffix(strings.TrimPrefix(op.Operands[0], "["), "]"))}
+				for {
+					tok, _, ok := l.next()
+					if !ok {
+						break
+					}
+					if f := numbers([]string{tok}); len(f) == 1 {
+						tm = matrix{1, 0, 0, 1, -f[0] / 1000 * gs.fontSize * gs.hScale, 0}.multiply(tm)
+						continue
+					}
+					show(tok)
+				}
+			}
+
+		case "BI":
+			r.flushText()
+			r.placeholders++
+			r.fillPolygons([][][2]float64{unitSquare(gs.ctm)}, placeholderColor, gs.clip)
+		case "sh":
+			r.flushText()
+			r.placeholders++
+			r.fillPolygons([][][2]float64{rectPolygon(gs.clip)}, placeholderColor, gs.clip)
+		case "Do":
+			if len(op.Operands) == 1 {
+				r.drawXObject(strings.TrimPrefix(op.Operands[0], "/"), resources, gs, depth)
+			}
+		}
+	}
+}
+
+// textMatrix returns the text rendering matrix for the text matrix tm.
+func (gs renderState) textMatrix(tm matrix) matrix {
+	return matrix{gs.fontSize * gs.hScale, 0, 0, gs.fontSize, 0, gs.rise}.multiply(tm).multiply(gs.ctm)
+}
+
+// wordBar returns the bar drawn for a word running from the text rendering
+// matrix start to end, covering roughly the x-height of its glyphs.
+func wordBar(start, end matrix) [][2]float64 {
+	x0, y0 := start.apply(0, 0.05)
+	x1, y1 := end.apply(-0.05, 0.05)
+	x2, y2 := end.apply(-0.05, 0.55)
+	x3, y3 := start.apply(0, 0.55)
+	return [][2]float64{{x0, y0}, {x1, y1}, {x2, y2}, {x3, y3}}
+}
+
+// addTextBar queues a word bar, drawing the queued bars first when they
+// differ in color or clip.
+func (r *pageRenderer) addTextBar(bar [][2]float64, c color.RGBA, clip image.Rectangle) {
+	if len(r.textBars) > 0 && (c != r.textColor || clip != r.textClip) {
+		r.flushText()
+	}
+	r.textBars = append(r.textBars, bar)
+	r.textColor, r.textClip = c, clip
+}
+
+// flushText draws the queued word bars, lightened so that text reads as gray
+// lines next to solid graphics.
+func (r *pageRenderer) flushText() {
+	if len(r.textBars) == 0 {
+		return
+	}
+	r.fillPolygons(r.textBars, fade(r.textColor, 0.6), r.textClip)
+	r.textBars = r.textBars[:0]
+}
+
+// drawXObject draws the named image or form XObject.
+func (r *pageRenderer) drawXObject(name string, resources types.Dict, gs renderState, depth int) {
+	xobjects, err := r.ctx.DereferenceDict(resources["XObject"])
+	if err != nil || xobjects == nil {
+		return
+	}
+	sd, _, err := r.ctx.DereferenceStreamDict(xobjects[name])
+	if err != nil || sd == nil {
+		return
+	}
+
+	subtype := sd.Dict.NameEntry("Subtype")
+	if subtype != nil && *subtype == "Image" {
+		objNr := 0
+		if ir, ok := xobjects[name].(types.IndirectRef); ok {
+			objNr = ir.ObjectNumber.Value()
+		}
+		r.flushText()
+		r.drawImage(sd, name, objNr, gs)
+		return
+	}
+	if subtype != nil && *subtype == "Form" && depth < maxFormDepth {
+		r.drawForm(sd, resources, gs, depth)
+	}
+}
+
+// drawForm draws a form XObject or appearance stream, clipped to its bounding box.
+func (r *pageRenderer) drawForm(sd *types.StreamDict, resources types.Dict, gs renderState, depth int) {
+	gs.ctm = matrixEntry(r.ctx, sd.Dict, "Matrix").multiply(gs.ctm)
+	if bbox, err := r.ctx.RectForArray(sd.ArrayEntry("BBox")); err == nil && bbox != nil {
+		gs.clip = gs.clip.Intersect(polygonBounds([][][2]float64{unitSquare(matrix{bbox.Width(), 0, 0, bbox.Height(), bbox.LL.X, bbox.LL.Y}.multiply(gs.ctm))}))
+	}
+	if gs.clip.Empty() {
+		return
+	}
+	if err := sd.Decode(); err != nil {
+		return
+	}
+	formResources, err := r.ctx.DereferenceDict(sd.Dict["Resources"])
+	if err != nil || formResources == nil {
+		formResources = resources
+	}
+	r.scan(sd.Content, formResources, gs, depth+1)
+}
+
+// drawImage draws an image XObject into the unit square of the current
+// transformation. Images that cannot be decoded, or that would exceed
+// maxImagePixels, are drawn as gray boxes.
+func (r *pageRenderer) drawImage(sd *types.StreamDict, name string, objNr int, gs renderState) {
+	img, ok := r.images[objNr]
+	if !ok || objNr == 0 {
+		img = r.decodeImage(sd, name, objNr)
+		r.images[objNr] = img
+	}
+
+	m := gs.ctm
+	if img == nil || math.Abs(m[0]*m[3]-m[1]*m[2]) < 1e-9 {
+		if img == nil {
+			r.placeholders++
+		}
+		r.fillPolygons([][][2]float64{unitSquare(m)}, placeholderColor, gs.clip)
+		return
+	}
+	if gs.clip.Empty() {
+		return
+	}
+
+	// Image rows run top down, while the unit square has y up.
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	s2d := f64.Aff3{
+		m[0] / w, -m[2] / h, m[2] + m[4] - (m[0]*float64(b.Min.X)/w - m[2]*float64(b.Min.Y)/h),
+		m[1] / w, -m[3] / h, m[3] + m[5] - (m[1]*float64(b.Min.X)/w - m[3]*float64(b.Min.Y)/h),
+	}
+	xdraw.ApproxBiLinear.Transform(r.img.SubImage(gs.clip).(*image.RGBA), s2d, img, b, xdraw.Over, nil)
+}
+
+// decodeImage decodes an image XObject within the pixel budget of the page,
+// or at reduced size if the renderer reduces images.
+func (r *pageRenderer) decodeImage(sd *types.StreamDict, name string, objNr int) (img image.Image) {
+	// pdfcpu panics on some malformed image dictionaries.
+	defer func() {
+		if recover() != nil {
+			img = nil
+		}
+	}()
+
+	w, err := r.ctx.DereferenceInteger(sd.Dict["Width"])
+	if err != nil || w == nil {
+		return nil
+	}
+	h, err := r.ctx.DereferenceInteger(sd.Dict["Height"])
+	if err != nil || h == nil {
+		return nil
+	}
+	pixels := w.Value() * h.Value()
+	switch {
+	case pixels <= 0:
+		return nil
+	case r.reduceImages > 0:
+		if pixels > maxScanPixels {
+			return nil
+		}
+	case r.pixels+pixels > maxImagePixels:
+		return nil
+	default:
+		r.pixels += pixels
+	}
+
+	extracted, err := pdfcpu.ExtractImage(r.ctx, sd, false, name, objNr, false)
+	if err != nil || extracted == nil || extracted.Reader == nil {
+		return nil
+	}
+	img, _, err = image.Decode(extracted)
+	if err != nil {
+		return nil
+	}
+	if r.reduceImages > 0 {
+		return reduceImage(img, r.reduceImages)
+	}
+	return img
+}
+
+// reduceImage scales img down to at most size pixels wide and high.
+func reduceImage(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	scale := float64(size) / float64(max(b.Dx(), b.Dy()))
+	if scale >= 1 {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(int(float64(b.Dx())*scale), 1), max(int(float64(b.Dy())*scale), 1)))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+// drawAnnotations draws the normal appearance of the visible annotations of a
+// page, such as filled in form fields and stamps.
+func (r *pageRenderer) drawAnnotations(pageDict types.Dict, gs renderState) {
+	annots, err := r.ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil {
+		return
+	}
+	for _, o := range annots {
+		d, err := r.ctx.DereferenceDict(o)
+		if err != nil || d == nil {
+			continue
+		}
+		if f := d.IntEntry("F"); f != nil && *f&(1<<1|1<<5) != 0 { // Hidden or NoView
+			continue
+		}
+		ap, err := r.ctx.DereferenceDict(d["AP"])
+		if err != nil || ap == nil {
+			continue
+		}
+		sd, _, err := r.ctx.DereferenceStreamDict(ap["N"])
+		if err != nil || sd == nil {
+			// Appearances with states, such as check boxes, are picked by AS.
+			states, err := r.ctx.DereferenceDict(ap["N"])
+			as := d.NameEntry("AS")
+			if err != nil || states == nil || as == nil {
+				continue
+			}
+			if sd, _, err = r.ctx.DereferenceStreamDict(states[*as]); err != nil || sd == nil {
+				continue
+			}
+		}
+		rectArray, err := r.ctx.DereferenceArray(d["Rect"])
+		if err != nil {
+			continue
+		}
+		rect, err := r.ctx.RectForArray(rectArray)
+		if err != nil || rect == nil {
+			continue
+		}
+		bbox, err := r.ctx.RectForArray(sd.ArrayEntry("BBox"))
+		if err != nil || bbox == nil {
+			continue
+		}
+
+		// The appearance is fitted to the annotation rectangle after its own
+		// matrix is applied, as described in PDF 32000-1 12.5.5.
+		tb := transformRect(bbox, matrixEntry(r.ctx, sd.Dict, "Matrix"))
+		if tb.Width() <= 0 || tb.Height() <= 0 {
+			continue
+		}
+		sx, sy := rect.Width()/tb.Width(), rect.Height()/tb.Height()
+		ags := gs
+		ags.ctm = matrix{sx, 0, 0, sy, rect.LL.X - tb.LL.X*sx, rect.LL.Y - tb.LL.Y*sy}.multiply(gs.ctm)
+		r.flushText()
+		r.drawForm(sd, nil, ags, 0)
+	}
+}
+
+// fillPolygons fills polygons given in pixels with c, limited to clip. Only
+// the area covered by the polygons is rasterized.
+// Overlapping polygons of the same orientation are filled once and opposite
+// ones cancel, which approximates the nonzero winding rule.
+func (r *pageRenderer) fillPolygons(polygons [][][2]float64, c color.RGBA, clip image.Rectangle) {
+	clip = clip.Intersect(r.img.Bounds()).Intersect(polygonBounds(polygons))
+	if clip.Empty() {
+		return
+	}
+
+	// The rasterizer mask starts at the clip origin.
+	ox, oy := float64(clip.Min.X), float64(clip.Min.Y)
+	r.raster.Reset(clip.Dx(), clip.Dy())
+	painted := false
+	for _, p := range polygons {
+		if len(p) < 2 {
+			continue
+		}
+		r.raster.MoveTo(float32(p[0][0]-ox), float32(p[0][1]-oy))
+		for _, q := range p[1:] {
+			r.raster.LineTo(float32(q[0]-ox), float32(q[1]-oy))
+		}
+		r.raster.ClosePath()
+		painted = true
+	}
+	if painted {
+		r.raster.Draw(r.img, clip, image.NewUniform(c), image.Point{})
+	}
+}
+
+// strokeWidth returns the line width of gs in pixels, at least a faint hairline.
+func strokeWidth(gs renderState) float64 {
+	m := gs.ctm
+	return math.Max(gs.lineWidth*math.Sqrt(math.Abs(m[0]*m[3]-m[1]*m[2])), 0.7)
+}
+
+// strokePolygons returns a quadrilateral of the given width around every
+// segment of path, all wound the same way.
+func strokePolygons(path [][][2]float64, width float64) [][][2]float64 {
+	var polygons [][][2]float64
+	for _, sub := range path {
+		for i := 1; i < len(sub); i++ {
+			p, q := sub[i-1], sub[i]
+			dx, dy := q[0]-p[0], q[1]-p[1]
+			l := math.Hypot(dx, dy)
+			if l == 0 {
+				continue
+			}
+			nx, ny := -dy/l*width/2, dx/l*width/2
+			polygons = append(polygons, [][2]float64{
+				{p[0] + nx, p[1] + ny}, {q[0] + nx, q[1] + ny}, {q[0] - nx, q[1] - ny}, {p[0] - nx, p[1] - ny},
+			})
+		}
+	}
+	return polygons
+}
+
+// polygonBounds returns the pixel rectangle covering polygons.
+func polygonBounds(polygons [][][2]float64) image.Rectangle {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range polygons {
+		for _, q := range p {
+			minX, maxX = math.Min(minX, q[0]), math.Max(maxX, q[0])
+			minY, maxY = math.Min(minY, q[1]), math.Max(maxY, q[1])
+		}
+	}
+	if minX > maxX {
+		return image.Rectangle{}
+	}
+	// Clamp before converting, coordinates far off the page overflow int.
+	clamp := func(f float64) int { return int(math.Max(math.Min(f, 1<<20), -1<<20)) }
+	return image.Rect(clamp(math.Floor(minX)), clamp(math.Floor(minY)), clamp(math.Ceil(maxX)), clamp(math.Ceil(maxY)))
+}
+
+// unitSquare returns the unit square transformed by m.
+func unitSquare(m matrix) [][2]float64 {
+	var p [][2]float64
+	for _, c := range [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		x, y := m.apply(c[0], c[1])
+		p = append(p, [2]float64{x, y})
+	}
+	return p
+}
+
+// rectPolygon returns the corners of a pixel rectangle.
+func rectPolygon(r image.Rectangle) [][2]float64 {
+	x0, y0, x1, y1 := float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y)
+	return [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+}
+
+// colorSpace classifies the color space named by a cs or CS operand for
+// deviceColor: tint for Separation and DeviceN, other for spaces whose
+// components are not colors, such as Indexed and Pattern, and empty for
+// spaces whose components are read by their count.
+func (r *pageRenderer) colorSpace(resources types.Dict, operand string) string {
+	name := strings.TrimPrefix(operand, "/")
+	if name == "Pattern" {
+		return "other"
+	}
+	spaces, err := r.ctx.DereferenceDict(resources["ColorSpace"])
+	if err != nil || spaces == nil {
+		return ""
+	}
+	o, err := r.ctx.Dereference(spaces[name])
+	if err != nil {
+		return ""
+	}
+	switch o := o.(type) {
+	case types.Name:
+		return r.colorSpace(nil, o.Value())
+	case types.Array:
+		if len(o) == 0 {
+			return ""
+		}
+		family, _ := r.ctx.Dereference(o[0])
+		switch family {
+		case types.Name("Separation"), types.Name("DeviceN"):
+			return "tint"
+		case types.Name("Indexed"), types.Name("Pattern"):
+			return "other"
+		}
+	}
+	return ""
+}
+
+// deviceColor converts color components in a color space classified by
+// colorSpace to RGB. Tints darken toward black and colors of other spaces,
+// such as indexed colors and patterns, become a mid gray. Components of the
+// remaining spaces are read as gray, RGB or CMYK by their count.
+func deviceColor(space string, n []float64) color.RGBA {
+	c := func(f float64) uint8 { return uint8(math.Round(math.Max(0, math.Min(f, 1)) * 255)) }
+	switch {
+	case space == "tint" && len(n) > 0:
+		t := 0.0
+		for _, f := range n {
+			t = math.Max(t, f)
+		}
+		return color.RGBA{c(1 - t), c(1 - t), c(1 - t), 255}
+	case space == "other":
+	case len(n) == 1:
+		return color.RGBA{c(n[0]), c(n[0]), c(n[0]), 255}
+	case len(n) == 3:
+		return color.RGBA{c(n[0]), c(n[1]), c(n[2]), 255}
+	case len(n) == 4:
+		k := 1 - n[3]
+		return color.RGBA{c((1 - n[0]) * k), c((1 - n[1]) * k), c((1 - n[2]) * k), 255}
+	}
+	return color.RGBA{0x80, 0x80, 0x80, 255}
+}
+
+// fade returns c with constant alpha a applied, premultiplied as color.RGBA expects.
+func fade(c color.RGBA, a float64) color.RGBA {
+	if a >= 1 {
+		return c
+	}
+	a = math.Max(a, 0)
+	return color.RGBA{uint8(float64(c.R) * a), uint8(float64(c.G) * a), uint8(float64(c.B) * a), uint8(float64(c.A) * a)}
+}
+
+// setExtGState applies the constant alpha of the named graphics state
+// parameter dictionary to gs; blend modes and soft masks are ignored.
+func (r *pageRenderer) setExtGState(resources types.Dict, name string, gs *renderState) {
+	states, err := r.ctx.DereferenceDict(resources["ExtGState"])
+	if err != nil || states == nil {
+		return
+	}
+	d, err := r.ctx.DereferenceDict(states[name])
+	if err != nil || d == nil {
+		return
+	}
+	if ca, err := r.ctx.DereferenceNumber(d["ca"]); err == nil && d["ca"] != nil {
+		gs.fillAlpha = ca
+	}
+	if ca, err := r.ctx.DereferenceNumber(d["CA"]); err == nil && d["CA"] != nil {
+		gs.strokeAlpha = ca
+	}
+}
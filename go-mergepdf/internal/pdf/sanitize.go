@@ -0,0 +1,491 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// SanitizeOptions selects the categories of content removed by SanitizePDF.
+type SanitizeOptions struct {
+	JavaScript      bool `json:"javascript"`
+	EmbeddedFiles   bool `json:"embeddedFiles"`
+	HiddenLayers    bool `json:"hiddenLayers"`
+	Annotations     bool `json:"annotations"`
+	FormFields      bool `json:"formFields"`
+	DocumentActions bool `json:"documentActions"`
+	Metadata        bool `json:"metadata"`
+}
+
+// IsZero reports whether no category is selected.
+func (opts SanitizeOptions) IsZero() bool {
+	return opts == SanitizeOptions{}
+}
+
+// SanitizeReport lists what SanitizePDF removed.
+// Revisions counts the incremental updates that were discarded; the document
+// is always rewritten from scratch, so earlier revisions never survive.
+type SanitizeReport struct {
+	JavaScript      int            `json:"javascript"`
+	EmbeddedFiles   []string       `json:"embeddedFiles"`
+	HiddenLayers    []string       `json:"hiddenLayers"`
+	Annotations     map[string]int `json:"annotations"`
+	FormFields      int            `json:"formFields"`
+	DocumentActions int            `json:"documentActions"`
+	Metadata        bool           `json:"metadata"`
+	Revisions       int            `json:"revisions"`
+}
+
+// SanitizePDF removes the selected categories of active or hidden content from
+// the PDF at pdfPath and writes the result to outputPath.
+func SanitizePDF(pdfPath, outputPath string, opts SanitizeOptions) (*SanitizeReport, error) {
+	raw, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	config := model.NewDefaultConfiguration()
+	ctx, err := pdfapi.ReadAndValidate(bytes.NewReader(raw), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	report := &SanitizeReport{
+		EmbeddedFiles: []string{},
+		HiddenLayers:  []string{},
+		Annotations:   map[string]int{},
+		Revisions:     countRevisions(raw, ctx.Read.Linearized),
+	}
+
+	// Document actions go first so that JavaScript counts only what is left.
+	if opts.DocumentActions {
+		for _, key := range []string{"OpenAction", "AA"} {
+			if _, ok := ctx.RootDict.Find(key); ok {
+				ctx.RootDict.Delete(key)
+				report.DocumentActions++
+			}
+		}
+	}
+	if opts.HiddenLayers {
+		if report.HiddenLayers, err = removeHiddenLayers(ctx); err != nil {
+			return nil, fmt.Errorf("failed to remove hidden layers: %w", err)
+		}
+	}
+	if opts.EmbeddedFiles {
+		if report.EmbeddedFiles, err = removeEmbeddedFiles(ctx); err != nil {
+			return nil, fmt.Errorf("failed to remove embedded files: %w", err)
+		}
+	}
+	if opts.FormFields {
+		if report.FormFields, err = removeFormFields(ctx); err != nil {
+			return nil, fmt.Errorf("failed to remove form fields: %w", err)
+		}
+	}
+	if opts.Annotations || opts.EmbeddedFiles || opts.FormFields {
+		if err := removeAnnotations(ctx, opts, report.Annotations); err != nil {
+			return nil, fmt.Errorf("failed to remove annotations: %w", err)
+		}
+	}
+	if opts.JavaScript {
+		if report.JavaScript, err = removeJavaScript(ctx); err != nil {
+			return nil, fmt.Errorf("failed to remove JavaScript: %w", err)
+		}
+	}
+	if opts.Metadata {
+		if err := stripMetadata(ctx); err != nil {
+			return nil, fmt.Errorf("failed to strip metadata: %w", err)
+		}
+		ctx.RootDict.Delete("PieceInfo")
+		report.Metadata = true
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return report, nil
+}
+
+// countRevisions returns the number of incremental updates in a raw PDF file.
+// A linearized file carries one extra xref section that is not a revision.
+func countRevisions(raw []byte, linearized bool) int {
+	n := bytes.Count(raw, []byte("startxref")) - 1
+	if linearized {
+		n--
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// isJavaScriptAction reports whether o resolves to a JavaScript action dictionary.
+func isJavaScriptAction(ctx *model.Context, o types.Object) bool {
+	d, err := ctx.DereferenceDict(o)
+	if err != nil || d == nil {
+		return false
+	}
+	s := d.NameEntry("S")
+	return s != nil && *s == "JavaScript"
+}
+
+// removeJavaScript drops the document JavaScript name tree and every action
+// that would run a script, returning the number of scripts removed.
+func removeJavaScript(ctx *model.Context) (int, error) {
+	count := 0
+
+	if err := ctx.LocateNameTree("JavaScript", false); err != nil {
+		return 0, err
+	}
+	if ctx.Names["JavaScript"] != nil {
+		if err := ctx.Names["JavaScript"].Process(ctx.XRefTable, func(*model.XRefTable, string, *types.Object) error {
+			count++
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+		delete(ctx.Names, "JavaScript")
+		if err := ctx.RemoveNameTree("JavaScript"); err != nil {
+			return 0, err
+		}
+	}
+
+	// Every action lives either in an indirect object or directly inside one,
+	// so walking the object table reaches all of them.
+	var walk func(o types.Object)
+	walk = func(o types.Object) {
+		switch o := o.(type) {
+		case types.Dict:
+			count += removeJavaScriptEntries(ctx, o)
+			for _, v := range o {
+				walk(v)
+			}
+		case types.StreamDict:
+			walk(o.Dict)
+		case types.Array:
+			for _, v := range o {
+				walk(v)
+			}
+		}
+	}
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		walk(entry.Object)
+	}
+	return count, nil
+}
+
+// removeJavaScriptEntries removes action entries of d that run JavaScript.
+func removeJavaScriptEntries(ctx *model.Context, d types.Dict) int {
+	count := 0
+	for _, key := range []string{"A", "OpenAction", "Next"} {
+		if o, ok := d.Find(key); ok && isJavaScriptAction(ctx, o) {
+			d.Delete(key)
+			count++
+		}
+	}
+	if o, ok := d.Find("AA"); ok {
+		aa, err := ctx.DereferenceDict(o)
+		if err == nil && aa != nil {
+			for trigger, action := range aa {
+				if isJavaScriptAction(ctx, action) {
+					aa.Delete(trigger)
+					count++
+				}
+			}
+			if aa.Len() == 0 {
+				d.Delete("AA")
+			}
+		}
+	}
+	return count
+}
+
+// removeEmbeddedFiles drops the embedded files name tree and portfolio
+// collection, returning the names of the removed files.
+func removeEmbeddedFiles(ctx *model.Context) ([]string, error) {
+	if err := ctx.LocateNameTree("EmbeddedFiles", false); err != nil {
+		return nil, err
+	}
+	attachments, err := ctx.ListAttachments()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, a := range attachments {
+		names = append(names, a.FileName)
+	}
+	if ctx.Names["EmbeddedFiles"] != nil {
+		if err := ctx.RemoveEmbeddedFilesNameTree(); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// removeFormFields drops the AcroForm dictionary, returning the number of
+// terminal fields it held. Widget annotations are removed by removeAnnotations.
+func removeFormFields(ctx *model.Context) (int, error) {
+	o, ok := ctx.RootDict.Find("AcroForm")
+	if !ok {
+		return 0, nil
+	}
+	acroForm, err := ctx.DereferenceDict(o)
+	if err != nil {
+		return 0, err
+	}
+
+	var count func(o types.Object) int
+	count = func(o types.Object) int {
+		a, err := ctx.DereferenceArray(o)
+		if err != nil {
+			return 0
+		}
+		n := 0
+		for _, f := range a {
+			d, err := ctx.DereferenceDict(f)
+			if err != nil || d == nil {
+				continue
+			}
+			if kids, ok := d.Find("Kids"); ok && hasFieldKids(ctx, kids) {
+				n += count(kids)
+				continue
+			}
+			n++
+		}
+		return n
+	}
+
+	n := 0
+	if acroForm != nil {
+		if fields, ok := acroForm.Find("Fields"); ok {
+			n = count(fields)
+		}
+	}
+	ctx.RootDict.Delete("AcroForm")
+	return n, nil
+}
+
+// hasFieldKids reports whether kids holds child fields rather than only widgets.
+func hasFieldKids(ctx *model.Context, kids types.Object) bool {
+	a, err := ctx.DereferenceArray(kids)
+	if err != nil {
+		return false
+	}
+	for _, k := range a {
+		d, err := ctx.DereferenceDict(k)
+		if err == nil && d != nil && d.StringEntry("T") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// removeAnnotations drops page annotations selected by opts and tallies them
+// by subtype in removed. Widgets go with form fields, file attachment
+// annotations with embedded files, and everything else with annotations.
+func removeAnnotations(ctx *model.Context, opts SanitizeOptions, removed map[string]int) error {
+	for i := 1; i <= ctx.PageCount; i++ {
+		d, _, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			continue
+		}
+		o, ok := d.Find("Annots")
+		if !ok {
+			continue
+		}
+		annots, err := ctx.DereferenceArray(o)
+		if err != nil {
+			return err
+		}
+
+		kept := types.Array{}
+		for _, a := range annots {
+			ad, err := ctx.DereferenceDict(a)
+			if err != nil || ad == nil {
+				continue
+			}
+			subtype := "Unknown"
+			if s := ad.NameEntry("Subtype"); s != nil {
+				subtype = *s
+			}
+			var drop bool
+			switch subtype {
+			case "Widget":
+				drop = opts.FormFields
+			case "FileAttachment":
+				drop = opts.EmbeddedFiles || opts.Annotations
+			default:
+				drop = opts.Annotations
+			}
+			if drop {
+				removed[subtype]++
+				continue
+			}
+			kept = append(kept, a)
+		}
+
+		if len(kept) == 0 {
+			d.Delete("Annots")
+		} else {
+			d.Update("Annots", kept)
+		}
+	}
+	return nil
+}
+
+// removeHiddenLayers deletes content belonging to optional content groups
+// that are off by default and then drops the optional content configuration,
+// leaving the remaining layers as plain content. It returns the names of the
+// hidden layers.
+func removeHiddenLayers(ctx *model.Context) ([]string, error) {
+	o, ok := ctx.RootDict.Find("OCProperties")
+	if !ok {
+		return []string{}, nil
+	}
+	ocProps, err := ctx.DereferenceDict(o)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := map[int]bool{}
+	names := []string{}
+	if ocProps != nil {
+		if def, err := ctx.DereferenceDict(ocProps["D"]); err == nil && def != nil {
+			if off, err := ctx.DereferenceArray(def["OFF"]); err == nil {
+				for _, g := range off {
+					ir, ok := g.(types.IndirectRef)
+					if !ok {
+						continue
+					}
+					hidden[ir.ObjectNumber.Value()] = true
+					if gd, err := ctx.DereferenceDict(ir); err == nil && gd != nil {
+						if name, err := ctx.DereferenceText(gd["Name"]); err == nil {
+							names = append(names, name)
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+
+	isHidden := func(o types.Object) bool {
+		ir, ok := o.(types.IndirectRef)
+		if !ok {
+			return false
+		}
+		if hidden[ir.ObjectNumber.Value()] {
+			return true
+		}
+		// Membership dictionaries are hidden when all of their groups are.
+		d, err := ctx.DereferenceDict(ir)
+		if err != nil || d == nil || d.NameEntry("Type") == nil || *d.NameEntry("Type") != "OCMD" {
+			return false
+		}
+		ocgs, err := ctx.Dereference(d["OCGs"])
+		if err != nil {
+			return false
+		}
+		switch ocgs := ocgs.(type) {
+		case types.Array:
+			for _, g := range ocgs {
+				if gr, ok := g.(types.IndirectRef); !ok || !hidden[gr.ObjectNumber.Value()] {
+					return false
+				}
+			}
+			return len(ocgs) > 0
+		}
+		if gr, ok := d["OCGs"].(types.IndirectRef); ok {
+			return hidden[gr.ObjectNumber.Value()]
+		}
+		return false
+	}
+
+	if len(hidden) > 0 {
+		for i := 1; i <= ctx.PageCount; i++ {
+			if err := removeHiddenContent(ctx, i, isHidden); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ctx.RootDict.Delete("OCProperties")
+	return names, nil
+}
+
+// removeHiddenContent cuts marked content sections and XObject invocations
+// bound to hidden optional content from a page.
+func removeHiddenContent(ctx *model.Context, pageNr int, isHidden func(types.Object) bool) error {
+	d, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil || d == nil {
+		return err
+	}
+	resources, err := ctx.DereferenceDict(d["Resources"])
+	if err != nil {
+		return err
+	}
+	if resources == nil && inh != nil {
+		resources = inh.Resources
+	}
+	if resources == nil {
+		return nil
+	}
+
+	properties, _ := ctx.DereferenceDict(resources["Properties"])
+	xobjects, _ := ctx.DereferenceDict(resources["XObject"])
+
+	hiddenXObjects := map[string]bool{}
+	for name, o := range xobjects {
+		sd, _, err := ctx.DereferenceStreamDict(o)
+		if err != nil || sd == nil {
+			continue
+		}
+		if oc, ok := sd.Find("OC"); ok && isHidden(oc) {
+			hiddenXObjects["/"+name] = true
+		}
+	}
+
+	content, err := pageContent(ctx, d)
+	if err != nil || content == nil {
+		return err
+	}
+
+	var cuts [][2]int
+	depth, hiddenDepth, hiddenStart := 0, -1, 0
+	for _, op := range parseContent(content) {
+		switch op.Name {
+		case "BMC", "BDC":
+			depth++
+			if hiddenDepth < 0 && op.Name == "BDC" && len(op.Operands) == 2 && op.Operands[0] == "/OC" && strings.HasPrefix(op.Operands[1], "/") {
+				if o, ok := properties[op.Operands[1][1:]]; ok && isHidden(o) {
+					hiddenDepth, hiddenStart = depth, op.Start
+				}
+			}
+		case "EMC":
+			if depth == hiddenDepth {
+				cuts = append(cuts, [2]int{hiddenStart, op.End})
+				hiddenDepth = -1
+			}
+			depth--
+		case "Do":
+			if hiddenDepth < 0 && len(op.Operands) == 1 && hiddenXObjects[op.Operands[0]] {
+				cuts = append(cuts, [2]int{op.Start, op.End})
+			}
+		}
+	}
+	if len(cuts) == 0 {
+		return nil
+	}
+	return setPageContent(ctx, d, cutRanges(content, cuts))
+}
@@ -0,0 +1,292 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrInvalidSplit is returned for split options that cannot be applied.
+var ErrInvalidSplit = errors.New("invalid split options")
+
+// minSplitBytes is the smallest byte budget accepted; no useful part fits
+// in less.
+const minSplitBytes = 10 * 1024
+
+// SplitOptions describes the parts SplitPDF cuts a document into. Each part
+// has at most MaxPages pages and MaxBytes bytes; zero means no limit, but at
+// least one limit must be set. With KeepFilesTogether parts only end where
+// a source document ends.
+type SplitOptions struct {
+	MaxPages          int   `json:"maxPages,omitempty"`
+	MaxBytes          int64 `json:"maxBytes,omitempty"`
+	KeepFilesTogether bool  `json:"keepFilesTogether,omitempty"`
+}
+
+// Validate reports whether opts describe a usable split.
+func (opts SplitOptions) Validate() error {
+	if opts.MaxPages < 0 || opts.MaxBytes < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidSplit)
+	}
+	if opts.MaxPages == 0 && opts.MaxBytes == 0 {
+		return fmt.Errorf("%w: maxPages or maxBytes is required", ErrInvalidSplit)
+	}
+	if opts.MaxBytes != 0 && opts.MaxBytes < minSplitBytes {
+		return fmt.Errorf("%w: maxBytes must be at least %d", ErrInvalidSplit, minSplitBytes)
+	}
+	return nil
+}
+
+// SplitPart describes a part written by SplitPDF. Oversized parts hold a
+// single page, or a single source document with KeepFilesTogether, that
+// exceeds the limits on its own.
+type SplitPart struct {
+	Name      string `json:"name"`
+	FirstPage int    `json:"firstPage"`
+	LastPage  int    `json:"lastPage"`
+	Size      int64  `json:"size"`
+	Oversized bool   `json:"oversized,omitempty"`
+}
+
+// SplitPDF cuts the PDF at pdfPath into consecutive parts within the limits
+// of opts and writes them to a ZIP archive at zipPath, named
+// <name>-part<i>-of-<n>.pdf. sources are the files the PDF was merged from,
+// in order; pages in front of the first of them, such as contents pages,
+// belong to the first. finish, if not nil, is applied to every part before
+// it is measured, e.g. to convert it to PDF/A.
+func SplitPDF(pdfPath, zipPath, name string, sources []string, opts SplitOptions, finish func(path string) error) ([]SplitPart, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	src, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	ctx, err := pdfapi.ReadAndValidate(bytes.NewReader(src), model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	pageCount := ctx.PageCount
+
+	// Collecting pages drops the Info dictionary, so parts get a copy.
+	entries, err := readInfoEntries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	info := map[string]string{}
+	for k, v := range entries {
+		if t, ok := v.(time.Time); ok {
+			info[k] = types.DateString(t)
+		} else {
+			info[k] = v.(string)
+		}
+	}
+
+	// units are the first pages of the pieces parts are made of.
+	units := make([]int, 0, pageCount)
+	if opts.KeepFilesTogether {
+		if units, err = sourceStarts(sources, pageCount); err != nil {
+			return nil, err
+		}
+	} else {
+		for p := 1; p <= pageCount; p++ {
+			units = append(units, p)
+		}
+	}
+
+	// Parts keep the labels of their pages.
+	labels, err := ReadPageLabels(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(zipPath), "split-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	s := &splitter{src: src, dir: dir, units: units, pageCount: pageCount, labels: labels, info: info, finish: finish}
+	var parts []SplitPart
+	var files []string
+	for i := 0; i < len(units); {
+		n, path, size, err := s.next(i, opts)
+		if err != nil {
+			return nil, err
+		}
+		first, last := s.pages(i, n)
+		oversized := opts.MaxBytes > 0 && size > opts.MaxBytes || opts.MaxPages > 0 && last-first+1 > opts.MaxPages
+		parts = append(parts, SplitPart{FirstPage: first, LastPage: last, Size: size, Oversized: oversized})
+		files = append(files, path)
+		i += n
+	}
+
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	for i, path := range files {
+		parts[i].Name = fmt.Sprintf("%s-part%d-of-%d.pdf", name, i+1, len(parts))
+		named := filepath.Join(dir, parts[i].Name)
+		if err := os.Rename(path, named); err != nil {
+			return nil, err
+		}
+		if err := addZipFile(zw, named); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return parts, out.Close()
+}
+
+// sourceStarts returns the pages where each source document begins in a
+// merged document of pageCount pages.
+func sourceStarts(sources []string, pageCount int) ([]int, error) {
+	counts := make([]int, len(sources))
+	total := 0
+	for i, source := range sources {
+		n, err := pdfapi.PageCountFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source PDF: %w", err)
+		}
+		counts[i] = n
+		total += n
+	}
+	if total > pageCount {
+		return nil, fmt.Errorf("%w: the sources have more pages than the document", ErrInvalidSplit)
+	}
+
+	starts := []int{1}
+	page := 1 + pageCount - total
+	for _, n := range counts[:max(len(counts)-1, 0)] {
+		page += n
+		if page > starts[len(starts)-1] {
+			starts = append(starts, page)
+		}
+	}
+	return starts, nil
+}
+
+// splitter finds the parts of a document.
+type splitter struct {
+	src       []byte
+	dir       string
+	units     []int
+	pageCount int
+	labels    PageLabels
+	info      map[string]string // Info entries of the source
+	finish    func(path string) error
+	written   int
+}
+
+// pages returns the first and last page of n units from unit i.
+func (s *splitter) pages(i, n int) (int, int) {
+	last := s.pageCount
+	if i+n < len(s.units) {
+		last = s.units[i+n] - 1
+	}
+	return s.units[i], last
+}
+
+// next returns the number of units in the part starting at unit i, the
+// file holding it and its size.
+func (s *splitter) next(i int, opts SplitOptions) (int, string, int64, error) {
+	limit := 1
+	for i+limit < len(s.units) {
+		first, last := s.pages(i, limit+1)
+		if opts.MaxPages > 0 && last-first+1 > opts.MaxPages {
+			break
+		}
+		limit++
+	}
+	if opts.MaxBytes == 0 {
+		path, size, err := s.write(i, limit)
+		return limit, path, size, err
+	}
+
+	// A first unit over the budget becomes a part by itself. Otherwise the
+	// part grows exponentially while it fits, then a binary search finds the
+	// largest that does.
+	path, size, err := s.write(i, 1)
+	if err != nil || size > opts.MaxBytes {
+		return 1, path, size, err
+	}
+	lo, hi := 1, limit+1 // lo units fit, hi do not
+	grow := true
+	for hi-lo > 1 {
+		n := (lo + hi) / 2
+		if grow {
+			n = min(lo*2, hi-1)
+		}
+		candidate, candidateSize, err := s.write(i, n)
+		if err != nil {
+			return 0, "", 0, err
+		}
+		if candidateSize <= opts.MaxBytes {
+			os.Remove(path)
+			lo, path, size = n, candidate, candidateSize
+		} else {
+			os.Remove(candidate)
+			hi, grow = n, false
+		}
+	}
+	return lo, path, size, nil
+}
+
+// write collects n units from unit i into a new file, applies finish and
+// returns the file and its size.
+func (s *splitter) write(i, n int) (string, int64, error) {
+	first, last := s.pages(i, n)
+	s.written++
+	path := filepath.Join(s.dir, fmt.Sprintf("candidate-%d.pdf", s.written))
+
+	var buf bytes.Buffer
+	selection := []string{fmt.Sprintf("%d-%d", first, last)}
+	if err := pdfapi.Collect(bytes.NewReader(s.src), &buf, selection, model.NewDefaultConfiguration()); err != nil {
+		return "", 0, fmt.Errorf("failed to collect pages %d-%d: %w", first, last, err)
+	}
+	if len(s.labels) == 0 {
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return "", 0, err
+		}
+	} else {
+		ctx, err := pdfapi.ReadAndValidate(bytes.NewReader(buf.Bytes()), model.NewDefaultConfiguration())
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read part: %w", err)
+		}
+		if err := setPageLabels(ctx, s.labels.Slice(first, last)); err != nil {
+			return "", 0, err
+		}
+		if err := writeContextFile(ctx, path); err != nil {
+			return "", 0, fmt.Errorf("failed to write part: %w", err)
+		}
+	}
+	if len(s.info) > 0 {
+		if err := writeInfoIncrement(path, s.info, nil, true); err != nil {
+			return "", 0, fmt.Errorf("failed to write metadata: %w", err)
+		}
+	}
+	if s.finish != nil {
+		if err := s.finish(path); err != nil {
+			return "", 0, err
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
@@ -0,0 +1,274 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// PageText is the text of one page. NoText is set for pages without
+// extractable text, such as scans that need OCR.
+type PageText struct {
+	Page   int    `json:"page"`
+	Text   string `json:"text"`
+	NoText bool   `json:"noText"`
+}
+
+// ExtractText returns the text of every page of the PDF at pdfPath in content
+// stream order, which matches the reading order of most documents. Spaces and
+// line breaks are inferred from the positions of the text runs. Characters
+// of fonts without a ToUnicode map or a known encoding are left out.
+func ExtractText(pdfPath string) ([]PageText, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	pages := make([]PageText, 0, ctx.PageCount)
+	fonts := map[types.IndirectRef]*textFont{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		pageDict, _, inh, err := ctx.PageDict(i, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", i, err)
+		}
+		text := ""
+		if pageDict != nil {
+			if text, err = pageText(ctx, pageDict, inh, fonts); err != nil {
+				return nil, fmt.Errorf("failed to read page %d: %w", i, err)
+			}
+		}
+		pages = append(pages, PageText{Page: i, Text: text, NoText: strings.TrimSpace(text) == ""})
+	}
+	return pages, nil
+}
+
+// textState is the part of the graphics state that affects text placement.
+type textState struct {
+	ctm       matrix
+	font      *textFont
+	fontSize  float64
+	charSpace float64
+	wordSpace float64
+	hScale    float64
+	leading   float64
+	rise      float64
+}
+
+// textWriter assembles the runs of text shown on a page.
+type textWriter struct {
+	ctx   *model.Context
+	fonts map[types.IndirectRef]*textFont
+	b     strings.Builder
+
+	started    bool
+	lastSpace  bool    // Whether the last run ended with white space
+	endX, endY float64 // Device space position after the last run
+	lineHeight float64 // Device space font size of the last run
+}
+
+func pageText(ctx *model.Context, pageDict types.Dict, inh *model.InheritedPageAttrs, fonts map[types.IndirectRef]*textFont) (string, error) {
+	content, err := pageContent(ctx, pageDict)
+	if err != nil || content == nil {
+		return "", err
+	}
+	resources, err := ctx.DereferenceDict(pageDict["Resources"])
+	if err != nil {
+		return "", err
+	}
+	if resources == nil && inh != nil {
+		resources = inh.Resources
+	}
+
+	tw := &textWriter{ctx: ctx, fonts: fonts}
+	tw.scan(content, resources, textState{ctm: identityMatrix, hScale: 1}, 0)
+	return tw.b.String(), nil
+}
+
+// font returns the named font of resources, loading each font once per document.
+func (tw *textWriter) font(resources types.Dict, name string) *textFont {
+	fontDicts, err := tw.ctx.DereferenceDict(resources["Font"])
+	if err != nil || fontDicts == nil {
+		return nil
+	}
+	o := fontDicts[name]
+	ir, isRef := o.(types.IndirectRef)
+	if isRef {
+		if f, ok := tw.fonts[ir]; ok {
+			return f
+		}
+	}
+	d, err := tw.ctx.DereferenceDict(o)
+	if err != nil || d == nil {
+		return nil
+	}
+	f := loadTextFont(tw.ctx, d)
+	if isRef {
+		tw.fonts[ir] = f
+	}
+	return f
+}
+
+// scan walks a content stream, writing the text it shows.
+func (tw *textWriter) scan(content []byte, resources types.Dict, ts textState, depth int) {
+	var stack []textState
+	var tm, tlm matrix
+
+	nextLine := func(tx, ty float64) {
+		tlm = matrix{1, 0, 0, 1, tx, ty}.multiply(tlm)
+		tm = tlm
+	}
+	show := func(tok string) {
+		if ts.font == nil {
+			return
+		}
+		trm := matrix{ts.fontSize * ts.hScale, 0, 0, ts.fontSize, 0, ts.rise}.multiply(tm).multiply(ts.ctm)
+		x, y := trm.apply(0, 0)
+		var run strings.Builder
+		ts.font.decode(stringBytes(tok), func(text string, width float64, space bool) {
+			run.WriteString(text)
+			advance := width/1000*ts.fontSize + ts.charSpace
+			if space {
+				advance += ts.wordSpace
+			}
+			tm = matrix{1, 0, 0, 1, advance * ts.hScale, 0}.multiply(tm)
+		})
+		ex, ey := matrix{ts.fontSize * ts.hScale, 0, 0, ts.fontSize, 0, ts.rise}.multiply(tm).multiply(ts.ctm).apply(0, 0)
+		tw.write(run.String(), x, y, ex, ey, math.Hypot(trm[2], trm[3]))
+	}
+
+	for _, op := range parseContent(content) {
+		n := numbers(op.Operands)
+		switch op.Name {
+		case "q":
+			stack = append(stack, ts)
+		case "Q":
+			if len(stack) > 0 {
+				ts, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(n) == 6 {
+				ts.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.multiply(ts.ctm)
+			}
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(op.Operands) == 2 && len(n) == 1 {
+				ts.font = tw.font(resources, strings.TrimPrefix(op.Operands[0], "/"))
+				ts.fontSize = n[0]
+			}
+		case "Tc":
+			if len(n) == 1 {
+				ts.charSpace = n[0]
+			}
+		case "Tw":
+			if len(n) == 1 {
+				ts.wordSpace = n[0]
+			}
+		case "Tz":
+			if len(n) == 1 {
+				ts.hScale = n[0] / 100
+			}
+		case "TL":
+			if len(n) == 1 {
+				ts.leading = n[0]
+			}
+		case "Ts":
+			if len(n) == 1 {
+				ts.rise = n[0]
+			}
+		case "Td", "TD":
+			if len(n) == 2 {
+				if op.Name == "TD" {
+					ts.leading = -n[1]
+				}
+				nextLine(n[0], n[1])
+			}
+		case "Tm":
+			if len(n) == 6 {
+				tlm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -ts.leading)
+		case "Tj", "'", "\"":
+			if op.Name != "Tj" {
+				nextLine(0, -ts.leading)
+			}
+			if op.Name == "\"" && len(n) >= 2 {
+				ts.wordSpace, ts.charSpace = n[0], n[1]
+			}
+			if len(op.Operands) > 0 {
+				show(op.Operands[len(op.Operands)-1])
+			}
+		case "TJ":
+			if len(op.Operands) == 1 {
+				l := &contentLexer{b: []byte(strings.TrimSuffix(strings.TrimPrefix(op.Operands[0], "["), "]"))}
+				for {
+					tok, _, ok := l.next()
+					if !ok {
+						break
+					}
+					if f := numbers([]string{tok}); len(f) == 1 {
+						tm = matrix{1, 0, 0, 1, -f[0] / 1000 * ts.fontSize * ts.hScale, 0}.multiply(tm)
+						continue
+					}
+					show(tok)
+				}
+			}
+		case "Do":
+			if len(op.Operands) == 1 && depth < maxFormDepth {
+				tw.scanForm(strings.TrimPrefix(op.Operands[0], "/"), resources, ts, depth)
+			}
+		}
+	}
+}
+
+// scanForm writes the text of the named form XObject.
+func (tw *textWriter) scanForm(name string, resources types.Dict, ts textState, depth int) {
+	xobjects, err := tw.ctx.DereferenceDict(resources["XObject"])
+	if err != nil || xobjects == nil {
+		return
+	}
+	sd, _, err := tw.ctx.DereferenceStreamDict(xobjects[name])
+	if err != nil || sd == nil {
+		return
+	}
+	if subtype := sd.Dict.NameEntry("Subtype"); subtype == nil || *subtype != "Form" {
+		return
+	}
+	if err := sd.Decode(); err != nil {
+		return
+	}
+	formResources, err := tw.ctx.DereferenceDict(sd.Dict["Resources"])
+	if err != nil || formResources == nil {
+		formResources = resources
+	}
+	ts.ctm = matrixEntry(tw.ctx, sd.Dict, "Matrix").multiply(ts.ctm)
+	tw.scan(sd.Content, formResources, ts, depth+1)
+}
+
+// write appends a run of text shown from (x, y) to (endX, endY) in device
+// space, separating it from the previous run by a line break when it starts
+// on another line and by a space when there is a gap.
+func (tw *textWriter) write(text string, x, y, endX, endY, height float64) {
+	if text == "" {
+		return
+	}
+	if tw.started {
+		h := math.Max(math.Min(height, tw.lineHeight), 1)
+		switch {
+		case math.Abs(y-tw.endY) > 0.5*h:
+			tw.b.WriteString("\n")
+		case math.Abs(x-tw.endX) > 0.15*h && !tw.lastSpace && !strings.HasPrefix(text, " "):
+			tw.b.WriteString(" ")
+		}
+	}
+	tw.b.WriteString(text)
+	tw.started = true
+	tw.lastSpace = strings.HasSuffix(text, " ")
+	tw.endX, tw.endY, tw.lineHeight = endX, endY, height
+}
@@ -0,0 +1,437 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// textFont maps the character codes of a font to text and glyph widths.
+type textFont struct {
+	codeBytes    int               // 1 for simple fonts, usually 2 for composite fonts
+	toUnicode    map[string]string // From the ToUnicode CMap, keyed by raw code
+	encoding     *[256]rune        // Simple fonts without a ToUnicode entry for a code
+	widths       map[int]float64   // Glyph widths in thousandths of text space
+	defaultWidth float64
+}
+
+// loadTextFont reads the parts of a font dictionary needed to extract text.
+func loadTextFont(ctx *model.Context, d types.Dict) *textFont {
+	f := &textFont{codeBytes: 1, widths: map[int]float64{}, defaultWidth: 500}
+
+	subtype := d.NameEntry("Subtype")
+	if subtype != nil && *subtype == "Type0" {
+		f.codeBytes = 2
+		f.defaultWidth = 1000
+		if descendants, err := ctx.DereferenceArray(d["DescendantFonts"]); err == nil && len(descendants) > 0 {
+			if cid, err := ctx.DereferenceDict(descendants[0]); err == nil && cid != nil {
+				f.loadCIDWidths(ctx, cid)
+			}
+		}
+	} else {
+		f.loadSimpleWidths(ctx, d)
+		f.encoding = simpleEncoding(ctx, d)
+	}
+
+	if sd, _, err := ctx.DereferenceStreamDict(d["ToUnicode"]); err == nil && sd != nil {
+		if err := sd.Decode(); err == nil {
+			f.toUnicode = parseToUnicode(sd.Content, f)
+		}
+	}
+	return f
+}
+
+func (f *textFont) loadSimpleWidths(ctx *model.Context, d types.Dict) {
+	first, _ := ctx.DereferenceNumber(d["FirstChar"])
+	widths, _ := ctx.DereferenceArray(d["Widths"])
+	for i, o := range widths {
+		if w, err := ctx.DereferenceNumber(o); err == nil {
+			f.widths[int(first)+i] = w
+		}
+	}
+	if fd, err := ctx.DereferenceDict(d["FontDescriptor"]); err == nil && fd != nil {
+		if w, err := ctx.DereferenceNumber(fd["MissingWidth"]); err == nil && w > 0 {
+			f.defaultWidth = w
+		}
+	}
+}
+
+// loadCIDWidths reads the W array of a CID font: "c [w1 w2 ...]" or "cFirst cLast w".
+func (f *textFont) loadCIDWidths(ctx *model.Context, d types.Dict) {
+	if dw, err := ctx.DereferenceNumber(d["DW"]); err == nil && dw > 0 {
+		f.defaultWidth = dw
+	}
+	w, _ := ctx.DereferenceArray(d["W"])
+	for i := 0; i+1 < len(w); {
+		first, err := ctx.DereferenceNumber(w[i])
+		if err != nil {
+			return
+		}
+		if a, err := ctx.DereferenceArray(w[i+1]); err == nil && a != nil {
+			for j, o := range a {
+				if v, err := ctx.DereferenceNumber(o); err == nil {
+					f.widths[int(first)+j] = v
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, err1 := ctx.DereferenceNumber(w[i+1])
+		v, err2 := ctx.DereferenceNumber(w[i+2])
+		if err1 != nil || err2 != nil || last-first > 0xFFFF {
+			return
+		}
+		for c := int(first); c <= int(last); c++ {
+			f.widths[c] = v
+		}
+		i += 3
+	}
+}
+
+// decode splits s into character codes and calls glyph with the text and
+// width of each, in thousandths of text space, and whether the code is a
+// single-byte space, which word spacing applies to.
+func (f *textFont) decode(s []byte, glyph func(text string, width float64, space bool)) {
+	f.codes(s, func(_ []byte, text string, width float64, space bool) {
+		glyph(text, width, space)
+	})
+}
+
+// codes is like decode but also passes the raw bytes of each code.
+func (f *textFont) codes(s []byte, glyph func(code []byte, text string, width float64, space bool)) {
+	n := f.codeBytes
+	for i := 0; i < len(s); i += n {
+		if i+n > len(s) {
+			n = len(s) - i
+		}
+		code := s[i : i+n]
+		c := 0
+		for _, b := range code {
+			c = c<<8 | int(b)
+		}
+
+		text, ok := f.toUnicode[string(code)]
+		if !ok && f.encoding != nil && len(code) == 1 {
+			if r := f.encoding[code[0]]; r != 0 {
+				text = string(r)
+			}
+		}
+		w, ok := f.widths[c]
+		if !ok {
+			w = f.defaultWidth
+		}
+		glyph(code, text, w, len(code) == 1 && code[0] == ' ')
+	}
+}
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap.
+// The code length is taken from the first codespace range.
+func parseToUnicode(b []byte, f *textFont) map[string]string {
+	m := map[string]string{}
+	l := &contentLexer{b: b}
+	var operands []string
+	section, codespaceSeen := "", false
+	for {
+		tok, _, ok := l.next()
+		if !ok {
+			break
+		}
+		switch tok {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = tok
+			operands = nil
+			continue
+		case "endcodespacerange", "endbfchar", "endbfrange":
+			section = ""
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		operands = append(operands, tok)
+
+		switch section {
+		case "begincodespacerange":
+			if len(operands) == 2 {
+				if lo := hexBytes(operands[0]); len(lo) > 0 && !codespaceSeen {
+					f.codeBytes = len(lo)
+					codespaceSeen = true
+				}
+				operands = nil
+			}
+		case "beginbfchar":
+			if len(operands) == 2 {
+				m[string(hexBytes(operands[0]))] = utf16Text(hexBytes(operands[1]))
+				operands = nil
+			}
+		case "beginbfrange":
+			if len(operands) == 3 {
+				addBFRange(m, hexBytes(operands[0]), hexBytes(operands[1]), operands[2])
+				operands = nil
+			}
+		}
+	}
+	return m
+}
+
+// addBFRange maps the codes lo to hi, either to consecutive characters or to
+// the entries of an array of strings.
+func addBFRange(m map[string]string, lo, hi []byte, dst string) {
+	if len(lo) == 0 || len(lo) != len(hi) {
+		return
+	}
+	start, end := bytesToInt(lo), bytesToInt(hi)
+	if end < start || end-start > 0xFFFF {
+		return
+	}
+
+	var targets []string
+	if strings.HasPrefix(dst, "[") {
+		al := &contentLexer{b: []byte(strings.Trim(dst, "[]"))}
+		for {
+			tok, _, ok := al.next()
+			if !ok {
+				break
+			}
+			targets = append(targets, utf16Text(hexBytes(tok)))
+		}
+	}
+	base := hexBytes(dst)
+
+	for c := start; c <= end; c++ {
+		code := intToBytes(c, len(lo))
+		switch {
+		case targets != nil:
+			if c-start < len(targets) {
+				m[string(code)] = targets[c-start]
+			}
+		case len(base) >= 2:
+			// The last byte of the destination is incremented along the range.
+			d := append([]byte(nil), base...)
+			v := (int(d[len(d)-2])<<8 | int(d[len(d)-1])) + c - start
+			d[len(d)-2], d[len(d)-1] = byte(v>>8), byte(v)
+			m[string(code)] = utf16Text(d)
+		}
+	}
+}
+
+func hexBytes(tok string) []byte {
+	if !strings.HasPrefix(tok, "<") {
+		return nil
+	}
+	s := strings.Map(func(r rune) rune {
+		if isWhitespace(byte(r)) {
+			return -1
+		}
+		return r
+	}, strings.Trim(tok, "<>"))
+	if len(s)%2 == 1 {
+		s += "0"
+	}
+	b, _ := hex.DecodeString(s)
+	return b
+}
+
+func bytesToInt(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+	return n
+}
+
+func intToBytes(n, size int) []byte {
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
+
+// utf16Text decodes UTF-16BE text as used by ToUnicode CMaps.
+func utf16Text(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(u))
+}
+
+// stringBytes returns the bytes encoded by a literal or hex string token.
+func stringBytes(tok string) []byte {
+	if strings.HasPrefix(tok, "<") {
+		return hexBytes(tok)
+	}
+	if len(tok) < 2 || tok[0] != '(' {
+		return nil
+	}
+	body := tok[1 : len(tok)-1]
+	var b bytes.Buffer
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' || i+1 == len(body) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = body[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '\r', '\n':
+			// Line continuation.
+			if c == '\r' && i+1 < len(body) && body[i+1] == '\n' {
+				i++
+			}
+		default:
+			if c >= '0' && c <= '7' {
+				j := i
+				for j < len(body) && j < i+3 && body[j] >= '0' && body[j] <= '7' {
+					j++
+				}
+				v, _ := strconv.ParseUint(body[i:j], 8, 8)
+				b.WriteByte(byte(v))
+				i = j - 1
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.Bytes()
+}
+
+// simpleEncoding builds the code to character table of a simple font from its
+// base encoding and Differences array.
+func simpleEncoding(ctx *model.Context, d types.Dict) *[256]rune {
+	enc := winAnsiEncoding
+	var differences types.Array
+
+	switch o, _ := ctx.Dereference(d["Encoding"]); o := o.(type) {
+	case types.Name:
+		if o == "MacRomanEncoding" {
+			enc = macRomanEncoding
+		}
+	case types.Dict:
+		if base := o.NameEntry("BaseEncoding"); base != nil && *base == "MacRomanEncoding" {
+			enc = macRomanEncoding
+		}
+		differences, _ = ctx.DereferenceArray(o["Differences"])
+	}
+
+	table := new([256]rune)
+	for i := range table {
+		table[i] = enc(byte(i))
+	}
+	code := 0
+	for _, o := range differences {
+		switch o := o.(type) {
+		case types.Integer:
+			code = o.Value()
+		case types.Name:
+			if code >= 0 && code < 256 {
+				table[code] = glyphRune(string(o))
+			}
+			code++
+		}
+	}
+	return table
+}
+
+// cp1252High holds the characters of codes 0x80 to 0x9F in WinAnsiEncoding.
+var cp1252High = []rune("€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f\u0090‘’“”•–—˜™š›œ\u009džŸ")
+
+// macRomanHigh holds the characters of codes 0x80 to 0xFF in MacRomanEncoding.
+var macRomanHigh = []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+
+func winAnsiEncoding(b byte) rune {
+	if b >= 0x80 && b < 0xA0 {
+		return cp1252High[b-0x80]
+	}
+	return rune(b)
+}
+
+func macRomanEncoding(b byte) rune {
+	if b >= 0x80 {
+		return macRomanHigh[b-0x80]
+	}
+	return rune(b)
+}
+
+// glyphRune returns the character for a glyph name from a Differences array,
+// or 0 if it is unknown.
+func glyphRune(name string) rune {
+	if r, ok := glyphNames[name]; ok {
+		return r
+	}
+	if len(name) == 1 {
+		return rune(name[0])
+	}
+	if h, ok := strings.CutPrefix(name, "uni"); ok && len(h) == 4 {
+		if v, err := strconv.ParseUint(h, 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+	if h, ok := strings.CutPrefix(name, "u"); ok && len(h) >= 4 && len(h) <= 6 {
+		if v, err := strconv.ParseUint(h, 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+	// Look the name up among the Latin-1 and WinAnsi characters.
+	return latinGlyphs[name]
+}
+
+// glyphNames covers the ASCII glyph names whose names are not the character itself.
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/', "zero": '0', "one": '1',
+	"two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8',
+	"nine": '9', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"asciicircum": '^', "underscore": '_', "grave": '`', "braceleft": '{', "bar": '|',
+	"braceright": '}', "asciitilde": '~', "quoteright": '’', "quoteleft": '‘', "fi": 'ﬁ', "fl": 'ﬂ',
+	"minus": '−', "nbspace": ' ', "sfthyphen": '­', "dotlessi": 'ı', "Lslash": 'Ł',
+	"lslash": 'ł', "fraction": '⁄', "breve": '˘', "caron": 'ˇ', "circumflex": 'ˆ', "dotaccent": '˙',
+	"hungarumlaut": '˝', "ogonek": '˛', "ring": '˚', "tilde": '˜',
+}
+
+// latinGlyphs maps the standard names of the WinAnsi characters above ASCII.
+var latinGlyphs = func() map[string]rune {
+	names := strings.Fields(`Euro - quotesinglbase florin quotedblbase ellipsis dagger daggerdbl
+		circumflex perthousand Scaron guilsinglleft OE - Zcaron - - quoteleft quoteright
+		quotedblleft quotedblright bullet endash emdash tilde trademark scaron guilsinglright
+		oe - zcaron Ydieresis space exclamdown cent sterling currency yen brokenbar section
+		dieresis copyright ordfeminine guillemotleft logicalnot hyphen registered macron
+		degree plusminus twosuperior threesuperior acute mu paragraph periodcentered cedilla
+		onesuperior ordmasculine guillemotright onequarter onehalf threequarters questiondown
+		Agrave Aacute Acircumflex Atilde Adieresis Aring AE Ccedilla Egrave Eacute Ecircumflex
+		Edieresis Igrave Iacute Icircumflex Idieresis Eth Ntilde Ograve Oacute Ocircumflex
+		Otilde Odieresis multiply Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn
+		germandbls agrave aacute acircumflex atilde adieresis aring ae ccedilla egrave eacute
+		ecircumflex edieresis igrave iacute icircumflex idieresis eth ntilde ograve oacute
+		ocircumflex otilde odieresis divide oslash ugrave uacute ucircumflex udieresis yacute
+		thorn ydieresis`)
+	m := map[string]rune{}
+	for i, name := range names {
+		if name != "-" {
+			m[name] = winAnsiEncoding(byte(0x80 + i))
+		}
+	}
+	return m
+}()
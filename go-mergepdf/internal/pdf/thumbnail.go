@@ -0,0 +1,112 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// ErrInvalidThumbnail is returned for a page outside the document or a width
+// out of range.
+var ErrInvalidThumbnail = errors.New("invalid thumbnail options")
+
+// Thumbnail widths in pixels. Pages taller than maxThumbnailAspect times their
+// width are scaled down further, which bounds the work per thumbnail.
+const (
+	MinThumbnailWidth     = 32
+	MaxThumbnailWidth     = 400
+	DefaultThumbnailWidth = 160
+	maxThumbnailAspect    = 4
+)
+
+// ThumbnailOptions selects the page and width of a thumbnail.
+//
+// Renderer is the path of a pdftoppm compatible binary used instead of the
+// built-in rasterizer, which draws text as gray bars rather than glyphs.
+type ThumbnailOptions struct {
+	Page     int
+	Width    int
+	Renderer string
+}
+
+// Validate reports whether opts are usable; the page is checked against the
+// document by RenderThumbnail.
+func (opts ThumbnailOptions) Validate() error {
+	if opts.Page < 1 {
+		return fmt.Errorf("%w: page must be at least 1", ErrInvalidThumbnail)
+	}
+	if opts.Width < MinThumbnailWidth || opts.Width > MaxThumbnailWidth {
+		return fmt.Errorf("%w: width must be between %d and %d", ErrInvalidThumbnail, MinThumbnailWidth, MaxThumbnailWidth)
+	}
+	return nil
+}
+
+// RenderThumbnail renders a page of the PDF at pdfPath as a PNG image
+// opts.Width pixels wide. Rendering is abandoned when ctx is done.
+func RenderThumbnail(ctx context.Context, pdfPath string, opts ThumbnailOptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Renderer != "" {
+		return renderThumbnailExternal(ctx, pdfPath, opts)
+	}
+
+	pdfCtx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if opts.Page > pdfCtx.PageCount {
+		return nil, fmt.Errorf("%w: page %d, document has %d pages", ErrInvalidThumbnail, opts.Page, pdfCtx.PageCount)
+	}
+
+	img, err := renderPage(pdfCtx, opts.Page, opts.Width, ctx.Done())
+	if err != nil {
+		return nil, fmt.Errorf("failed to render page %d: %w", opts.Page, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderThumbnailExternal runs opts.Renderer with pdftoppm arguments, which
+// other renderers such as pdftocairo accept as well.
+func renderThumbnailExternal(ctx context.Context, pdfPath string, opts ThumbnailOptions) ([]byte, error) {
+	pageCount, err := pdfapi.PageCountFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if opts.Page > pageCount {
+		return nil, fmt.Errorf("%w: page %d, document has %d pages", ErrInvalidThumbnail, opts.Page, pageCount)
+	}
+
+	dir, err := os.MkdirTemp("", "thumbnail")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	page := strconv.Itoa(opts.Page)
+	outPrefix := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, opts.Renderer, "-png", "-f", page, "-l", page, "-singlefile",
+		"-scale-to-x", strconv.Itoa(opts.Width), "-scale-to-y", "-1", pdfPath, outPrefix)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if out = bytes.TrimSpace(out); len(out) > 0 {
+			return nil, fmt.Errorf("renderer failed: %w: %s", err, out)
+		}
+		return nil, fmt.Errorf("renderer failed: %w", err)
+	}
+	return os.ReadFile(outPrefix + ".png")
+}
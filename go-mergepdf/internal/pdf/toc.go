@@ -0,0 +1,305 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// TOCOptions describes the table of contents AddTOC puts in front of a
+// merged document. Title is the heading, "Contents" if empty. With Bookmarks
+// the top-level bookmarks of each file are listed under it.
+type TOCOptions struct {
+	Title     string `json:"title,omitempty"`
+	Bookmarks bool   `json:"bookmarks,omitempty"`
+}
+
+// TOCEntry is a line of a table of contents: a source file (Level 0) or one
+// of its bookmarks (Level 1). Page is 1-based in the document the contents
+// are added to, before the contents pages.
+type TOCEntry struct {
+	Title string
+	Page  int
+	Level int
+}
+
+// Layout of the contents pages, in points.
+const (
+	tocMargin     = 72
+	tocTitleSize  = 20
+	tocFileSize   = 12
+	tocMarkSize   = 10
+	tocMarkIndent = 18
+)
+
+// TOCEntries lists the files as they would be merged in sequence, titled by
+// titles, with their top-level bookmarks if bookmarks is set. Outlines that
+// cannot be read are left out rather than failing the merge.
+func TOCEntries(files, titles []string, bookmarks bool) ([]TOCEntry, error) {
+	var entries []TOCEntry
+	offset := 0
+	for i, file := range files {
+		ctx, err := pdfapi.ReadContextFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PDF: %w", err)
+		}
+		entries = append(entries, TOCEntry{Title: titles[i], Page: offset + 1})
+		if bookmarks {
+			bms, _ := pdfcpu.Bookmarks(ctx)
+			for _, bm := range bms {
+				if bm.PageFrom < 1 || bm.PageFrom > ctx.PageCount {
+					continue
+				}
+				entries = append(entries, TOCEntry{Title: bm.Title, Page: offset + bm.PageFrom, Level: 1})
+			}
+		}
+		offset += ctx.PageCount
+	}
+	return entries, nil
+}
+
+// AddTOC inserts contents pages listing entries in front of the PDF at
+// pdfPath, each line linking to its page, and writes the result to
+// outputPath, which may equal pdfPath. Page numbers are printed as the page
+// labels of the document if it has any; the contents pages are then labelled
+// i, ii and so on and the existing labels move behind them. It returns the
+// number of contents pages.
+func AddTOC(pdfPath, outputPath string, entries []TOCEntry, opts TOCOptions) (int, error) {
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	_, _, inh, err := ctx.PageDict(1, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read page 1: %w", err)
+	}
+	if inh.MediaBox == nil {
+		return 0, fmt.Errorf("page 1 has no media box")
+	}
+	box := inh.MediaBox
+
+	// Link targets are looked up before the page numbers shift.
+	targets := make([]types.IndirectRef, len(entries))
+	for i, e := range entries {
+		ir, err := ctx.PageDictIndRef(e.Page)
+		if err != nil || ir == nil {
+			return 0, fmt.Errorf("failed to find page %d: %v", e.Page, err)
+		}
+		targets[i] = *ir
+	}
+
+	pages := layoutTOC(entries, box)
+	n := len(pages)
+
+	labels, err := pageLabelsOf(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read page labels: %w", err)
+	}
+	if len(labels) > 0 {
+		shifted := PageLabels{{Page: 1, Style: "lower-roman"}}
+		for _, r := range labels {
+			r.Page += n
+			shifted = append(shifted, r)
+		}
+		labels = shifted
+	}
+
+	for i := 0; i < n; i++ {
+		if err := ctx.InsertBlankPages(types.IntSet{1: true}, nil, true); err != nil {
+			return 0, fmt.Errorf("failed to insert contents page: %w", err)
+		}
+	}
+	ctx.PageCount += n
+	if err := setPageLabels(ctx, labels); err != nil {
+		return 0, err
+	}
+
+	fonts, err := addStandardFonts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	heading := winAnsiBytes(opts.Title)
+	if opts.Title == "" {
+		heading = []byte("Contents")
+	}
+	for i, lines := range pages {
+		pageDict, _, _, err := ctx.PageDict(i+1, false)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read contents page: %w", err)
+		}
+		content := drawTOCPage(pageDict, box, fonts, heading, entries, lines, targets, labels, n)
+		if err := setPageContent(ctx, pageDict, content); err != nil {
+			return 0, fmt.Errorf("failed to write contents page: %w", err)
+		}
+		heading = nil
+	}
+
+	if err := writeContextFile(ctx, outputPath); err != nil {
+		return 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return n, nil
+}
+
+// tocLine is an entry placed on a contents page at baseline y.
+type tocLine struct {
+	entry int
+	y     float64
+}
+
+// layoutTOC distributes entries over pages of the size of box, below the
+// title on the first page.
+func layoutTOC(entries []TOCEntry, box *types.Rectangle) [][]tocLine {
+	top := box.UR.Y - tocMargin
+	bottom := box.LL.Y + tocMargin
+	pages := [][]tocLine{nil}
+	y := top - tocTitleSize*2
+	for i, e := range entries {
+		size := float64(tocFileSize)
+		if e.Level > 0 {
+			size = tocMarkSize
+		}
+		step := size * 1.5
+		if e.Level == 0 && i > 0 {
+			step += size / 2
+		}
+		y -= step
+		if y < bottom && len(pages[len(pages)-1]) > 0 {
+			pages = append(pages, nil)
+			y = top - size
+		}
+		pages[len(pages)-1] = append(pages[len(pages)-1], tocLine{entry: i, y: y})
+	}
+	return pages
+}
+
+// drawTOCPage writes the content and link annotations of a contents page.
+// Entry pages are shifted by the n contents pages in front of them.
+func drawTOCPage(pageDict types.Dict, box *types.Rectangle, fonts types.Dict, heading []byte, entries []TOCEntry, lines []tocLine, targets []types.IndirectRef, labels PageLabels, n int) []byte {
+	left, right := box.LL.X+tocMargin, box.UR.X-tocMargin
+	var content bytes.Buffer
+	if heading != nil {
+		fmt.Fprintf(&content, "BT /F2 %d Tf %.2f %.2f Td %s Tj ET\n", tocTitleSize, left, box.UR.Y-tocMargin-tocTitleSize, literalString(heading))
+	}
+
+	annots := types.Array{}
+	for _, l := range lines {
+		e := entries[l.entry]
+		size, x := float64(tocFileSize), left
+		if e.Level > 0 {
+			size, x = tocMarkSize, left+tocMarkIndent
+		}
+
+		number := winAnsiBytes(labels.Label(e.Page + n))
+		numberX := right - textWidth(number, "Helvetica", size)
+		text := winAnsiBytes(e.Title)
+		if room := numberX - x - size; textWidth(text, "Helvetica", size) > room {
+			for len(text) > 0 && textWidth(append(text, 0x85), "Helvetica", size) > room {
+				text = text[:len(text)-1]
+			}
+			text = append(text, 0x85) // ellipsis
+		}
+		fmt.Fprintf(&content, "BT /F1 %.0f Tf %.2f %.2f Td %s Tj ET\n", size, x, l.y, literalString(text))
+
+		// Dot leaders between the title and the page number.
+		dot := textWidth([]byte("."), "Helvetica", size)
+		start, end := x+textWidth(text, "Helvetica", size)+size/2, numberX-size/2
+		if count := int((end - start) / dot); count > 0 {
+			fmt.Fprintf(&content, "BT /F1 %.0f Tf %.2f %.2f Td (%s) Tj ET\n", size, end-float64(count)*dot, l.y, strings.Repeat(".", count))
+		}
+		fmt.Fprintf(&content, "BT /F1 %.0f Tf %.2f %.2f Td %s Tj ET\n", size, numberX, l.y, literalString(number))
+
+		annots = append(annots, types.Dict{
+			"Type":    types.Name("Annot"),
+			"Subtype": types.Name("Link"),
+			"Rect":    types.NewRectangle(left, l.y-size*0.3, right, l.y+size).Array(),
+			"Border":  types.NewIntegerArray(0, 0, 0),
+			"Dest":    types.Array{targets[l.entry], types.Name("XYZ"), nil, nil, nil},
+		})
+	}
+
+	pageDict["Resources"] = types.Dict{"Font": fonts}
+	pageDict["Annots"] = annots
+	return content.Bytes()
+}
+
+// addStandardFonts adds Helvetica as F1 and Helvetica-Bold as F2 to ctx,
+// WinAnsi encoded, and returns them as a font resource dictionary.
+func addStandardFonts(ctx *model.Context) (types.Dict, error) {
+	fonts := types.Dict{}
+	for name, baseFont := range map[string]string{"F1": "Helvetica", "F2": "Helvetica-Bold"} {
+		// Widths are optional for the standard fonts but help simple readers.
+		widths := types.Array{}
+		for c := 32; c <= 255; c++ {
+			widths = append(widths, types.Integer(font.CharWidth(baseFont, rune(c))))
+		}
+		ir, err := ctx.IndRefForNewObject(types.Dict{
+			"Type":      types.Name("Font"),
+			"Subtype":   types.Name("Type1"),
+			"BaseFont":  types.Name(baseFont),
+			"Encoding":  types.Name("WinAnsiEncoding"),
+			"FirstChar": types.Integer(32),
+			"LastChar":  types.Integer(255),
+			"Widths":    widths,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fonts[name] = *ir
+	}
+	return fonts, nil
+}
+
+// winAnsiBytes encodes s in WinAnsiEncoding for the standard fonts, replacing
+// characters it cannot represent with a question mark.
+func winAnsiBytes(s string) []byte {
+	var b []byte
+	for _, r := range s {
+		switch {
+		case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+			b = append(b, byte(r))
+		default:
+			c := byte('?')
+			for i, h := range cp1252High {
+				if h == r && i != 0x01 && i != 0x0D && i != 0x0F && i != 0x10 && i != 0x1D {
+					c = byte(0x80 + i)
+				}
+			}
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// textWidth returns the width of WinAnsi encoded text in a standard font.
+func textWidth(b []byte, fontName string, size float64) float64 {
+	w := 0
+	for _, c := range b {
+		w += font.CharWidth(fontName, rune(c))
+	}
+	return float64(w) * size / 1000
+}
+
+// literalString writes b as a PDF literal string.
+func literalString(b []byte) string {
+	var out strings.Builder
+	out.WriteByte('(')
+	for _, c := range b {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c < 0x20 || c >= 0x7F:
+			fmt.Fprintf(&out, "\\%03o", c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte(')')
+	return out.String()
+}
@@ -0,0 +1,365 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// ErrRepairFailed is returned when a damaged PDF could not be repaired.
+var ErrRepairFailed = errors.New("repair failed")
+
+// ValidationIssue is a problem found in a PDF. Severity is "error" for
+// problems that keep the file from being processed and "warning" otherwise.
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the result of ValidatePDF. Valid files pass pdfcpu's
+// relaxed validation, which merging and editing rely on; Strict files also
+// pass its strict validation against the PDF specification. Repaired is set
+// by callers that repaired the file.
+type ValidationReport struct {
+	Valid    bool              `json:"valid"`
+	Strict   bool              `json:"strict"`
+	Repaired bool              `json:"repaired,omitempty"`
+	Issues   []ValidationIssue `json:"issues"`
+}
+
+// ValidatePDF checks the structure of the PDF at pdfPath in relaxed and
+// strict mode and looks for a damaged cross-reference section, which pdfcpu
+// otherwise reports only as an unreadable file. Problems with the file are
+// reported as issues; an error is returned only if it cannot be read.
+func ValidatePDF(pdfPath string) (*ValidationReport, error) {
+	raw, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Issues: []ValidationIssue{}}
+	for _, msg := range xrefProblems(raw) {
+		report.Issues = append(report.Issues, ValidationIssue{Severity: "warning", Message: msg})
+	}
+
+	if err := validateMode(pdfPath, model.ValidationRelaxed); err != nil {
+		report.Issues = append(report.Issues, ValidationIssue{Severity: "error", Message: err.Error()})
+		return report, nil
+	}
+	report.Valid = true
+
+	if err := validateMode(pdfPath, model.ValidationStrict); err != nil {
+		report.Issues = append(report.Issues, ValidationIssue{Severity: "warning", Message: "Strict validation: " + err.Error()})
+		return report, nil
+	}
+	report.Strict = true
+	return report, nil
+}
+
+// validateMode runs pdfcpu validation in the given mode.
+func validateMode(pdfPath string, mode int) (err error) {
+	// pdfcpu panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = mode
+	return pdfapi.ValidateFile(pdfPath, conf)
+}
+
+var (
+	startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+	objectPattern    = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	xrefStartPattern = regexp.MustCompile(`^\s*(xref\b|\d+\s+\d+\s+obj\b)`)
+)
+
+// xrefProblems describes what is wrong with the end of the file, where
+// readers look for the cross-reference section.
+func xrefProblems(raw []byte) []string {
+	var problems []string
+	tail := raw[max(len(raw)-1024, 0):]
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		problems = append(problems, "The file does not end with %%EOF and may be truncated")
+	}
+
+	m := startXRefPattern.FindAllSubmatch(tail, -1)
+	if len(m) == 0 {
+		return append(problems, "The startxref entry pointing to the cross-reference section is missing")
+	}
+	offset, err := strconv.Atoi(string(m[len(m)-1][1]))
+	if err != nil || offset >= len(raw) || !xrefStartPattern.Match(raw[offset:min(offset+64, len(raw))]) {
+		problems = append(problems, fmt.Sprintf("startxref points to offset %s, where there is no cross-reference section", m[len(m)-1][1]))
+	}
+	return problems
+}
+
+// RepairPDF writes a repaired copy of the PDF at pdfPath to outputPath, which
+// may equal pdfPath. Files pdfcpu can still read are rewritten with a fresh
+// cross-reference table and trailer. Otherwise the objects are recovered by
+// scanning the file, including those in object streams, and the catalog is
+// located to rebuild the trailer. The result must pass relaxed validation.
+func RepairPDF(pdfPath, outputPath string) error {
+	tmpPath := outputPath + ".repair"
+	defer os.Remove(tmpPath)
+
+	if err := rewritePDF(pdfPath, tmpPath); err != nil {
+		raw, err := os.ReadFile(pdfPath)
+		if err != nil {
+			return err
+		}
+		rebuilt, err := rebuildPDF(raw)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRepairFailed, err)
+		}
+		if err := os.WriteFile(tmpPath, rebuilt, 0644); err != nil {
+			return err
+		}
+		if err := rewritePDF(tmpPath, tmpPath); err != nil {
+			return fmt.Errorf("%w: %v", ErrRepairFailed, err)
+		}
+	}
+
+	if err := validateMode(tmpPath, model.ValidationRelaxed); err != nil {
+		return fmt.Errorf("%w: %v", ErrRepairFailed, err)
+	}
+	return os.Rename(tmpPath, outputPath)
+}
+
+// rewritePDF reads the PDF at pdfPath with pdfcpu and writes it to outputPath.
+func rewritePDF(pdfPath, outputPath string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+	ctx, err := pdfapi.ReadContextFile(pdfPath)
+	if err != nil {
+		return err
+	}
+	return writeContextFile(ctx, outputPath)
+}
+
+var (
+	catalogPattern = regexp.MustCompile(`/Type\s*/Catalog\b`)
+	objStmPattern  = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	xrefObjPattern = regexp.MustCompile(`/Type\s*/XRef\b`)
+	infoPattern    = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	lengthPattern  = regexp.MustCompile(`/Length\s+\d+(\s+\d+\s+R)?`)
+	firstPattern   = regexp.MustCompile(`/First\s+(\d+)`)
+	countPattern   = regexp.MustCompile(`/N\s+(\d+)`)
+)
+
+const (
+	// maxObjectStreamSize limits how much of an object stream is decompressed.
+	maxObjectStreamSize = 64 << 20
+	// maxObjectNumber is the largest object number readers must support;
+	// larger ones found by scanning are taken to be stray stream data.
+	maxObjectNumber = 8388607
+)
+
+// recoveredObject is an object found by scanning a damaged file.
+type recoveredObject struct {
+	nr, gen int
+	body    []byte // Everything between obj and endobj
+	pos     int    // Position in the file; later definitions win
+}
+
+// rebuildPDF reassembles a damaged file from the objects it contains, with a
+// new cross-reference table and trailer.
+func rebuildPDF(raw []byte) ([]byte, error) {
+	objects := map[int]recoveredObject{}
+	add := func(o recoveredObject) {
+		if o.nr <= 0 || o.nr > maxObjectNumber {
+			return
+		}
+		if prev, ok := objects[o.nr]; !ok || prev.pos <= o.pos {
+			objects[o.nr] = o
+		}
+	}
+
+	matches := objectPattern.FindAllSubmatchIndex(raw, -1)
+	for i, m := range matches {
+		end := len(raw)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		body, ok := objectBody(raw[m[1]:end])
+		if !ok {
+			continue
+		}
+		nr, _ := strconv.Atoi(string(raw[m[2]:m[3]]))
+		gen, _ := strconv.Atoi(string(raw[m[4]:m[5]]))
+
+		dict := body
+		if s := bytes.Index(body, []byte("stream")); s >= 0 {
+			dict = body[:s]
+		}
+		switch {
+		case xrefObjPattern.Match(dict):
+			continue
+		case objStmPattern.Match(dict):
+			for _, o := range objectStreamObjects(body) {
+				o.pos = m[0]
+				add(o)
+			}
+			continue
+		}
+		add(recoveredObject{nr: nr, gen: gen, body: body, pos: m[0]})
+	}
+
+	root := -1
+	for nr, o := range objects {
+		dict := o.body
+		if s := bytes.Index(dict, []byte("stream")); s >= 0 {
+			dict = dict[:s]
+		}
+		if catalogPattern.Match(dict) && (root < 0 || o.pos > objects[root].pos) {
+			root = nr
+		}
+	}
+	if root < 0 {
+		return nil, errors.New("no document catalog found")
+	}
+
+	nrs := make([]int, 0, len(objects))
+	for nr := range objects {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+	size := nrs[len(nrs)-1] + 1
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, size)
+	for _, nr := range nrs {
+		o := objects[nr]
+		offsets[nr] = b.Len()
+		fmt.Fprintf(&b, "%d %d obj\n", nr, o.gen)
+		b.Write(o.body)
+		b.WriteString("\nendobj\n")
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f\r\n", size)
+	for nr := 1; nr < size; nr++ {
+		if o, ok := objects[nr]; ok {
+			fmt.Fprintf(&b, "%010d %05d n\r\n", offsets[nr], o.gen)
+		} else {
+			b.WriteString("0000000000 65535 f\r\n")
+		}
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d %d R", size, root, objects[root].gen)
+	if m := infoPattern.FindAllSubmatch(raw, -1); len(m) > 0 {
+		if nr, err := strconv.Atoi(string(m[len(m)-1][1])); err == nil {
+			if _, ok := objects[nr]; ok {
+				fmt.Fprintf(&b, " /Info %d %s R", nr, m[len(m)-1][2])
+			}
+		}
+	}
+	fmt.Fprintf(&b, " >>\nstartxref\n%d\n%%%%EOF\n", xref)
+	return b.Bytes(), nil
+}
+
+// objectBody returns the part of an object up to endobj. For streams the
+// Length entry is corrected to the data actually found, and truncated
+// streams are dropped.
+func objectBody(b []byte) ([]byte, bool) {
+	s := bytes.Index(b, []byte("stream"))
+	if s < 0 {
+		if e := bytes.Index(b, []byte("endobj")); e >= 0 {
+			b = b[:e]
+		}
+		return bytes.TrimSpace(b), true
+	}
+
+	e := bytes.LastIndex(b, []byte("endstream"))
+	if e < s {
+		return nil, false
+	}
+	dataStart := s + len("stream")
+	if dataStart < len(b) && b[dataStart] == '\r' {
+		dataStart++
+	}
+	if dataStart < len(b) && b[dataStart] == '\n' {
+		dataStart++
+	}
+	dataEnd := e
+	if dataEnd > dataStart && b[dataEnd-1] == '\n' {
+		dataEnd--
+	}
+	if dataEnd > dataStart && b[dataEnd-1] == '\r' {
+		dataEnd--
+	}
+	if dataEnd < dataStart {
+		dataEnd = dataStart
+	}
+
+	dict := lengthPattern.ReplaceAll(b[:s], []byte(fmt.Sprintf("/Length %d", dataEnd-dataStart)))
+	var out bytes.Buffer
+	out.Write(bytes.TrimSpace(dict))
+	out.WriteString("\nstream\n")
+	out.Write(b[dataStart:dataEnd])
+	out.WriteString("\nendstream")
+	return out.Bytes(), true
+}
+
+// objectStreamObjects returns the objects compressed in an object stream.
+// Only Flate encoded streams without predictors, as written by all common
+// producers, are decoded.
+func objectStreamObjects(body []byte) []recoveredObject {
+	s := bytes.Index(body, []byte("\nstream\n"))
+	if s < 0 {
+		return nil
+	}
+	dict := body[:s]
+	data := body[s+len("\nstream\n") : len(body)-len("\nendstream")]
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Contains(dict, []byte("/DecodeParms")) {
+		return nil
+	}
+	first := firstPattern.FindSubmatch(dict)
+	count := countPattern.FindSubmatch(dict)
+	if first == nil || count == nil {
+		return nil
+	}
+	firstOffset, _ := strconv.Atoi(string(first[1]))
+	n, _ := strconv.Atoi(string(count[1]))
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	content, err := io.ReadAll(io.LimitReader(zr, maxObjectStreamSize))
+	if len(content) == 0 || (err != nil && !errors.Is(err, io.ErrUnexpectedEOF)) || firstOffset > len(content) {
+		return nil
+	}
+
+	header := bytes.Fields(content[:firstOffset])
+	var objects []recoveredObject
+	for i := 0; i < n && 2*i+1 < len(header); i++ {
+		nr, err1 := strconv.Atoi(string(header[2*i]))
+		off, err2 := strconv.Atoi(string(header[2*i+1]))
+		if err1 != nil || err2 != nil || firstOffset+off > len(content) {
+			break
+		}
+		end := len(content)
+		if 2*i+3 < len(header) {
+			if next, err := strconv.Atoi(string(header[2*i+3])); err == nil && firstOffset+next <= len(content) && next >= off {
+				end = firstOffset + next
+			}
+		}
+		objects = append(objects, recoveredObject{nr: nr, body: bytes.TrimSpace(content[firstOffset+off : end])})
+	}
+	return objects
+}
@@ -39,19 +39,51 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(middleware.Logger)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"https://*", "http://*"},
-		AllowedMethods: []string{"GET", "POST", "PUT"},
-		AllowedHeaders: []string{"Content-Type"},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT"},
+		AllowedHeaders: []string{"Content-Type", "Range", "If-Range"},
+		// PDF viewers read these to load downloads in ranges
+		ExposedHeaders: []string{"Accept-Ranges", "Content-Range", "Content-Length", "Content-Disposition"},
 	}))
 	r.With(localhostOnly).Get("/swagger/*", httpSwagger.WrapHandler)
 	h := handlers.NewAPIHandler(s.SessionManager, s.UploadDir, s.OutputDir)
+	h.ThumbnailRenderer = s.ThumbnailRenderer
+	if s.CleanupDelay > 0 {
+		h.CleanupDelay = s.CleanupDelay
+	}
 	r.Route("/api/sessions", func(api chi.Router) {
 		api.Post("/", h.CreateSession)
+		api.Get("/{sessionID}", h.GetSession)
 		api.Post("/{sessionID}/files", h.UploadFile)
 		api.Post("/{sessionID}/signature", h.UploadSignature)
+		api.Post("/{sessionID}/assets", h.UploadAsset)
 		api.Put("/{sessionID}/order", h.UpdateOrder)
 		api.Post("/{sessionID}/actions/merge", h.MergeFiles)
+		api.Post("/{sessionID}/actions/metadata", h.SetMetadata)
+		api.Post("/{sessionID}/actions/sanitize", h.SanitizePDF)
+		api.Post("/{sessionID}/actions/fill", h.FillForm)
+		api.Post("/{sessionID}/actions/flatten", h.FlattenForm)
+		api.Post("/{sessionID}/actions/bulkfill", h.BulkFillForm)
+		api.Post("/{sessionID}/actions/overlay", h.OverlayPDF)
+		api.Post("/{sessionID}/actions/impose", h.ImposePDF)
+		api.Post("/{sessionID}/actions/crop", h.CropPDF)
+		api.Post("/{sessionID}/actions/insert", h.InsertPages)
+		api.Post("/{sessionID}/actions/extract-images", h.ExtractImages)
+		api.Post("/{sessionID}/actions/attach", h.AttachFiles)
+		api.Post("/{sessionID}/actions/redact", h.RedactPDF)
+		api.Post("/{sessionID}/actions/compare", h.ComparePDFs)
+		api.Post("/{sessionID}/actions/remove-annotations", h.RemoveAnnotations)
+		api.Post("/{sessionID}/actions/flatten-annotations", h.FlattenAnnotations)
+		api.Post("/{sessionID}/actions/remove-blank-pages", h.RemoveBlankPages)
 		api.Post("/{sessionID}/sign", h.SignPDF)
 		api.Get("/{sessionID}/files/{filename}", h.DownloadFile)
+		api.Head("/{sessionID}/files/{filename}", h.DownloadFile)
+		api.Get("/{sessionID}/files/{filename}/fields", h.ListFormFields)
+		api.Get("/{sessionID}/files/{filename}/text", h.ExtractText)
+		api.Get("/{sessionID}/files/{filename}/thumbnail", h.GetThumbnail)
+		api.Get("/{sessionID}/files/{filename}/attachments", h.ListAttachments)
+		api.Get("/{sessionID}/files/{filename}/attachments/{name}", h.ExtractAttachment)
+		api.Get("/{sessionID}/files/{filename}/pdfa", h.CheckPDFA)
+		api.Get("/{sessionID}/files/{filename}/annotations", h.ListAnnotations)
 	})
 
 	return r
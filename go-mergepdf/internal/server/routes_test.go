@@ -1,9 +1,16 @@
 package server
 
 import (
+	"archive/zip"
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -11,8 +18,14 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"go-mergepdf/internal/pdf"
 	"go-mergepdf/internal/session"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 )
 
 func setupTestServer() *httptest.Server {
@@ -258,3 +271,2295 @@ func TestSignPDF(t *testing.T) {
 		t.Error("Expected downloadUrl in response")
 	}
 }
+
+func TestMergeWithMetadata(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	first := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"title":        "Exhibit Bundle",
+			"producer":     "gluepdf",
+			"creationDate": "2024-01-02T03:04:05Z",
+			"strip":        true,
+		},
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+
+	get, err := http.Get(server.URL + "/api/sessions/" + sessionID)
+	if err != nil {
+		t.Fatalf("Failed to inspect session: %v", err)
+	}
+	defer get.Body.Close()
+	var info struct {
+		MergeStatus string                 `json:"mergeStatus"`
+		Metadata    map[string]interface{} `json:"metadata"`
+	}
+	if err := json.NewDecoder(get.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode session info: %v", err)
+	}
+	if info.MergeStatus != "done" {
+		t.Errorf("Expected merge status done, got %q", info.MergeStatus)
+	}
+	if info.Metadata["title"] != "Exhibit Bundle" {
+		t.Errorf("Expected title in metadata, got %q", info.Metadata["title"])
+	}
+	if info.Metadata["producer"] != "gluepdf" {
+		t.Errorf("Expected producer in metadata, got %q", info.Metadata["producer"])
+	}
+	if info.Metadata["creationDate"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("Expected creation date in metadata, got %q", info.Metadata["creationDate"])
+	}
+
+	t.Run("action", func(t *testing.T) {
+		type metadataResult struct {
+			Filename    string `json:"filename"`
+			DownloadURL string `json:"downloadUrl"`
+			Metadata    struct {
+				Title string `json:"title"`
+			} `json:"metadata"`
+		}
+		setMetadata := func(body map[string]interface{}) metadataResult {
+			resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/metadata", body)
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				b, _ := io.ReadAll(resp.Body)
+				t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(b))
+			}
+			var result metadataResult
+			_ = json.NewDecoder(resp.Body).Decode(&result)
+			return result
+		}
+
+		// Uploads are edited in place and the merged output stays.
+		result := setMetadata(map[string]interface{}{"file": first, "title": "Cover"})
+		if result.Filename != first || result.DownloadURL != "" || result.Metadata.Title != "Cover" {
+			t.Errorf("Expected the upload to be edited in place, got %+v", result)
+		}
+		if md, err := pdf.ReadMetadata(filepath.Join("uploads", first)); err != nil || md.Title != "Cover" {
+			t.Errorf("Expected the upload to have the new title, got %+v (%v)", md, err)
+		}
+		if _, err := os.Stat(filepath.Join("output", filepath.Base(merged.DownloadURL))); err != nil {
+			t.Errorf("Expected the merged output to stay: %v", err)
+		}
+		result = setMetadata(map[string]interface{}{"title": "Bundle"})
+		if result.DownloadURL == "" || result.Metadata.Title != "Bundle" {
+			t.Errorf("Expected a new output, got %+v", result)
+		}
+	})
+}
+
+func createTestSession(t *testing.T, serverURL string) string {
+	t.Helper()
+	resp, err := http.Post(serverURL+"/api/sessions/", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer resp.Body.Close()
+	var result map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return result["sessionId"]
+}
+
+func uploadTestPDF(t *testing.T, serverURL, sessionID, fname string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	file, err := os.Open("testfiles/" + fname)
+	if err != nil {
+		t.Fatalf("Failed to open test PDF: %v", err)
+	}
+	defer file.Close()
+	part, _ := writer.CreateFormFile("pdf", fname)
+	_, _ = io.Copy(part, file)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", serverURL+"/api/sessions/"+sessionID+"/files", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to upload PDF: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 OK for upload, got %d", resp.StatusCode)
+	}
+	var result map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return result["filename"].(string)
+}
+
+func postJSON(t *testing.T, url string, body interface{}) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Request to %s failed: %v", url, err)
+	}
+	return resp
+}
+
+func TestSanitizePDF(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	writeActivePDF(t, "testfiles/active.pdf")
+	defer os.Remove("testfiles/active.pdf")
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "active.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/sanitize", map[string]interface{}{
+		"file":            pdfFilename,
+		"javascript":      true,
+		"embeddedFiles":   true,
+		"hiddenLayers":    true,
+		"annotations":     true,
+		"formFields":      true,
+		"documentActions": true,
+		"metadata":        true,
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		Filename    string             `json:"filename"`
+		DownloadURL string             `json:"downloadUrl"`
+		Report      pdf.SanitizeReport `json:"report"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.Filename != pdfFilename || result.DownloadURL != "" {
+		t.Errorf("Expected the upload to be sanitized in place, got %+v", result)
+	}
+	// form.pdf runs six scripts of its own and has four links besides the
+	// widgets of its 30 fields.
+	report := result.Report
+	if report.JavaScript != 8 || report.DocumentActions != 1 || report.FormFields != 30 || !report.Metadata {
+		t.Errorf("Expected 8 scripts, an open action, 30 fields and metadata, got %+v", report)
+	}
+	if len(report.EmbeddedFiles) != 1 || report.EmbeddedFiles[0] != "notes.txt" {
+		t.Errorf("Expected notes.txt to be removed, got %v", report.EmbeddedFiles)
+	}
+	if len(report.HiddenLayers) != 1 || report.HiddenLayers[0] != "Draft" {
+		t.Errorf("Expected the Draft layer to be removed, got %v", report.HiddenLayers)
+	}
+	if report.Annotations["Text"] != 1 || report.Annotations["Link"] != 4 || report.Annotations["Widget"] != 36 {
+		t.Errorf("Expected a note, 4 links and 36 widgets, got %v", report.Annotations)
+	}
+
+	ctx, err := pdfapi.ReadContextFile(filepath.Join("uploads", pdfFilename))
+	if err != nil {
+		t.Fatalf("Failed to read sanitized PDF: %v", err)
+	}
+	for _, key := range []string{"Names", "OCProperties", "AcroForm", "OpenAction", "Metadata"} {
+		if _, ok := ctx.RootDict.Find(key); ok {
+			t.Errorf("Expected no %s in the catalog", key)
+		}
+	}
+	// No script is left anywhere the document reaches.
+	seen := map[int]bool{}
+	var walk func(o types.Object)
+	walk = func(o types.Object) {
+		if ir, ok := o.(types.IndirectRef); ok {
+			if seen[ir.ObjectNumber.Value()] {
+				return
+			}
+			seen[ir.ObjectNumber.Value()] = true
+			o, _ = ctx.Dereference(ir)
+		}
+		switch o := o.(type) {
+		case types.Dict:
+			if s := o.NameEntry("S"); s != nil && *s == "JavaScript" {
+				t.Errorf("Expected no JavaScript actions, found %v", o)
+			}
+			for _, v := range o {
+				walk(v)
+			}
+		case types.StreamDict:
+			walk(o.Dict)
+		case types.Array:
+			for _, v := range o {
+				walk(v)
+			}
+		}
+	}
+	walk(ctx.RootDict)
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, _ := ctx.PageDict(pageNr, false)
+		for _, key := range []string{"Annots", "AA"} {
+			if _, ok := pageDict.Find(key); ok {
+				t.Errorf("Expected no %s on page %d", key, pageNr)
+			}
+		}
+		content, err := ctx.PageContent(pageDict)
+		if err != nil || bytes.Contains(content, []byte("/OC")) {
+			t.Errorf("Expected the hidden layer to be cut from page %d (%v)", pageNr, err)
+		}
+	}
+	if md, _ := pdf.ReadMetadata(filepath.Join("uploads", pdfFilename)); md.Title != "" || md.Creator != "" {
+		t.Errorf("Expected the document information to be stripped, got %+v", md)
+	}
+
+	t.Run("no categories", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/sanitize", map[string]interface{}{
+			"file": pdfFilename,
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestFormFields(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	type field struct {
+		Name  string      `json:"name"`
+		Type  string      `json:"type"`
+		Value interface{} `json:"value"`
+	}
+	listFields := func(sessionID, filename string) map[string]field {
+		resp, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + filename + "/fields")
+		if err != nil {
+			t.Fatalf("Failed to list form fields: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d", resp.StatusCode)
+		}
+		var result struct {
+			Fields []field `json:"fields"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if result.Fields == nil {
+			t.Fatalf("Expected a field list, got null")
+		}
+		fields := map[string]field{}
+		for _, f := range result.Fields {
+			fields[f.Name] = f
+		}
+		return fields
+	}
+	outputName := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+		}
+		var result struct {
+			DownloadURL string `json:"downloadUrl"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return filepath.Base(result.DownloadURL)
+	}
+
+	sessionID := createTestSession(t, server.URL)
+	plain := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	if fields := listFields(sessionID, plain); len(fields) != 0 {
+		t.Errorf("Expected an empty field list, got %v", fields)
+	}
+
+	sessionID = createTestSession(t, server.URL)
+	first := uploadTestPDF(t, server.URL, sessionID, "form.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "form.pdf")
+	fields := listFields(sessionID, first)
+	if len(fields) != 30 || fields["lastName1"].Type != "text" || fields["cb21"].Value != true {
+		t.Fatalf("Expected the 30 fields of the form, got %v", fields)
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/fill", map[string]interface{}{
+			"file":   first,
+			"values": map[string]interface{}{"name": "Ada"},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", resp.StatusCode)
+		}
+	})
+
+	output := outputName(postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"renameFormFields": true,
+	}))
+	fields = listFields(sessionID, output)
+	if len(fields) != 60 {
+		t.Errorf("Expected 60 fields after merging two copies, got %d", len(fields))
+	}
+	for name := range fields {
+		if !strings.HasPrefix(name, "doc1_") && !strings.HasPrefix(name, "doc2_") {
+			t.Errorf("Expected field %q to carry a doc<n>_ prefix", name)
+		}
+	}
+	if _, ok := fields["doc1_lastName1"]; !ok {
+		t.Errorf("Expected doc1_lastName1 in %v", fields)
+	}
+	if _, ok := fields["doc2_lastName1"]; !ok {
+		t.Errorf("Expected doc2_lastName1 in %v", fields)
+	}
+
+	// Uploads are filled in place, leaving the merged output as it is.
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/fill", map[string]interface{}{
+		"file":   first,
+		"values": map[string]interface{}{"firstName1": "Ada"},
+	})
+	var filled struct {
+		Filename string `json:"filename"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&filled)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || filled.Filename != first {
+		t.Errorf("Expected %s to be filled in place, got %d %+v", first, resp.StatusCode, filled)
+	}
+	if fields := listFields(sessionID, first); fields["firstName1"].Value != "Ada" {
+		t.Errorf("Expected firstName1 to be filled, got %+v", fields["firstName1"])
+	}
+	if fields := listFields(sessionID, output); len(fields) != 60 {
+		t.Errorf("Expected the merged output to keep its 60 fields, got %d", len(fields))
+	}
+
+	output = outputName(postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/fill", map[string]interface{}{
+		"values": map[string]interface{}{"doc2_lastName1": "Lovelace"},
+	}))
+	fields = listFields(sessionID, output)
+	if fields["doc2_lastName1"].Value != "Lovelace" || fields["doc1_lastName1"].Value != "" {
+		t.Errorf("Expected only doc2_lastName1 to be filled, got %+v and %+v", fields["doc2_lastName1"], fields["doc1_lastName1"])
+	}
+
+	output = outputName(postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/flatten", map[string]interface{}{}))
+	if fields := listFields(sessionID, output); len(fields) != 0 {
+		t.Errorf("Expected no fields after flattening, got %d", len(fields))
+	}
+}
+
+func TestBulkFillFormWithoutFields(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("csv", "hires.csv")
+	_, _ = part.Write([]byte("firstName,lastName\nAda,Lovelace\n"))
+	_ = writer.WriteField("file", pdfFilename)
+	writer.Close()
+
+	resp, err := http.Post(server.URL+"/api/sessions/"+sessionID+"/actions/bulkfill", writer.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("Failed to post bulk fill: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request for a template without fields, got %d", resp.StatusCode)
+	}
+}
+
+func TestBulkFillForm(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	uploadTestPDF(t, server.URL, sessionID, "form.pdf")
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{})
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+	resp.Body.Close()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("csv", "hires.csv")
+	_, _ = part.Write([]byte("First,lastName1,cb21\nAda,Lovelace,false\nAlan,Turing,maybe\nGrace,Hopper,true\n"))
+	_ = writer.WriteField("mapping", `{"First": "firstName1", "lastName1": "lastName1", "cb21": "cb21"}`)
+	_ = writer.WriteField("nameTemplate", "{First}")
+	writer.Close()
+	resp, err := http.Post(server.URL+"/api/sessions/"+sessionID+"/actions/bulkfill", writer.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("Failed to post bulk fill: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		DownloadURL string `json:"downloadUrl"`
+		Report      struct {
+			Rows   int      `json:"rows"`
+			Filled int      `json:"filled"`
+			Files  []string `json:"files"`
+			Errors []struct {
+				Row   int    `json:"row"`
+				Error string `json:"error"`
+			} `json:"errors"`
+		} `json:"report"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	report := result.Report
+	if report.Rows != 3 || report.Filled != 2 || len(report.Errors) != 1 || report.Errors[0].Row != 2 || !strings.Contains(report.Errors[0].Error, "cb21") {
+		t.Fatalf("Expected rows 1 and 3 to be filled and row 2 to fail on cb21, got %+v", report)
+	}
+
+	download, err := http.Get(server.URL + result.DownloadURL)
+	if err != nil {
+		t.Fatalf("Failed to download archive: %v", err)
+	}
+	data, _ := io.ReadAll(download.Body)
+	download.Body.Close()
+	if download.StatusCode != http.StatusOK || !strings.Contains(download.Header.Get("Content-Disposition"), `filename="bulkfill.zip"`) {
+		t.Fatalf("Expected the archive to download as bulkfill.zip, got %d %q", download.StatusCode, download.Header.Get("Content-Disposition"))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	want := map[string][2]interface{}{"Ada.pdf": {"Lovelace", false}, "Grace.pdf": {"Hopper", true}}
+	if len(archive.File) != len(want) {
+		t.Errorf("Expected %d files in the archive, got %d", len(want), len(archive.File))
+	}
+	for _, f := range archive.File {
+		values, ok := want[f.Name]
+		if !ok {
+			t.Errorf("Unexpected archive entry %s", f.Name)
+			continue
+		}
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		path := filepath.Join(t.TempDir(), f.Name)
+		_ = os.WriteFile(path, content, 0644)
+		fields, err := pdf.ListFormFields(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", f.Name, err)
+		}
+		got := map[string]interface{}{}
+		for _, field := range fields {
+			got[field.Name] = field.Value
+		}
+		if got["lastName1"] != values[0] || got["cb21"] != values[1] {
+			t.Errorf("Expected %s to hold %v, got lastName1=%v cb21=%v", f.Name, values, got["lastName1"], got["cb21"])
+		}
+	}
+
+	// The archive comes beside the merged output, which stays usable.
+	fields, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + filepath.Base(merged.DownloadURL) + "/fields")
+	if err != nil {
+		t.Fatalf("Failed to list output fields: %v", err)
+	}
+	fields.Body.Close()
+	if fields.StatusCode != http.StatusOK {
+		t.Errorf("Expected the merged output to remain, got %d", fields.StatusCode)
+	}
+}
+
+func TestOverlayLetterhead(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", "valid2.pdf")
+	template, err := os.ReadFile("testfiles/valid2.pdf")
+	if err != nil {
+		t.Fatalf("Failed to read template: %v", err)
+	}
+	_, _ = part.Write(template)
+	writer.Close()
+	resp, err := http.Post(server.URL+"/api/sessions/"+sessionID+"/assets", writer.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("Failed to upload asset: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 OK for asset upload, got %d", resp.StatusCode)
+	}
+	var asset map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&asset)
+	templateFilename := asset["filename"].(string)
+
+	// Template pages 7 and 13 show just their number, which lands in front
+	// of the page text when stamped underneath and after it on top.
+	// Uploads are stamped in place, so each mode gets its own copy.
+	for _, underlay := range []bool{true, false} {
+		pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+		resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/overlay", map[string]interface{}{
+			"file":       pdfFilename,
+			"template":   templateFilename,
+			"firstPage":  7,
+			"otherPages": 13,
+			"underlay":   underlay,
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+		}
+		var result struct {
+			Filename string `json:"filename"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		if result.Filename != pdfFilename {
+			t.Errorf("Expected %s to be stamped in place, got %+v", pdfFilename, result)
+		}
+		pages, err := pdf.ExtractText(filepath.Join("uploads", pdfFilename))
+		if err != nil || len(pages) != 2 {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		for i, stamp := range []string{"7", "13"} {
+			lines := strings.Split(strings.TrimSpace(pages[i].Text), "\n")
+			got := lines[len(lines)-1]
+			if underlay {
+				got = lines[0]
+			}
+			if got != stamp {
+				t.Errorf("Expected template page %s stamped on page %d (underlay %v), got %q", stamp, i+1, underlay, pages[i].Text)
+			}
+		}
+	}
+
+	t.Run("template not a PDF", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "letterhead.txt")
+		_, _ = part.Write([]byte("not a PDF"))
+		writer.Close()
+		resp, err := http.Post(server.URL+"/api/sessions/"+sessionID+"/assets", writer.FormDataContentType(), &buf)
+		if err != nil {
+			t.Fatalf("Failed to upload asset: %v", err)
+		}
+		var asset map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&asset)
+		resp.Body.Close()
+		name, _ := asset["filename"].(string)
+		if name == "" {
+			t.Fatalf("Expected the text file to be accepted as an asset, got %v", asset)
+		}
+
+		resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/overlay", map[string]interface{}{
+			"file":     pdfFilename,
+			"template": name,
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request for a text template, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("nothing to stamp", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/overlay", map[string]interface{}{
+			"file":       pdfFilename,
+			"template":   templateFilename,
+			"firstPage":  -1,
+			"otherPages": -1,
+		})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected 200 OK, got %d", resp.StatusCode)
+		}
+		if n, err := pdfapi.PageCountFile(filepath.Join("uploads", pdfFilename)); err != nil || n != 2 {
+			t.Errorf("Expected the upload to stay intact, got %d pages (%v)", n, err)
+		}
+	})
+
+	t.Run("missing template page", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/overlay", map[string]interface{}{
+			"file":      pdfFilename,
+			"template":  templateFilename,
+			"firstPage": 999,
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestMergeWithPageSize(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	t.Run("invalid size", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+			"pageSize": map[string]interface{}{"size": "postcard-ish"},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", resp.StatusCode)
+		}
+	})
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"pageSize": map[string]interface{}{"size": "Letter", "mode": "fit", "margin": 18},
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+
+	ctx, err := pdfapi.ReadContextFile(filepath.Join("output", filepath.Base(result.DownloadURL)))
+	if err != nil {
+		t.Fatalf("Failed to read merged PDF: %v", err)
+	}
+	if ctx.PageCount != 18 {
+		t.Fatalf("Expected 18 pages, got %d", ctx.PageCount)
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, inh, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			t.Fatalf("Failed to read page %d: %v", pageNr, err)
+		}
+		if box := inh.MediaBox; !near(box.Width(), 612) || !near(box.Height(), 792) {
+			t.Errorf("Expected a 612x792 Letter page %d, got %v", pageNr, box)
+		}
+
+		// The source page is scaled into the area within the margins,
+		// filling it in one direction and centered in the other.
+		content, err := ctx.PageContent(pageDict)
+		if err != nil {
+			t.Fatalf("Failed to read content of page %d: %v", pageNr, err)
+		}
+		var x, y, w, h, sx, sy, tx, ty float64
+		if _, err := fmt.Sscanf(string(content), "q %f %f %f %f re W n %f 0 0 %f %f %f cm", &x, &y, &w, &h, &sx, &sy, &tx, &ty); err != nil {
+			t.Fatalf("Expected page %d to start with the placement, got %q", pageNr, content[:min(len(content), 80)])
+		}
+		if x < 18-0.01 || y < 18-0.01 || x+w > 594+0.01 || y+h > 774+0.01 {
+			t.Errorf("Expected page %d within the 18pt margins, got %v %v %v %v", pageNr, x, y, w, h)
+		}
+		if !near(w, 576) && !near(h, 756) {
+			t.Errorf("Expected page %d to fit the width or height, got %vx%v", pageNr, w, h)
+		}
+		if !near(x-18, 594-(x+w)) || !near(y-18, 774-(y+h)) {
+			t.Errorf("Expected page %d to be centered, got %v %v %v %v", pageNr, x, y, w, h)
+		}
+		if !near(sx, sy) {
+			t.Errorf("Expected page %d to keep its aspect ratio, got scale %v x %v", pageNr, sx, sy)
+		}
+	}
+}
+
+func TestImposePDF(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	short := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	// The pages of valid2.pdf show just their number.
+	for _, tc := range []struct {
+		body  map[string]interface{}
+		count int
+		pages []string // Page numbers on each sheet side
+	}{
+		// 16 pages four to a sheet make four sheets.
+		{map[string]interface{}{"file": pdfFilename, "n": 4, "border": true, "order": "column"}, 4, []string{"1 2 3 4", "5 6 7 8", "9 10 11 12", "13 14 15 16"}},
+		// A booklet puts two pages on each side of a folded sheet, so the
+		// first side carries the last and the first page.
+		{map[string]interface{}{"file": pdfFilename, "mode": "booklet"}, 8, []string{"16 1", "15 2", "14 3", "13 4", "12 5", "11 6", "10 7", "9 8"}},
+		// Two pages are padded to four, one sheet printed on both sides.
+		{map[string]interface{}{"file": short, "mode": "booklet"}, 2, nil},
+	} {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/impose", tc.body)
+		var result struct {
+			DownloadURL string `json:"downloadUrl"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 OK for %v, got %d", tc.body, resp.StatusCode)
+		}
+		pages, err := pdf.ExtractText(filepath.Join("output", filepath.Base(result.DownloadURL)))
+		if err != nil {
+			t.Fatalf("Failed to read imposed PDF: %v", err)
+		}
+		if len(pages) != tc.count {
+			t.Errorf("Expected %d pages for %v, got %d", tc.count, tc.body, len(pages))
+			continue
+		}
+		for i, want := range tc.pages {
+			if got := strings.Join(strings.Fields(pages[i].Text), " "); got != want {
+				t.Errorf("Expected pages %q on side %d for %v, got %q", want, i+1, tc.body, got)
+			}
+		}
+	}
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/impose", map[string]interface{}{
+		"file": pdfFilename,
+		"n":    5,
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request for n=5, got %d", resp.StatusCode)
+	}
+}
+
+func TestMergeInterleave(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"mode":          "interleave",
+		"reverseSecond": true,
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+
+	pages, err := pdf.ExtractText(filepath.Join("output", filepath.Base(merged.DownloadURL)))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if len(pages) != 18 {
+		t.Fatalf("Expected 18 pages, got %d", len(pages))
+	}
+	// The pages of valid2.pdf show just their number. Reversed, they run
+	// from 16 down, alternating with the two pages of valid1.pdf, and the
+	// rest follow once valid1.pdf runs out.
+	want := []string{"WALDEN", "16", "SOLITUDE", "15"}
+	for n := 14; n >= 1; n-- {
+		want = append(want, fmt.Sprint(n))
+	}
+	for i, w := range want {
+		if fields := strings.Fields(pages[i].Text); len(fields) == 0 || fields[0] != w {
+			t.Errorf("Expected page %d to start with %q, got %q", i+1, w, pages[i].Text)
+		}
+	}
+
+	t.Run("three files", func(t *testing.T) {
+		sessionID := createTestSession(t, server.URL)
+		for _, fname := range []string{"valid1.pdf", "valid2.pdf", "valid1.pdf"} {
+			uploadTestPDF(t, server.URL, sessionID, fname)
+		}
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+			"mode": "interleave",
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestCropPDF(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	numbered := uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	crop := func(body map[string]interface{}) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/crop", body)
+		var result struct {
+			Filename string `json:"filename"`
+			Cropped  int    `json:"cropped"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || result.Filename != body["file"] || result.Cropped != 1 {
+			t.Errorf("Expected one page of the upload to be cropped for %v, got %d %+v", body, resp.StatusCode, result)
+		}
+	}
+	// boxes returns the crop and trim boxes set on the pages of an upload.
+	boxes := func(filename string) (crop, trim []*types.Rectangle) {
+		ctx, err := pdfapi.ReadContextFile(filepath.Join("uploads", filename))
+		if err != nil {
+			t.Fatalf("Failed to read cropped PDF: %v", err)
+		}
+		rect := func(d types.Dict, key string) *types.Rectangle {
+			a, err := ctx.DereferenceArray(d[key])
+			if err != nil || a == nil {
+				return nil
+			}
+			r, _ := ctx.RectForArray(a)
+			return r
+		}
+		for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+			pageDict, _, _, _ := ctx.PageDict(pageNr, false)
+			crop = append(crop, rect(pageDict, "CropBox"))
+			trim = append(trim, rect(pageDict, "TrimBox"))
+		}
+		return crop, trim
+	}
+	near := func(r *types.Rectangle, llx, lly, urx, ury float64) bool {
+		return r != nil && math.Abs(r.LL.X-llx) < 0.01 && math.Abs(r.LL.Y-lly) < 0.01 &&
+			math.Abs(r.UR.X-urx) < 0.01 && math.Abs(r.UR.Y-ury) < 0.01
+	}
+
+	// valid1.pdf has A4 pages without a crop box.
+	crop(map[string]interface{}{"file": pdfFilename, "pages": "1", "unit": "percent", "top": 10, "left": 5})
+	cropBoxes, trimBoxes := boxes(pdfFilename)
+	if !near(cropBoxes[0], 0.05*595.28, 0, 595.28, 0.9*841.89) || trimBoxes[0] != nil {
+		t.Errorf("Expected 5%% left and 10%% top cut from page 1, got crop %v trim %v", cropBoxes[0], trimBoxes[0])
+	}
+	if cropBoxes[1] != nil || trimBoxes[1] != nil {
+		t.Errorf("Expected page 2 to keep its boxes, got crop %v trim %v", cropBoxes[1], trimBoxes[1])
+	}
+
+	// valid2.pdf has A6 pages showing a small page number.
+	crop(map[string]interface{}{"file": numbered, "pages": "2", "auto": true, "padding": 6, "box": "both"})
+	cropBoxes, trimBoxes = boxes(numbered)
+	if c := cropBoxes[1]; c == nil || trimBoxes[1] == nil || *c != *trimBoxes[1] ||
+		c.LL.X <= 0 || c.LL.Y <= 0 || c.UR.X >= 297.64 || c.UR.Y >= 419.53 || c.Width() < 12 || c.Height() < 12 {
+		t.Errorf("Expected page 2 trimmed to its number on both boxes, got crop %v trim %v", cropBoxes[1], trimBoxes[1])
+	}
+	for i, c := range cropBoxes {
+		if i != 1 && (!near(c, 0, 0, 297.64, 419.53) || trimBoxes[i] != nil) {
+			t.Errorf("Expected page %d to keep its boxes, got crop %v trim %v", i+1, c, trimBoxes[i])
+		}
+	}
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/crop", map[string]interface{}{
+		"file":   pdfFilename,
+		"unit":   "percent",
+		"top":    60,
+		"bottom": 40,
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request for margins covering the page, got %d", resp.StatusCode)
+	}
+
+	// The cropped upload is still a merge input.
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 OK merging cropped files, got %d", resp.StatusCode)
+	}
+}
+
+func TestInsertPages(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	sourceFilename := uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	for _, body := range []map[string]interface{}{
+		{"file": pdfFilename, "after": 1, "source": sourceFilename, "pages": "2-3"},
+		{"file": pdfFilename, "after": 0, "blank": 2, "size": "A5"},
+	} {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/insert", body)
+		var result struct {
+			Filename string `json:"filename"`
+			Inserted int    `json:"inserted"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || result.Filename != pdfFilename || result.Inserted != 2 {
+			t.Errorf("Expected 2 pages inserted for %v, got %d %+v", body, resp.StatusCode, result)
+		}
+	}
+
+	// Two blank A5 pages, then valid1.pdf with pages 2 and 3 of valid2.pdf
+	// after its first page.
+	pages, err := pdf.ExtractText(filepath.Join("uploads", pdfFilename))
+	if err != nil {
+		t.Fatalf("Failed to read edited PDF: %v", err)
+	}
+	want := []string{"", "", "WALDEN", "2", "3", "SOLITUDE"}
+	if len(pages) != len(want) {
+		t.Fatalf("Expected %d pages after inserting, got %d", len(want), len(pages))
+	}
+	for i, w := range want {
+		if fields := strings.Fields(pages[i].Text); (w == "") != (len(fields) == 0) || (w != "" && fields[0] != w) {
+			t.Errorf("Expected page %d to start with %q, got %q", i+1, w, pages[i].Text)
+		}
+	}
+	dims, err := pdfapi.PageDimsFile(filepath.Join("uploads", pdfFilename))
+	if err != nil {
+		t.Fatalf("Failed to read page sizes: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if math.Abs(dims[i].Width-420) > 1 || math.Abs(dims[i].Height-595) > 1 {
+			t.Errorf("Expected blank page %d to be A5, got %.2fx%.2f", i+1, dims[i].Width, dims[i].Height)
+		}
+	}
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/insert", map[string]interface{}{
+		"file":  pdfFilename,
+		"after": 10,
+		"blank": 1,
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 Bad Request for a position past the last page, got %d", resp.StatusCode)
+	}
+
+	// Drop the source from the order and merge the edited file on its own.
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/sessions/"+sessionID+"/order",
+		strings.NewReader(`{"files": ["`+pdfFilename+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	resp.Body.Close()
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 OK merging the edited file, got %d", resp.StatusCode)
+	}
+}
+
+func TestExtractImages(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/extract-images", map[string]interface{}{
+		"file": pdfFilename,
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a PDF without images, got %d", resp.StatusCode)
+	}
+
+	// Signing stamps an image onto the output.
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("signature", "signature1.png")
+	sig, err := os.ReadFile("testfiles/signature1.png")
+	if err != nil {
+		t.Fatalf("Failed to read signature: %v", err)
+	}
+	_, _ = part.Write(sig)
+	writer.Close()
+	resp, err = http.Post(server.URL+"/api/sessions/"+sessionID+"/signature", writer.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("Failed to upload signature: %v", err)
+	}
+	var sigResult map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&sigResult)
+	resp.Body.Close()
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/sign", map[string]interface{}{
+		"sourcePdf": pdfFilename,
+		"signature": sigResult["filename"],
+		"page":      1,
+		"scale":     1.0,
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 OK for sign request, got %d", resp.StatusCode)
+	}
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/extract-images", map[string]interface{}{})
+	defer resp.Body.Close()
+	var result struct {
+		DownloadURL string `json:"downloadUrl"`
+		Report      struct {
+			Images int `json:"images"`
+		} `json:"report"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK || result.Report.Images != 1 {
+		t.Fatalf("Expected one image, got %d %+v", resp.StatusCode, result)
+	}
+
+	download, err := http.Get(server.URL + result.DownloadURL)
+	if err != nil {
+		t.Fatalf("Failed to download archive: %v", err)
+	}
+	defer download.Body.Close()
+	if ct := download.Header.Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Expected application/zip, got %q", ct)
+	}
+
+	// The signed output is still the session output.
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/extract-images", map[string]interface{}{})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the output to survive the extraction, got %d", resp.StatusCode)
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+
+	resp, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + pdfFilename + "/text")
+	if err != nil {
+		t.Fatalf("Failed to get text: %v", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Pages []struct {
+			Page   int    `json:"page"`
+			Text   string `json:"text"`
+			NoText bool   `json:"noText"`
+		} `json:"pages"`
+		NoTextPages []int `json:"noTextPages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(result.Pages) != 2 || !strings.Contains(result.Pages[0].Text, "WALDEN") || len(result.NoTextPages) != 0 {
+		t.Errorf("Unexpected text: %+v", result)
+	}
+
+	resp2, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + pdfFilename + "/text?format=text")
+	if err != nil {
+		t.Fatalf("Failed to get plain text: %v", err)
+	}
+	defer resp2.Body.Close()
+	body, _ := io.ReadAll(resp2.Body)
+	if !strings.HasPrefix(resp2.Header.Get("Content-Type"), "text/plain") || strings.Count(string(body), "\f") != 1 {
+		t.Errorf("Expected two plain text pages, got %q", resp2.Header.Get("Content-Type"))
+	}
+}
+
+func TestGetThumbnail(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	url := server.URL + "/api/sessions/" + sessionID + "/files/" + pdfFilename + "/thumbnail?page=2&width=120"
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to get thumbnail: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to decode thumbnail: %v", err)
+	}
+	if img.Bounds().Dx() != 120 || img.Bounds().Dy() <= 120 {
+		t.Errorf("Unexpected thumbnail size %v", img.Bounds())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to revalidate thumbnail: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", resp2.StatusCode)
+	}
+
+	resp3, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + pdfFilename + "/thumbnail?page=3")
+	if err != nil {
+		t.Fatalf("Failed to get thumbnail: %v", err)
+	}
+	resp3.Body.Close()
+	if resp3.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a missing page, got %d", resp3.StatusCode)
+	}
+}
+
+func TestAttachFiles(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+
+	data := []byte("region,total\nnorth,12\nsouth,7\n")
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", "figures.csv")
+	_, _ = part.Write(data)
+	writer.Close()
+	resp, err := http.Post(server.URL+"/api/sessions/"+sessionID+"/assets", writer.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("Failed to upload asset: %v", err)
+	}
+	defer resp.Body.Close()
+	var asset map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&asset)
+	assetFilename := asset["filename"].(string)
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/attach", map[string]interface{}{
+		"file": pdfFilename,
+		"attachments": []map[string]interface{}{
+			{"file": assetFilename, "description": "Source figures"},
+		},
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+
+	filesURL := server.URL + "/api/sessions/" + sessionID + "/files/" + pdfFilename + "/attachments"
+	resp, err = http.Get(filesURL)
+	if err != nil {
+		t.Fatalf("Failed to list attachments: %v", err)
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Attachments []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Size        int    `json:"size"`
+		} `json:"attachments"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&list)
+	if len(list.Attachments) != 1 || list.Attachments[0].Name != "figures.csv" || list.Attachments[0].Size != len(data) {
+		t.Fatalf("Unexpected attachments %+v", list.Attachments)
+	}
+
+	resp, err = http.Get(filesURL + "/figures.csv")
+	if err != nil {
+		t.Fatalf("Failed to extract attachment: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Errorf("Unexpected attachment %d: %q", resp.StatusCode, body)
+	}
+	// The attachment name comes from the PDF, so it never picks the type.
+	if ct := resp.Header.Get("Content-Type"); ct != "application/octet-stream" ||
+		resp.Header.Get("X-Content-Type-Options") != "nosniff" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment;") {
+		t.Errorf("Expected a forced octet-stream download, got %q %q", ct, resp.Header.Get("Content-Disposition"))
+	}
+
+	resp, err = http.Get(filesURL + "/missing.csv")
+	if err != nil {
+		t.Fatalf("Failed to extract attachment: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing attachment, got %d", resp.StatusCode)
+	}
+}
+
+func TestRedactPDF(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	pdfFilename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/redact", map[string]interface{}{
+		"file":       pdfFilename,
+		"search":     "walden",
+		"ignoreCase": true,
+		"areas":      []map[string]interface{}{{"page": 2, "x": 50, "y": 50, "width": 300, "height": 100}},
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		Report struct {
+			Pages []struct {
+				Page       int `json:"page"`
+				Areas      int `json:"areas"`
+				Matches    int `json:"matches"`
+				Characters int `json:"characters"`
+			} `json:"pages"`
+			Matches int `json:"matches"`
+		} `json:"report"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	if result.Report.Matches != 1 || len(result.Report.Pages) != 2 || result.Report.Pages[0].Characters != 6 {
+		t.Errorf("Unexpected report %+v", result.Report)
+	}
+
+	// The subset font of valid1.pdf shows WALDEN as the codes !"#$%&. Only
+	// they should be gone from the page, with all the text around them kept.
+	walden := []byte(`!"#$%&`)
+	before := shownCodes(t, "testfiles/valid1.pdf", 1)
+	if !bytes.Contains(before, walden) {
+		t.Fatalf("Expected valid1.pdf to show %q on page 1", walden)
+	}
+	if after := shownCodes(t, filepath.Join("uploads", pdfFilename), 1); !bytes.Equal(after, bytes.Replace(before, walden, nil, 1)) {
+		t.Errorf("Expected only %q removed from page 1, got %q from %q", walden, after, before)
+	}
+
+	resp, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + pdfFilename + "/text")
+	if err != nil {
+		t.Fatalf("Failed to get text: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(strings.ToLower(string(body)), "walden") || !strings.Contains(string(body), "THOREAU") {
+		t.Errorf("Unexpected text after redaction: %s", body)
+	}
+
+	t.Run("empty match", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/redact", map[string]interface{}{
+			"file":   pdfFilename,
+			"search": `\d*`,
+			"regex":  true,
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 Bad Request, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestUploadRepairsDamagedPDF(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	raw, err := os.ReadFile("testfiles/valid1.pdf")
+	if err != nil {
+		t.Fatalf("Failed to read test PDF: %v", err)
+	}
+	// Point startxref at the header, as a broken editor might.
+	i := bytes.LastIndex(raw, []byte("startxref"))
+	damaged := append(append([]byte{}, raw[:i]...), "startxref\n0\n%%EOF\n"...)
+
+	upload := func(repair string) *http.Response {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("pdf", "damaged.pdf")
+		_, _ = part.Write(damaged)
+		if repair != "" {
+			_ = writer.WriteField("repair", repair)
+		}
+		writer.Close()
+		resp, err := http.Post(server.URL+"/api/sessions/"+sessionID+"/files", writer.FormDataContentType(), &buf)
+		if err != nil {
+			t.Fatalf("Failed to upload PDF: %v", err)
+		}
+		return resp
+	}
+
+	resp := upload("")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		Filename   string `json:"filename"`
+		Validation struct {
+			Valid    bool `json:"valid"`
+			Repaired bool `json:"repaired"`
+			Issues   []struct {
+				Severity string `json:"severity"`
+				Message  string `json:"message"`
+			} `json:"issues"`
+		} `json:"validation"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	if !result.Validation.Valid || !result.Validation.Repaired || len(result.Validation.Issues) == 0 {
+		t.Errorf("Unexpected validation %+v", result.Validation)
+	}
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/crop", map[string]interface{}{
+		"file": result.Filename,
+		"top":  10,
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the repaired file to be usable, got %d", resp.StatusCode)
+	}
+
+	resp = upload("false")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 without repair, got %d", resp.StatusCode)
+	}
+}
+
+func TestPDFA(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	type report struct {
+		Claimed string `json:"claimed"`
+		Levels  []struct {
+			Level      string `json:"level"`
+			Conformant bool   `json:"conformant"`
+			Issues     []struct {
+				Code    string `json:"code"`
+				Fixable bool   `json:"fixable"`
+			} `json:"issues"`
+		} `json:"levels"`
+	}
+	check := func(sessionID, filename, query string) report {
+		resp, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + filename + "/pdfa" + query)
+		if err != nil {
+			t.Fatalf("Failed to check PDF/A: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+		}
+		var r report
+		_ = json.NewDecoder(resp.Body).Decode(&r)
+		return r
+	}
+
+	sessionID := createTestSession(t, server.URL)
+	filename := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	if r := check(sessionID, filename, ""); len(r.Levels) != 3 || r.Levels[1].Conformant {
+		t.Errorf("Expected three non-conformant levels for the upload, got %+v", r)
+	}
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{"pdfa": "2b"})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+	output := merged.DownloadURL[strings.LastIndex(merged.DownloadURL, "/")+1:]
+	if r := check(sessionID, output, "?level=2b"); r.Claimed != "2B" || len(r.Levels) != 1 || !r.Levels[0].Conformant {
+		t.Errorf("Expected a conformant PDF/A-2b output, got %+v", r)
+	}
+
+	t.Run("unembedded fonts", func(t *testing.T) {
+		sessionID := createTestSession(t, server.URL)
+		uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+		before, _ := filepath.Glob("output/merged-*")
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{"pdfa": "2b"})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected 422, got %d", resp.StatusCode)
+		}
+		if after, _ := filepath.Glob("output/merged-*"); len(after) != len(before) {
+			t.Errorf("Expected the failed merge to leave no output, got %d files instead of %d", len(after), len(before))
+		}
+		var result struct {
+			Issues []struct {
+				Code string `json:"code"`
+			} `json:"issues"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		if len(result.Issues) != 1 || result.Issues[0].Code != "fonts" {
+			t.Errorf("Expected the unembedded fonts to be reported, got %+v", result.Issues)
+		}
+	})
+
+	t.Run("split", func(t *testing.T) {
+		sessionID := createTestSession(t, server.URL)
+		uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+		before, _ := filepath.Glob("output/merged-*")
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+			"pdfa":  "2b",
+			"split": map[string]interface{}{"maxPages": 8},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected 422 for a part that cannot be converted, got %d", resp.StatusCode)
+		}
+		if after, _ := filepath.Glob("output/merged-*"); len(after) != len(before) {
+			t.Errorf("Expected the failed merge to leave no output, got %d files instead of %d", len(after), len(before))
+		}
+		var result struct {
+			Issues []struct {
+				Code string `json:"code"`
+			} `json:"issues"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		if len(result.Issues) != 1 || result.Issues[0].Code != "fonts" {
+			t.Errorf("Expected the unembedded fonts to be reported, got %+v", result.Issues)
+		}
+	})
+}
+
+func TestLinearizedRangeDownload(t *testing.T) {
+	s := &Server{
+		SessionManager: session.NewSessionManager(),
+		UploadDir:      "uploads",
+		OutputDir:      "output",
+		CleanupDelay:   time.Millisecond,
+	}
+	server := httptest.NewServer(s.RegisterRoutes())
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{"linearize": true})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+
+	get := func(rangeHeader string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("GET", server.URL+merged.DownloadURL+"?inline=true", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to download: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, head := get("bytes=0-1023")
+	if resp.StatusCode != http.StatusPartialContent || len(head) != 1024 {
+		t.Fatalf("Expected 206 with 1024 bytes, got %d with %d", resp.StatusCode, len(head))
+	}
+	if !bytes.Contains(head, []byte("/Linearized 1")) {
+		t.Error("Expected the linearization dictionary at the start of the file")
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "inline") {
+		t.Errorf("Expected inline disposition, got %q", resp.Header.Get("Content-Disposition"))
+	}
+
+	// The session survives range and inline requests.
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := s.SessionManager.GetSession(sessionID); !ok {
+		t.Fatal("Expected the session to survive a range request")
+	}
+	resp, tail := get("bytes=-6")
+	if resp.StatusCode != http.StatusPartialContent || !strings.Contains(string(tail), "EOF") {
+		t.Errorf("Expected the end of the file, got %d: %q", resp.StatusCode, tail)
+	}
+	resp, full := get("")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || !bytes.HasPrefix(full, head) {
+		t.Errorf("Expected the full file with Accept-Ranges, got %d", resp.StatusCode)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := s.SessionManager.GetSession(sessionID); !ok {
+		t.Fatal("Expected the session to survive an inline download")
+	}
+
+	// A complete download as an attachment ends the session.
+	download, err := http.Get(server.URL + merged.DownloadURL)
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, download.Body)
+	download.Body.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, ok := s.SessionManager.GetSession(sessionID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the download to end the session")
+		}
+	}
+}
+
+func TestMergeWithPageLabels(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	second := uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+	// Give the second file front matter style labels of its own.
+	if err := pdf.SetPageLabels("uploads/"+second, "uploads/"+second, pdf.PageLabels{{Page: 1, Style: "lower-roman"}}); err != nil {
+		t.Fatalf("Failed to label upload: %v", err)
+	}
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"pageLabels": []map[string]interface{}{{"page": 1, "prefix": "Exhibit A-"}},
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+
+	labels, err := pdf.ReadPageLabels(filepath.Join("output", filepath.Base(merged.DownloadURL)))
+	if err != nil {
+		t.Fatalf("Failed to read page labels: %v", err)
+	}
+	want := pdf.PageLabels{
+		{Page: 1, Style: "decimal", Prefix: "Exhibit A-"},
+		{Page: 3, Style: "lower-roman"},
+	}
+	if len(labels) != len(want) || labels[0] != want[0] || labels[1] != want[1] {
+		t.Errorf("Expected labels %+v, got %+v", want, labels)
+	}
+
+	t.Run("page out of range", func(t *testing.T) {
+		sessionID := createTestSession(t, server.URL)
+		uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+			"pageLabels": []map[string]interface{}{{"page": 3, "style": "roman"}},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestMergeWithTOC(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"toc": map[string]interface{}{"title": "Exhibits", "bookmarks": true},
+	})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+
+	pages, err := pdf.ExtractText(filepath.Join("output", filepath.Base(merged.DownloadURL)))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if len(pages) != 19 {
+		t.Fatalf("Expected a contents page and 18 merged pages, got %d pages", len(pages))
+	}
+	lines := strings.Split(pages[0].Text, "\n")
+	if lines[0] != "Exhibits" || !strings.HasPrefix(lines[1], "valid1 .") || !strings.HasSuffix(lines[1], " 2") ||
+		!strings.HasPrefix(lines[2], "valid2 .") || !strings.HasSuffix(lines[2], " 4") {
+		t.Errorf("Expected the files with their pages, got %q", pages[0].Text)
+	}
+
+	t.Run("interleave", func(t *testing.T) {
+		sessionID := createTestSession(t, server.URL)
+		uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+		uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+			"mode": "interleave", "toc": map[string]interface{}{},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestMergeSplit(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	type part struct {
+		Name      string `json:"name"`
+		FirstPage int    `json:"firstPage"`
+		LastPage  int    `json:"lastPage"`
+		Size      int64  `json:"size"`
+		Oversized bool   `json:"oversized"`
+	}
+	split := func(options map[string]interface{}) []part {
+		sessionID := createTestSession(t, server.URL)
+		for _, name := range []string{"valid1.pdf", "valid2.pdf", "valid1.pdf"} {
+			uploadTestPDF(t, server.URL, sessionID, name)
+		}
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+			"split":    options,
+			"metadata": map[string]interface{}{"title": "Exhibits", "producer": "gluepdf"},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+		}
+		var result struct {
+			DownloadURL string `json:"downloadUrl"`
+			Parts       []part `json:"parts"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+
+		zr, err := zip.OpenReader(filepath.Join("output", filepath.Base(result.DownloadURL)))
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		defer zr.Close()
+		if len(zr.File) != len(result.Parts) {
+			t.Fatalf("Expected %d parts in the archive, got %d", len(result.Parts), len(zr.File))
+		}
+		for i, f := range zr.File {
+			if f.Name != result.Parts[i].Name || int64(f.UncompressedSize64) != result.Parts[i].Size {
+				t.Errorf("Expected part %+v, got %s with %d bytes", result.Parts[i], f.Name, f.UncompressedSize64)
+			}
+		}
+
+		// Parts keep the metadata of the merge.
+		rc, err := zr.File[0].Open()
+		if err != nil {
+			t.Fatalf("Failed to open part: %v", err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		partPath := filepath.Join(t.TempDir(), "part.pdf")
+		_ = os.WriteFile(partPath, data, 0o644)
+		md, err := pdf.ReadMetadata(partPath)
+		if err != nil {
+			t.Fatalf("Failed to read part metadata: %v", err)
+		}
+		if md.Title != "Exhibits" || md.Producer != "gluepdf" {
+			t.Errorf("Expected the part to keep the metadata, got %+v", md)
+		}
+		return result.Parts
+	}
+
+	parts := split(map[string]interface{}{"maxPages": 5, "keepFilesTogether": true})
+	want := []part{
+		{Name: "merged-part1-of-3.pdf", FirstPage: 1, LastPage: 2},
+		{Name: "merged-part2-of-3.pdf", FirstPage: 3, LastPage: 18, Oversized: true},
+		{Name: "merged-part3-of-3.pdf", FirstPage: 19, LastPage: 20},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("Expected %d parts, got %+v", len(want), parts)
+	}
+	for i, p := range parts {
+		p.Size = 0
+		if p != want[i] {
+			t.Errorf("Expected part %+v, got %+v", want[i], p)
+		}
+	}
+
+	const budget = 40000
+	parts = split(map[string]interface{}{"maxBytes": budget})
+	next := 1
+	for _, p := range parts {
+		if p.FirstPage != next || p.Size > budget && (!p.Oversized || p.FirstPage != p.LastPage) {
+			t.Errorf("Unexpected part %+v", p)
+		}
+		next = p.LastPage + 1
+	}
+	if next != 21 {
+		t.Errorf("Expected the parts to cover 20 pages, got %d", next-1)
+	}
+}
+
+func TestDuplicateUploadsAndPages(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	sessionID := createTestSession(t, server.URL)
+	first := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	data, _ := os.ReadFile("testfiles/valid1.pdf")
+	part, _ := writer.CreateFormFile("pdf", "copy.pdf")
+	_, _ = part.Write(data)
+	writer.Close()
+	req, _ := http.NewRequest("POST", server.URL+"/api/sessions/"+sessionID+"/files", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to upload PDF: %v", err)
+	}
+	var upload struct {
+		Filename    string   `json:"filename"`
+		SHA256      string   `json:"sha256"`
+		DuplicateOf []string `json:"duplicateOf"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&upload)
+	resp.Body.Close()
+	if len(upload.SHA256) != 64 || len(upload.DuplicateOf) != 1 || upload.DuplicateOf[0] != first {
+		t.Fatalf("Expected the copy to be flagged as a duplicate of %s, got %+v", first, upload)
+	}
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{"dropDuplicatePages": true})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		DownloadURL  string `json:"downloadUrl"`
+		RemovedPages []struct {
+			File        string `json:"file"`
+			Page        int    `json:"page"`
+			DuplicateOf struct {
+				File string `json:"file"`
+				Page int    `json:"page"`
+			} `json:"duplicateOf"`
+		} `json:"removedPages"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	if len(result.RemovedPages) != 2 {
+		t.Fatalf("Expected 2 removed pages, got %+v", result.RemovedPages)
+	}
+	for i, p := range result.RemovedPages {
+		if p.File != upload.Filename || p.Page != i+1 || p.DuplicateOf.File != first || p.DuplicateOf.Page != i+1 {
+			t.Errorf("Unexpected removed page %+v", p)
+		}
+	}
+
+	pages, err := pdf.ExtractText(filepath.Join("output", filepath.Base(result.DownloadURL)))
+	if err != nil {
+		t.Fatalf("Failed to read merged PDF: %v", err)
+	}
+	if len(pages) != 18 {
+		t.Errorf("Expected 18 pages after dropping the copy, got %d", len(pages))
+	}
+}
+
+func TestComparePDFs(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	type comparison struct {
+		SameContent       bool `json:"sameContent"`
+		OriginalPageCount int  `json:"originalPageCount"`
+		RevisedPageCount  int  `json:"revisedPageCount"`
+		Metadata          []struct {
+			Field   string `json:"field"`
+			Revised string `json:"revised"`
+		} `json:"metadata"`
+		Pages []struct {
+			Status string `json:"status"`
+		} `json:"pages"`
+	}
+	compare := func(sessionID string, body map[string]interface{}) (comparison, string) {
+		resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/compare", body)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(b))
+		}
+		var result struct {
+			Comparison  comparison `json:"comparison"`
+			DownloadURL string     `json:"downloadUrl"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return result.Comparison, result.DownloadURL
+	}
+
+	sessionID := createTestSession(t, server.URL)
+	draft := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{
+		"metadata": map[string]interface{}{"title": "Signed copy"},
+	})
+	var merged struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&merged)
+	resp.Body.Close()
+
+	// The merged copy of a single file has the same pages.
+	c, _ := compare(sessionID, map[string]interface{}{"original": draft})
+	if !c.SameContent || len(c.Pages) != 0 || c.OriginalPageCount != 2 || c.RevisedPageCount != 2 {
+		t.Errorf("Expected the same content on 2 pages, got %+v", c)
+	}
+	found := false
+	for _, m := range c.Metadata {
+		found = found || m.Field == "title" && m.Revised == "Signed copy"
+	}
+	if !found {
+		t.Errorf("Expected the title change in %+v", c.Metadata)
+	}
+
+	other := uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+	c, downloadURL := compare(sessionID, map[string]interface{}{"original": draft, "revised": other, "report": true})
+	if c.SameContent || c.RevisedPageCount != 16 || len(c.Pages) != 16 {
+		t.Errorf("Expected 16 differing pages, got %+v", c)
+	}
+	pages, err := pdf.ExtractText(filepath.Join("output", filepath.Base(downloadURL)))
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	if !strings.Contains(pages[0].Text, "Comparison report") {
+		t.Errorf("Expected a comparison report, got %q", pages[0].Text)
+	}
+
+	// The report leaves the merged output in place.
+	if c, _ := compare(sessionID, map[string]interface{}{"original": draft, "report": true}); !c.SameContent {
+		t.Errorf("Expected the merged output to be unchanged, got %+v", c)
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"original": draft, "revised": draft},
+		{"original": "", "revised": filepath.Base(merged.DownloadURL)},
+	} {
+		resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/compare", body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for comparing a file with itself as %v, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	type annotation struct {
+		Page     int    `json:"page"`
+		Type     string `json:"type"`
+		Author   string `json:"author"`
+		Contents string `json:"contents"`
+	}
+	list := func(sessionID, filename string) []annotation {
+		resp, err := http.Get(server.URL + "/api/sessions/" + sessionID + "/files/" + filename + "/annotations")
+		if err != nil {
+			t.Fatalf("Failed to list annotations: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 OK, got %d", resp.StatusCode)
+		}
+		var result struct {
+			Annotations []annotation `json:"annotations"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return result.Annotations
+	}
+
+	writeAnnotatedPDF(t, "testfiles/annotated.pdf")
+	defer os.Remove("testfiles/annotated.pdf")
+	sessionID := createTestSession(t, server.URL)
+	filename := uploadTestPDF(t, server.URL, sessionID, "annotated.pdf")
+	want := []annotation{
+		{Page: 1, Type: "Text", Author: "Alice", Contents: "Please check this paragraph"},
+		{Page: 1, Type: "Highlight", Author: "Bob", Contents: "Typo"},
+		{Page: 2, Type: "Square", Author: "Alice", Contents: "Remove this figure"},
+	}
+	got := list(sessionID, filename)
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d annotations, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], got[i])
+		}
+	}
+
+	// The square has no appearance, so only the note is flattened.
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/flatten-annotations", map[string]interface{}{
+		"file": filename, "authors": []string{"alice"},
+	})
+	var result map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if result["flattened"] != float64(1) {
+		t.Errorf("Expected 1 flattened annotation, got %v", result)
+	}
+	if got := list(sessionID, filename); len(got) != 2 || got[0].Type != "Highlight" || got[1].Type != "Square" {
+		t.Errorf("Expected the highlight and square to remain, got %+v", got)
+	}
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/remove-annotations", map[string]interface{}{
+		"file": filename, "types": []string{"square"},
+	})
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if result["removed"] != float64(1) {
+		t.Errorf("Expected 1 removed annotation, got %v", result)
+	}
+	if got := list(sessionID, filename); len(got) != 1 || got[0].Author != "Bob" {
+		t.Errorf("Expected Bob's highlight to remain, got %+v", got)
+	}
+
+	resp = postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/remove-annotations", map[string]interface{}{
+		"file": filename, "types": []string{"Widget"},
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for removing form fields, got %d", resp.StatusCode)
+	}
+}
+
+// writeActivePDF writes form.pdf with one of everything SanitizePDF removes
+// to path: a document script and a page script, an embedded notes.txt, a
+// square in the hidden layer Draft, a sticky note, the form, an open action
+// and the document information.
+func writeActivePDF(t *testing.T, path string) {
+	t.Helper()
+	ctx, err := pdfapi.ReadContextFile("testfiles/form.pdf")
+	if err != nil {
+		t.Fatalf("Failed to read test PDF: %v", err)
+	}
+	script := func(js string) types.IndirectRef {
+		ir, _ := ctx.IndRefForNewObject(types.Dict{"S": types.Name("JavaScript"), "JS": types.StringLiteral(js)})
+		return *ir
+	}
+	names, _ := ctx.IndRefForNewObject(types.Dict{"Names": types.Array{types.StringLiteral("init"), script("app.alert(1)")}})
+	ctx.RootDict["Names"] = types.Dict{"JavaScript": *names}
+	if err := ctx.AddAttachment(model.Attachment{Reader: strings.NewReader("notes"), ID: "notes.txt", FileName: "notes.txt"}, false); err != nil {
+		t.Fatalf("Failed to attach file: %v", err)
+	}
+
+	pageDict, pageRef, _, _ := ctx.PageDict(1, false)
+	pageDict["AA"] = types.Dict{"O": script("app.alert(2)")}
+	ocg, _ := ctx.IndRefForNewObject(types.Dict{"Type": types.Name("OCG"), "Name": types.StringLiteral("Draft")})
+	ctx.RootDict["OCProperties"] = types.Dict{
+		"OCGs": types.Array{*ocg},
+		"D":    types.Dict{"OFF": types.Array{*ocg}},
+	}
+	resources, _ := ctx.DereferenceDict(pageDict["Resources"])
+	resources["Properties"] = types.Dict{"Draft": *ocg}
+	pageDict["Resources"] = resources
+	layer, _ := ctx.NewStreamDictForBuf([]byte("/OC /Draft BDC 0 0 100 100 re f EMC"))
+	_ = layer.Encode()
+	layerRef, _ := ctx.IndRefForNewObject(*layer)
+	contents, _ := ctx.DereferenceArray(pageDict["Contents"])
+	if contents == nil {
+		contents = types.Array{pageDict["Contents"]}
+	}
+	pageDict["Contents"] = append(contents, *layerRef)
+	note, _ := ctx.IndRefForNewObject(types.Dict{
+		"Type":     types.Name("Annot"),
+		"Subtype":  types.Name("Text"),
+		"Rect":     types.NewNumberArray(500, 750, 520, 770),
+		"Contents": types.StringLiteral("Note"),
+		"P":        *pageRef,
+	})
+	annots, _ := ctx.DereferenceArray(pageDict["Annots"])
+	pageDict["Annots"] = append(annots, *note)
+
+	ctx.RootDict["OpenAction"] = types.Array{*pageRef, types.Name("Fit")}
+	if err := pdfapi.WriteContextFile(ctx, path); err != nil {
+		t.Fatalf("Failed to write active PDF: %v", err)
+	}
+}
+
+// writeAnnotatedPDF writes valid1.pdf with review annotations to path: a
+// sticky note by Alice with a pop-up and a highlight by Bob on page 1, both
+// with appearances, and a square by Alice without one on page 2.
+func writeAnnotatedPDF(t *testing.T, path string) {
+	t.Helper()
+	ctx, err := pdfapi.ReadContextFile("testfiles/valid1.pdf")
+	if err != nil {
+		t.Fatalf("Failed to read test PDF: %v", err)
+	}
+	appearance := func(content string, w, h float64) types.IndirectRef {
+		sd, _ := ctx.NewStreamDictForBuf([]byte(content))
+		sd.Dict["Type"] = types.Name("XObject")
+		sd.Dict["Subtype"] = types.Name("Form")
+		sd.Dict["BBox"] = types.NewNumberArray(0, 0, w, h)
+		_ = sd.Encode()
+		ir, _ := ctx.IndRefForNewObject(*sd)
+		return *ir
+	}
+	add := func(pageNr int, d types.Dict) types.IndirectRef {
+		pageDict, pageRef, _, _ := ctx.PageDict(pageNr, false)
+		d["Type"] = types.Name("Annot")
+		d["P"] = *pageRef
+		ir, _ := ctx.IndRefForNewObject(d)
+		annots, _ := ctx.DereferenceArray(pageDict["Annots"])
+		pageDict["Annots"] = append(annots, *ir)
+		return *ir
+	}
+
+	note := add(1, types.Dict{
+		"Subtype":  types.Name("Text"),
+		"Rect":     types.NewNumberArray(500, 750, 520, 770),
+		"T":        types.StringLiteral("Alice"),
+		"Contents": types.StringLiteral("Please check this paragraph"),
+		"AP":       types.Dict{"N": appearance("1 1 0 rg 0 0 20 20 re f", 20, 20)},
+	})
+	noteDict, _ := ctx.DereferenceDict(note)
+	noteDict["Popup"] = add(1, types.Dict{
+		"Subtype": types.Name("Popup"),
+		"Rect":    types.NewNumberArray(520, 650, 590, 770),
+		"Parent":  note,
+	})
+	add(1, types.Dict{
+		"Subtype":    types.Name("Highlight"),
+		"Rect":       types.NewNumberArray(72, 600, 300, 615),
+		"QuadPoints": types.NewNumberArray(72, 615, 300, 615, 72, 600, 300, 600),
+		"T":          types.StringLiteral("Bob"),
+		"Contents":   types.StringLiteral("Typo"),
+		"AP":         types.Dict{"N": appearance("1 1 0 rg 0 0 228 15 re f", 228, 15)},
+	})
+	add(2, types.Dict{
+		"Subtype":  types.Name("Square"),
+		"Rect":     types.NewNumberArray(100, 100, 200, 200),
+		"T":        types.StringLiteral("Alice"),
+		"Contents": types.StringLiteral("Remove this figure"),
+	})
+	if err := pdfapi.WriteContextFile(ctx, path); err != nil {
+		t.Fatalf("Failed to write annotated PDF: %v", err)
+	}
+}
+
+// writeScannedPDF writes a duplex scan of one sheet at 600 dpi to path:
+// page 1 is the front with a dark block, page 2 the back, which is paper
+// gray with a speck of dust.
+func writeScannedPDF(t *testing.T, path string) {
+	t.Helper()
+	ctx, err := pdfapi.ReadContextFile("testfiles/valid1.pdf")
+	if err != nil {
+		t.Fatalf("Failed to read test PDF: %v", err)
+	}
+	for pageNr := 1; pageNr <= 2; pageNr++ {
+		scan := image.NewGray(image.Rect(0, 0, 4961, 7016))
+		for i := range scan.Pix {
+			scan.Pix[i] = 235
+		}
+		ink := image.Rect(2000, 3000, 2020, 3020)
+		if pageNr == 1 {
+			ink = image.Rect(1000, 1000, 4000, 3000)
+		}
+		for y := ink.Min.Y; y < ink.Max.Y; y++ {
+			for x := ink.Min.X; x < ink.Max.X; x++ {
+				scan.Pix[scan.PixOffset(x, y)] = 0
+			}
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scan, &jpeg.Options{Quality: 50}); err != nil {
+			t.Fatalf("Failed to encode scan: %v", err)
+		}
+		ir, _, _, err := model.CreateImageResource(ctx.XRefTable, &buf, false, false)
+		if err != nil {
+			t.Fatalf("Failed to add scan: %v", err)
+		}
+		content, _ := ctx.NewStreamDictForBuf([]byte("q 595 0 0 842 0 0 cm /Scan Do Q"))
+		_ = content.Encode()
+		contentRef, _ := ctx.IndRefForNewObject(*content)
+		pageDict, _, _, _ := ctx.PageDict(pageNr, false)
+		pageDict["MediaBox"] = types.NewNumberArray(0, 0, 595, 842)
+		pageDict["Contents"] = *contentRef
+		pageDict["Resources"] = types.Dict{"XObject": types.Dict{"Scan": *ir}}
+	}
+	if err := pdfapi.WriteContextFile(ctx, path); err != nil {
+		t.Fatalf("Failed to write scanned PDF: %v", err)
+	}
+}
+
+func TestRemoveBlankPages(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	type blankPage struct {
+		File   string `json:"file"`
+		Page   int    `json:"page"`
+		Reason string `json:"reason"`
+	}
+	type blankResult struct {
+		Filename    string      `json:"filename"`
+		DownloadURL string      `json:"downloadUrl"`
+		BlankPages  []blankPage `json:"blankPages"`
+	}
+	post := func(url string, body map[string]interface{}) blankResult {
+		resp := postJSON(t, url, body)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200 OK, got %d: %s", resp.StatusCode, string(b))
+		}
+		var result blankResult
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+
+	sessionID := createTestSession(t, server.URL)
+	scanned := uploadTestPDF(t, server.URL, sessionID, "valid1.pdf")
+	uploadTestPDF(t, server.URL, sessionID, "valid2.pdf")
+	resp := postJSON(t, server.URL+"/api/sessions/"+sessionID+"/actions/insert", map[string]interface{}{"file": scanned, "after": 1, "blank": 1})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 OK inserting a blank page, got %d", resp.StatusCode)
+	}
+
+	actionURL := server.URL + "/api/sessions/" + sessionID + "/actions/remove-blank-pages"
+	resp = postJSON(t, actionURL, map[string]interface{}{"file": scanned, "threshold": 20})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a threshold over the limit, got %d", resp.StatusCode)
+	}
+	result := post(actionURL, map[string]interface{}{"file": scanned, "dryRun": true})
+	if len(result.BlankPages) != 1 || result.BlankPages[0].Page != 2 || result.BlankPages[0].Reason != "empty" {
+		t.Fatalf("Expected page 2 to be found blank, got %+v", result.BlankPages)
+	}
+
+	result = post(server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{"removeBlankPages": map[string]interface{}{}})
+	if len(result.BlankPages) != 1 || result.BlankPages[0].File != scanned || result.BlankPages[0].Page != 2 {
+		t.Fatalf("Expected the inserted page to be left out, got %+v", result.BlankPages)
+	}
+	merged := filepath.Join("output", filepath.Base(result.DownloadURL))
+	if n, err := pdfapi.PageCountFile(merged); err != nil || n != 18 {
+		t.Errorf("Expected 18 merged pages, got %d (%v)", n, err)
+	}
+	if n, _ := pdfapi.PageCountFile(filepath.Join("uploads", scanned)); n != 3 {
+		t.Errorf("Expected the upload to keep its 3 pages after merging, got %d", n)
+	}
+
+	// Merging again without the option brings the page back in a new output.
+	result = post(server.URL+"/api/sessions/"+sessionID+"/actions/merge", map[string]interface{}{})
+	if n, err := pdfapi.PageCountFile(filepath.Join("output", filepath.Base(result.DownloadURL))); err != nil || n != 19 {
+		t.Errorf("Expected 19 pages merging again, got %d (%v)", n, err)
+	}
+	if _, err := os.Stat(merged); !os.IsNotExist(err) {
+		t.Errorf("Expected the new merge to replace the previous output, got %v", err)
+	}
+
+	result = post(actionURL, map[string]interface{}{"file": scanned})
+	if result.Filename != scanned || len(result.BlankPages) != 1 {
+		t.Fatalf("Expected the blank page to be removed in place, got %+v", result)
+	}
+	if n, _ := pdfapi.PageCountFile(filepath.Join("uploads", scanned)); n != 2 {
+		t.Errorf("Expected 2 pages after removing the blank page, got %d", n)
+	}
+
+	// The output of the second merge still has the page.
+	result = post(actionURL, map[string]interface{}{})
+	if result.DownloadURL == "" || len(result.BlankPages) != 1 || result.BlankPages[0].Page != 2 {
+		t.Errorf("Expected a new output without the blank page, got %+v", result)
+	}
+
+	// Scans are too large to render in full but still measured.
+	writeScannedPDF(t, "testfiles/scanned.pdf")
+	defer os.Remove("testfiles/scanned.pdf")
+	duplex := uploadTestPDF(t, server.URL, sessionID, "scanned.pdf")
+	result = post(actionURL, map[string]interface{}{"file": duplex, "dryRun": true})
+	if len(result.BlankPages) != 1 || result.BlankPages[0].Page != 2 || result.BlankPages[0].Reason != "ink" {
+		t.Errorf("Expected the back of the scan to be found blank, got %+v", result.BlankPages)
+	}
+}
+
+// stringEscapes maps the letters of PDF string escapes to their bytes.
+var stringEscapes = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', 'b': '\b', 'f': '\f'}
+
+// shownCodes returns the bytes of every string in the content of a page, in
+// stream order: the character codes it shows, before any font decoding.
+func shownCodes(t *testing.T, path string, pageNr int) []byte {
+	t.Helper()
+	ctx, err := pdfapi.ReadContextFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	pageDict, _, _, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		t.Fatalf("Failed to read page %d: %v", pageNr, err)
+	}
+	content, err := ctx.PageContent(pageDict)
+	if err != nil {
+		t.Fatalf("Failed to read content of page %d: %v", pageNr, err)
+	}
+
+	var codes []byte
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i++
+		case c == '<':
+			j := bytes.IndexByte(content[i:], '>')
+			if j < 0 {
+				t.Fatalf("Unterminated hex string on page %d", pageNr)
+			}
+			hexDigits := strings.Join(strings.Fields(string(content[i+1:i+j])), "")
+			if len(hexDigits)%2 == 1 {
+				hexDigits += "0"
+			}
+			b, err := hex.DecodeString(hexDigits)
+			if err != nil {
+				t.Fatalf("Bad hex string on page %d: %v", pageNr, err)
+			}
+			codes = append(codes, b...)
+			i += j
+		case c == '(':
+			depth := 1
+			for i++; i < len(content) && depth > 0; i++ {
+				switch c := content[i]; c {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case '\\':
+					i++
+					if i == len(content) {
+						continue
+					}
+					switch e := content[i]; {
+					case e >= '0' && e <= '7':
+						n := 0
+						for k := 0; k < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; k++ {
+							n = n*8 + int(content[i]-'0')
+							i++
+						}
+						i--
+						codes = append(codes, byte(n))
+					case e == '\r' || e == '\n':
+						if e == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+							i++
+						}
+					default:
+						if b, ok := stringEscapes[e]; ok {
+							e = b
+						}
+						codes = append(codes, e)
+					}
+					continue
+				}
+				if depth > 0 {
+					codes = append(codes, content[i])
+				}
+			}
+			i--
+		}
+	}
+	return codes
+}
@@ -27,10 +27,12 @@ import (
 )
 
 type Server struct {
-	port           int
-	SessionManager *session.SessionManager
-	UploadDir      string
-	OutputDir      string
+	port              int
+	SessionManager    *session.SessionManager
+	UploadDir         string
+	OutputDir         string
+	ThumbnailRenderer string        // Optional pdftoppm compatible binary, from THUMBNAIL_RENDERER
+	CleanupDelay      time.Duration // Optional delay before a downloaded session is removed
 }
 
 func NewServer() *http.Server {
@@ -42,10 +44,11 @@ func NewServer() *http.Server {
 	os.MkdirAll(outputDir, 0755)
 
 	srv := &Server{
-		port:           port,
-		SessionManager: session.NewSessionManager(),
-		UploadDir:      uploadDir,
-		OutputDir:      outputDir,
+		port:              port,
+		SessionManager:    session.NewSessionManager(),
+		UploadDir:         uploadDir,
+		OutputDir:         outputDir,
+		ThumbnailRenderer: os.Getenv("THUMBNAIL_RENDERER"),
 	}
 
 	// Cleanup goroutine for old sessions/files
@@ -1,7 +1,7 @@
 // Package session manages user sessions and file lists for PDF merging.
 //
 // Types:
-//   - Session: Tracks uploaded files, output file, and status for a user session.
+//   - Session: Tracks uploaded files, supporting assets, output file, artifacts, and status for a user session.
 //   - SessionManager: Manages all active sessions.
 //
 // Expected outputs:
@@ -14,7 +14,9 @@ package session
 
 import (
 	"go-mergepdf/internal/utils"
+	"log"
 	"os"
+	"slices"
 	"sync"
 	"time"
 )
@@ -22,7 +24,10 @@ import (
 type Session struct {
 	ID          string
 	Files       []string
+	Assets      []string          // Supporting uploads such as templates; never merged
+	Hashes      map[string]string // SHA-256 of each uploaded file as received, by path
 	OutputFile  string
+	Artifacts   []string // Downloads made besides the output, such as archives and reports
 	CreatedAt   time.Time
 	MergeStatus string
 	Mutex       sync.Mutex
@@ -84,12 +89,85 @@ func (s *Session) GetFiles() []string {
 	return s.Files
 }
 
+// SetFileHash records the content hash of an uploaded file.
+func (s *Session) SetFileHash(filepath, hash string) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	if s.Hashes == nil {
+		s.Hashes = map[string]string{}
+	}
+	s.Hashes[filepath] = hash
+}
+
+// FilesWithHash returns the session files whose content hash is hash.
+func (s *Session) FilesWithHash(hash string) []string {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	var files []string
+	for _, file := range s.Files {
+		if s.Hashes[file] == hash {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (s *Session) AddAsset(filepath string) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.Assets = append(s.Assets, filepath)
+}
+
+func (s *Session) GetAssets() []string {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	return s.Assets
+}
+
+func (s *Session) GetOutputFile() string {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	return s.OutputFile
+}
+
+// SetOutputFile replaces the session output, removing the previous output file.
+func (s *Session) SetOutputFile(filepath string) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	if s.OutputFile != "" && s.OutputFile != filepath {
+		log.Printf("Removing old output file: %s", s.OutputFile)
+		os.Remove(s.OutputFile)
+	}
+	s.OutputFile = filepath
+}
+
+// AddArtifact records a download made besides the output, which stays in
+// place.
+func (s *Session) AddArtifact(filepath string) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.Artifacts = append(s.Artifacts, filepath)
+}
+
+// HasArtifact reports whether filepath is one of the session's artifacts.
+func (s *Session) HasArtifact(filepath string) bool {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	return slices.Contains(s.Artifacts, filepath)
+}
+
 func (s *Session) Cleanup() {
 	s.Mutex.Lock()
 	defer s.Mutex.Unlock()
 	for _, file := range s.Files {
 		os.Remove(file)
 	}
+	for _, file := range s.Assets {
+		os.Remove(file)
+	}
+	for _, file := range s.Artifacts {
+		os.Remove(file)
+	}
 	if s.OutputFile != "" {
 		os.Remove(s.OutputFile)
 	}